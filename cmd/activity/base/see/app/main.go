@@ -0,0 +1,157 @@
+package see
+
+//Application scan the activities database from top to bottom and write them
+//to the console.
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// handle retrieves responses from the channel, formats them, and
+// writes them to the handle's own output file.
+func handle(c chan goengage.BaseResponse, writer output.Writer, id int) {
+	log.Printf("handle-%d: begin\n", id)
+	for true {
+		resp, ok := <-c
+		if !ok {
+			break
+		}
+		for _, a := range resp.Payload.Activities {
+			date := strings.Split(fmt.Sprintf("%v", a.ActivityDate), " ")[0]
+			record := []string{
+				a.SupporterID,
+				a.PersonName,
+				a.PersonEmail,
+				a.ActivityType,
+				date,
+			}
+			err := writer.Write(record)
+			if err != nil {
+				panic(err)
+			}
+		}
+		log.Printf("handle-%d: write %d\n", id, len(resp.Payload.Activities))
+		err := writer.Flush()
+		if err != nil {
+			panic(err)
+		}
+	}
+	err := writer.Close()
+	if err != nil {
+		panic(err)
+	}
+	log.Printf("handle-%d: end\n", id)
+}
+
+// startHandler creates a handler that reads from a channel of responses
+// and writes to the 'n'th output file. Output files have "-n" just before
+// the dot that separates the name from the extension (whatever-1.csv,
+// whatever-2.csv, etc.)  The extension follows the output format.
+// Errors panic.
+func startHandler(c chan goengage.BaseResponse, opt output.Options, filename string, n int) {
+	parts := strings.Split(filename, ".")
+	csvFile := fmt.Sprintf("%s-%d.%s", parts[0], n, parts[1])
+	headers := []string{
+		"SupporterID",
+		"PersonName",
+		"PersonEmail",
+		"ActivityType",
+		"ActivityDate",
+	}
+	writer, err := opt.Create(opt.Filename(csvFile), headers)
+	if err != nil {
+		panic(err)
+	}
+	handle(c, writer, n)
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app     = kingpin.New("activity-see", "List all activities")
+		login   = app.Flag("login", "YAML file with API token").Required().String()
+		csvFile = app.Flag("output", "CSVf file for results").Required().String()
+		format  = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip    = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	types := []string{
+		// goengage.SubscriptionManagementType,
+		//goengage.SubscriptionType,
+		// goengage.FundraiseType,
+		goengage.PetitionType,
+		goengage.TargetedLetterType,
+		// goengage.TicketedEventType,
+		// goengage.P2PEventType,
+	}
+	c := make(chan goengage.BaseResponse, 1000)
+	var wg sync.WaitGroup
+	for i := 1; i < 6; i++ {
+		wg.Add(1)
+		go func(c chan goengage.BaseResponse, filename string, id int, wg *sync.WaitGroup) {
+			startHandler(c, opt, *csvFile, id)
+			wg.Done()
+		}(c, *csvFile, i, &wg)
+		log.Printf("main: started handler %d\n", i)
+	}
+
+	// Listeners are all ready.  Start the talker.
+	wg.Add(1)
+	go func(e *goengage.Environment, c chan<- goengage.BaseResponse, wg *sync.WaitGroup) {
+		for _, r := range types {
+			offset := int32(0)
+			count := int32(e.BatchSize())
+			for count == int32(e.BatchSize()) {
+				payload := goengage.ActivityRequestPayload{
+					Type:         r,
+					Offset:       offset,
+					Count:        e.BatchSize(),
+					ModifiedFrom: "2000-01-01T00:00:00.000Z",
+				}
+				rqt := goengage.ActivityRequest{
+					Header:  goengage.RequestHeader{},
+					Payload: payload,
+				}
+				var resp goengage.BaseResponse
+				n := goengage.NetOp{
+					Host:     e.Host,
+					Method:   goengage.SearchMethod,
+					Endpoint: goengage.SearchActivity,
+					Token:    e.Token,
+					Request:  &rqt,
+					Response: &resp,
+				}
+				err = n.Do()
+				if err != nil {
+					panic(err)
+				}
+				c <- resp
+				count = resp.Payload.Count
+				offset += count
+				log.Printf("main: offset %d\n", offset)
+			}
+		}
+		close(c)
+		wg.Done()
+	}(e, c, &wg)
+	log.Print("main: started talker")
+	log.Print("main: waiting...")
+	wg.Wait()
+	log.Printf("main: done  Look for output files like '%s'\n", *csvFile)
+	return nil
+}
@@ -0,0 +1,328 @@
+package dedication
+
+//Application scan for fundraising activities with dedications
+//and write them to a CSV.
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	report "github.com/salsalabs/goengage/pkg/report"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	//DedicationAddressName is the supporter custom field name that contains
+	//the dedication address.
+	DedicationAddressName = "Address of Recipient to Notify"
+)
+
+// DedicationGuide is the Guide proxy.
+type DedicationGuide struct {
+	Span     report.Span
+	AddKeys  bool
+	Timezone *time.Location
+	Check    *goengage.Checkpoint
+	Out      output.Options
+	Totals   *DedicationTotals
+}
+
+// DedicationTotals counts dedications and their amounts by dedication
+// type.  Used for the summary sheet.
+type DedicationTotals struct {
+	Types   []string
+	Counts  map[string]int
+	Amounts map[string]float64
+}
+
+// NewDedicationGuide returns an initialized DedicationGuide.
+func NewDedicationGuide(span report.Span, addKeys bool, location *time.Location) DedicationGuide {
+	return DedicationGuide{
+		Span:     span,
+		AddKeys:  addKeys,
+		Timezone: location,
+		Totals: &DedicationTotals{
+			Counts:  make(map[string]int),
+			Amounts: make(map[string]float64),
+		},
+	}
+}
+
+// TypeActivity returns the kind of activity being read.
+// Implements goengage.report.Guide.
+func (g DedicationGuide) TypeActivity() string {
+	return goengage.FundraiseType
+}
+
+// Filter returns true if the record should be used.
+// Implements goengage.report.Guide.
+func (g DedicationGuide) Filter(f goengage.Fundraise) bool {
+	return f.DedicationType != goengage.None && !f.ActivityDate.Before(g.Span.S) && !f.ActivityDate.After(g.Span.E)
+}
+
+// Headers returns column headers for a CSV file.
+// Implements goengage.report.Guide.
+func (g DedicationGuide) Headers() []string {
+	a := []string{
+		"FirstName",
+		"LastName",
+		"PersonEmail",
+		"AddressLine1",
+		"AddressLine2",
+		"City",
+		"State",
+		"Zip",
+		"TransactionDate",
+		"DonationType",
+		"ActivityType",
+		"TransactionType",
+		"Amount",
+		"DedicationType",
+		"Dedication",
+		"Notify",
+		"DedicationAddress",
+	}
+	if g.AddKeys {
+		a = append(a, "ActivityID")
+		a = append(a, "DonationID")
+		a = append(a, "TransactionID")
+		a = append(a, "SupporterID")
+	}
+	return a
+}
+
+// Line returns a list of strings to go in to the CSV file.
+// Implements goengage.report.Guide.
+func (g DedicationGuide) Line(f goengage.Fundraise) []string {
+	addressLine1 := ""
+	addressLine2 := ""
+	city := ""
+	state := ""
+	postalCode := ""
+	dedicationAddress := ""
+	dedication := strings.Replace(f.Dedication, "\n", " ", -1)
+	dedication = strings.Replace(dedication, "\r", " ", -1)
+	dedication = strings.Replace(dedication, "\t", " ", -1)
+	activityDate := f.ActivityDate.In(g.Location())
+	transactionDate := activityDate.Format(report.BriefFormat)
+
+	s := &f.Supporter
+	if s != nil {
+		if f.Supporter.Address != nil {
+			addressLine1 = f.Supporter.Address.AddressLine1
+			addressLine2 = f.Supporter.Address.AddressLine2
+			city = f.Supporter.Address.City
+			state = f.Supporter.Address.State
+			postalCode = f.Supporter.Address.PostalCode
+		}
+		if f.Supporter.CustomFieldValues != nil {
+			for _, c := range f.Supporter.CustomFieldValues {
+				if c.Name == DedicationAddressName {
+					dedicationAddress = strings.Replace(c.Value, "\n", " ", -1)
+					dedicationAddress = strings.Replace(dedicationAddress, "\r", " ", -1)
+					dedicationAddress = strings.Replace(dedicationAddress, "\t", " ", -1)
+					re := regexp.MustCompile("[\r\n\t ]+")
+					dedicationAddress = re.ReplaceAllString(dedicationAddress, " ")
+					break
+				}
+			}
+		}
+	}
+	a := []string{
+		f.Supporter.FirstName,
+		f.Supporter.LastName,
+		f.PersonEmail,
+		addressLine1,
+		addressLine2,
+		city,
+		state,
+		postalCode,
+		transactionDate,
+		goengage.ToTitle(f.DonationType),
+		goengage.ToTitle(f.ActivityType),
+		goengage.ToTitle(f.Transactions[0].Type),
+		fmt.Sprintf("%.2f", f.TotalReceivedAmount),
+		goengage.ToTitle(f.DedicationType),
+		f.Dedication,
+		f.Notify,
+		dedicationAddress,
+	}
+	if g.AddKeys {
+		a = append(a, f.ActivityID)
+		a = append(a, f.DonationID)
+		a = append(a, f.Transactions[0].TransactionID)
+		a = append(a, f.SupporterID)
+	}
+	return a
+}
+
+// Location returns the local location. Useful for date conversions.
+func (g DedicationGuide) Location() *time.Location {
+	return g.Timezone
+}
+
+// Readers returns the number of readers to start.
+func (g DedicationGuide) Readers() int {
+	return 3
+}
+
+// Filename returns the CSV filename.
+func (g DedicationGuide) Filename() string {
+	s := g.Span.S.Format(report.BriefFormat)
+	return fmt.Sprintf("%s_dedications.csv", s)
+}
+
+// Offset returns the starting offset for the first read.
+func (g DedicationGuide) Offset() int32 {
+	return int32(0)
+}
+
+// Output returns the output format.
+// Implements goengage.report.Output.
+func (g DedicationGuide) Output() output.Options {
+	return g.Out
+}
+
+// Summarize adds a dedication to the totals.
+// Implements goengage.report.Summarizer.
+func (g DedicationGuide) Summarize(f goengage.Fundraise) {
+	t := goengage.ToTitle(f.DedicationType)
+	if _, ok := g.Totals.Counts[t]; !ok {
+		g.Totals.Types = append(g.Totals.Types, t)
+	}
+	g.Totals.Counts[t]++
+	g.Totals.Amounts[t] += f.TotalReceivedAmount
+}
+
+// Summary returns the summary sheet.  It has the count and amount for
+// each dedication type, then the totals.
+// Implements goengage.report.Summarizer.
+func (g DedicationGuide) Summary() (string, []string, [][]string) {
+	headers := []string{
+		"DedicationType",
+		"Count",
+		"Amount",
+	}
+	var rows [][]string
+	count := 0
+	amount := 0.0
+	for _, t := range g.Totals.Types {
+		n := g.Totals.Counts[t]
+		x := g.Totals.Amounts[t]
+		rows = append(rows, []string{t, fmt.Sprintf("%d", n), fmt.Sprintf("%.2f", x)})
+		count += n
+		amount += x
+	}
+	rows = append(rows, []string{"Total", fmt.Sprintf("%d", count), fmt.Sprintf("%.2f", amount)})
+	return "Summary", headers, rows
+}
+
+// Checkpoint returns the checkpoint for a restartable run.
+// Implements goengage.report.Resumable.
+func (g DedicationGuide) Checkpoint() *goengage.Checkpoint {
+	return g.Check
+}
+
+// Keys returns the column that identifies a row.  Each activity is one row.  Files without
+// --add-keys don't have it, and are matched on whole rows.
+// Implements goengage.report.Keyed.
+func (g DedicationGuide) Keys() []string {
+	return []string{"ActivityID"}
+}
+
+// DefaultDates computes the default start and end dates.
+// Default end date is just before the most recent Monday at midnight.
+// Default start date is the Monday before the end date at 00:00.
+// Formatted like Classic, "YYYY-MM-DD".
+func DefaultDates() (start, end string) {
+	now := time.Now()
+	startDelta := 6 + int(now.Weekday())
+	startTime := now.AddDate(0, 0, -startDelta)
+	endTime := startTime.AddDate(0, 0, 6)
+	start = startTime.Format(report.BriefFormat)
+	end = endTime.Format(report.BriefFormat)
+	return start, end
+}
+
+// Validate validates the provided start and end dates.
+// Converts the dates from the provided location to Zulu, checks for start
+// time before end time, then returns a slice of Span objects.  Typically,
+// the Slice is 1 entry.  It becomes multiple entries when interval between
+// startDate and endDate crosses month boundaries.
+// Errors are internal and fatal.
+func Validate(startDate string, endDate string, loc *time.Location) []report.Span {
+	st := report.Parse(startDate, loc, report.StartDuration)
+	et := report.Parse(endDate, loc, report.EndDuration)
+
+	if et.Before(st) {
+		log.Fatalf("end date '%v' is before start date '%v'", endDate, startDate)
+	}
+	var a []report.Span
+	day, _ := time.ParseDuration(report.DayDuration)
+	yesterday, err := time.ParseDuration(report.BackupDuration)
+	if err != nil {
+		panic(err)
+	}
+	for ct := st; ct.Before(et); ct = ct.Add(day) {
+		if ct.Month() != st.Month() {
+			span := report.Span{S: st, E: ct.Add(yesterday)}
+			a = append(a, span)
+			st = ct
+		}
+	}
+	span := report.Span{S: st, E: et}
+	a = append(a, span)
+	return a
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	start, end := DefaultDates()
+	var (
+		app       = kingpin.New("dedications", "Write dedications to a CSV")
+		login     = app.Flag("login", "YAML file with API token").Required().String()
+		startDate = app.Flag("startDate", "Start date, YYYY-MM-YY, default is Monday of last week at midnight").Default(start).String()
+		endDate   = app.Flag("endDate", "End date, YYYY-MM-YY, default is the most recent Monday at midnight").Default(end).String()
+		timeZone  = app.Flag("timezone", "Client's timezone, defaults to EST/EDT").Default("America/New_York").String()
+		addKeys   = app.Flag("keys", "Export activity, donation, transaction and supporter IDs").Bool()
+		resume    = app.Flag("resume", "Continue where the last run stopped and append to its CSVs").Bool()
+		format    = app.Flag("format", "Output format").Default(output.CSV).Enum(output.Formats...)
+		gzip      = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(*timeZone)
+	spans := Validate(*startDate, *endDate, location)
+	if err != nil {
+		return err
+	}
+	// One checkpoint covers all of the spans.  Each span has its own key.
+	fn := fmt.Sprintf("%s_%s_dedications", *startDate, *endDate)
+	cp, err := goengage.OpenCheckpoint(goengage.CheckpointFile(fn), *resume)
+	if err != nil {
+		return err
+	}
+	for _, span := range spans {
+		guide := NewDedicationGuide(span, *addKeys, location)
+		guide.Check = cp
+		guide.Out = output.Options{Format: *format, Gzip: *gzip}
+		ts := report.NewTimeSpan(span.S, span.E)
+		err = report.ReportFundraising(e, guide, ts)
+		if err != nil {
+			return err
+		}
+	}
+	err = cp.Finish()
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -11,4 +11,4 @@
 [ ! -e ~/tmp/apda ] && mkdir tmp/apda
 cd ~/tmp/apda
 export PATH=~/go/bin:$PATH
-fundraise_dedication --login ~/.logins/apda.yaml $*
+goengage activity fundraise dedication --login ~/.logins/apda.yaml $*
@@ -0,0 +1,337 @@
+package lybunt
+
+//Application to find lapsed donors.  LYBUNT donors gave "Last Year But
+//Unfortunately Not This" year.  SYBUNT donors gave "Some Year But
+//Unfortunately Not This" year, and not last year either.  Years are
+//fiscal years.  Each list is written to a file and, optionally, pushed
+//into an Engage segment.
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	report "github.com/salsalabs/goengage/pkg/report"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	//ReaderCount is the number of Engage readers to start.
+	ReaderCount = 3
+)
+
+// Donor holds the giving history for a single supporter.
+type Donor struct {
+	Supporter      goengage.Supporter
+	Email          string
+	ByYear         map[int]float64
+	Total          float64
+	LastGiftDate   time.Time
+	LastGiftAmount float64
+}
+
+// LapsedGuide is the report.Source proxy.
+type LapsedGuide struct {
+	Span            report.Span
+	Timezone        *time.Location
+	FiscalYearStart time.Month
+	ReadOffset      int32
+}
+
+// NewLapsedGuide returns an initialized LapsedGuide.
+func NewLapsedGuide(span report.Span, location *time.Location, fiscalYearStart time.Month, readOffset int32) LapsedGuide {
+	return LapsedGuide{
+		Span:            span,
+		Timezone:        location,
+		FiscalYearStart: fiscalYearStart,
+		ReadOffset:      readOffset,
+	}
+}
+
+// TypeActivity returns the kind of activity being read.
+// Implements goengage.report.Source.
+func (g LapsedGuide) TypeActivity() string {
+	return goengage.FundraiseType
+}
+
+// Filter returns true if the record should be used.
+// Implements goengage.report.Source.
+func (g LapsedGuide) Filter(f goengage.Fundraise) bool {
+	return f.ActivityDate != nil && !f.ActivityDate.Before(g.Span.S) && !f.ActivityDate.After(g.Span.E)
+}
+
+// Readers returns the number of readers to start.
+// Implements goengage.report.Source.
+func (g LapsedGuide) Readers() int {
+	return ReaderCount
+}
+
+// Offset returns the offset for the first read.
+// Implements goengage.report.Source.
+func (g LapsedGuide) Offset() int32 {
+	return g.ReadOffset
+}
+
+// FiscalYear returns the fiscal year for a time.  Fiscal years are named
+// for the calendar year in which they end.
+func (g LapsedGuide) FiscalYear(t time.Time) int {
+	t = t.In(g.Timezone)
+	if g.FiscalYearStart == time.January || t.Month() < g.FiscalYearStart {
+		return t.Year()
+	}
+	return t.Year() + 1
+}
+
+// FiscalYearBegins returns the first moment of a fiscal year.
+func (g LapsedGuide) FiscalYearBegins(fy int) time.Time {
+	year := fy
+	if g.FiscalYearStart != time.January {
+		year--
+	}
+	return time.Date(year, g.FiscalYearStart, 1, 0, 0, 0, 0, g.Timezone)
+}
+
+// Donors groups fundraising transactions by supporter and by fiscal year.
+// Charges add to the totals.  Refunds subtract from them.
+func (g LapsedGuide) Donors(a []goengage.Fundraise) map[string]*Donor {
+	m := make(map[string]*Donor)
+	for _, f := range a {
+		d, ok := m[f.SupporterID]
+		if !ok {
+			d = &Donor{
+				Supporter: f.Supporter,
+				Email:     f.PersonEmail,
+				ByYear:    make(map[int]float64),
+			}
+			m[f.SupporterID] = d
+		}
+		transactions := f.Transactions
+		if len(transactions) == 0 {
+			transactions = []goengage.Transaction{{
+				Type:   goengage.Charge,
+				Date:   f.ActivityDate,
+				Amount: f.TotalReceivedAmount,
+			}}
+		}
+		for _, t := range transactions {
+			date := f.ActivityDate
+			if t.Date != nil {
+				date = t.Date
+			}
+			if date == nil {
+				continue
+			}
+			amount := t.Amount
+			switch t.Type {
+			case goengage.Charge:
+				if date.After(d.LastGiftDate) {
+					d.LastGiftDate = *date
+					d.LastGiftAmount = t.Amount
+				}
+			case goengage.Refund:
+				amount = -amount
+			default:
+				continue
+			}
+			d.ByYear[g.FiscalYear(*date)] += amount
+			d.Total += amount
+		}
+	}
+	return m
+}
+
+// Classify splits donors into LYBUNT and SYBUNT lists for the fiscal year
+// that contains the provided time.  Both lists are sorted by last gift date,
+// most recent first.
+func (g LapsedGuide) Classify(m map[string]*Donor, asOf time.Time) (lybunt, sybunt []*Donor) {
+	thisYear := g.FiscalYear(asOf)
+	lastYear := thisYear - 1
+	for _, d := range m {
+		if d.ByYear[thisYear] > 0 {
+			continue
+		}
+		if d.ByYear[lastYear] > 0 {
+			lybunt = append(lybunt, d)
+			continue
+		}
+		for fy, amount := range d.ByYear {
+			if fy < lastYear && amount > 0 {
+				sybunt = append(sybunt, d)
+				break
+			}
+		}
+	}
+	for _, a := range [][]*Donor{lybunt, sybunt} {
+		sort.Slice(a, func(i, j int) bool {
+			return a[i].LastGiftDate.After(a[j].LastGiftDate)
+		})
+	}
+	return lybunt, sybunt
+}
+
+// Headers returns column headers for the output CSV files.
+func (g LapsedGuide) Headers() []string {
+	return []string{
+		"SupporterID",
+		"FirstName",
+		"LastName",
+		"Email",
+		"Phone",
+		"AddressLine1",
+		"AddressLine2",
+		"City",
+		"State",
+		"Zip",
+		"LastGiftDate",
+		"LastGiftAmount",
+		"LastGiftFiscalYear",
+		"CumulativeGiving",
+	}
+}
+
+// Line returns the CSV row for a donor.
+func (g LapsedGuide) Line(d *Donor) []string {
+	s := d.Supporter
+	email := d.Email
+	if p := goengage.FirstEmail(s); p != nil {
+		email = *p
+	}
+	phone := ""
+	for _, c := range s.Contacts {
+		if c.Type == goengage.ContactHome || c.Type == goengage.ContactWork || c.Type == goengage.ContactCell {
+			phone = c.Value
+			break
+		}
+	}
+	var addr goengage.Address
+	if s.Address != nil {
+		addr = *s.Address
+	}
+	return []string{
+		s.SupporterID,
+		s.FirstName,
+		s.LastName,
+		email,
+		phone,
+		addr.AddressLine1,
+		addr.AddressLine2,
+		addr.City,
+		addr.State,
+		addr.PostalCode,
+		d.LastGiftDate.In(g.Timezone).Format(report.BriefFormat),
+		fmt.Sprintf("%.2f", d.LastGiftAmount),
+		fmt.Sprintf("%d", g.FiscalYear(d.LastGiftDate)),
+		fmt.Sprintf("%.2f", d.Total),
+	}
+}
+
+// Store writes a list of donors to a file in the output format.
+func (g LapsedGuide) Store(opt output.Options, fn string, a []*Donor) error {
+	w, err := opt.Create(opt.Filename(fn), g.Headers())
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, d := range a {
+		err = w.Write(g.Line(d))
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// Assign pushes a list of donors into an Engage segment.  Does nothing
+// if the segment ID is empty.
+func Assign(e *goengage.Environment, segmentID string, a []*Donor) error {
+	if len(segmentID) == 0 || len(a) == 0 {
+		return nil
+	}
+	var ids []string
+	for _, d := range a {
+		ids = append(ids, d.Supporter.SupporterID)
+	}
+	results, err := goengage.AssignSupporters(e, segmentID, ids, nil)
+	if err != nil {
+		return err
+	}
+	log.Printf("Assign: segment %s, %d supporters sent, %d results\n", segmentID, len(ids), len(results))
+	return nil
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app             = kingpin.New("lybunt", "Write LYBUNT and SYBUNT donors to CSVs")
+		login           = app.Flag("login", "YAML file with API token").Required().String()
+		asOfDate        = app.Flag("asOf", "Report as of this date, YYYY-MM-DD, default is today").Default(time.Now().Format(report.BriefFormat)).String()
+		fiscalYearStart = app.Flag("fiscalYearStart", "Month that the fiscal year starts, 1 through 12").Default("1").Int()
+		years           = app.Flag("years", "Number of fiscal years of history to read before this one").Default("5").Int()
+		timeZone        = app.Flag("timezone", "Client's timezone, defaults to EST/EDT").Default("America/New_York").String()
+		lybuntFile      = app.Flag("lybunt", "CSV filename for LYBUNT donors").Default("lybunt.csv").String()
+		sybuntFile      = app.Flag("sybunt", "CSV filename for SYBUNT donors").Default("sybunt.csv").String()
+		lybuntSegment   = app.Flag("lybuntSegment", "Optional segment ID to receive LYBUNT donors").String()
+		sybuntSegment   = app.Flag("sybuntSegment", "Optional segment ID to receive SYBUNT donors").String()
+		readOffset      = app.Flag("readOffset", "Start reading here, useful for restarts").Default("0").Int32()
+		format          = app.Flag("format", "Output format, changes the output files' extensions").Default(output.CSV).Enum(output.Formats...)
+		gzip            = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+
+	if *fiscalYearStart < 1 || *fiscalYearStart > 12 {
+		return fmt.Errorf("--fiscalYearStart must be between 1 and 12, not %d", *fiscalYearStart)
+	}
+	if *years < 1 {
+		return fmt.Errorf("--years must be at least 1, not %d", *years)
+	}
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		return err
+	}
+	asOf := report.Parse(*asOfDate, location, report.EndDuration)
+	guide := NewLapsedGuide(report.Span{}, location, time.Month(*fiscalYearStart), *readOffset)
+	thisYear := guide.FiscalYear(asOf)
+	guide.Span = report.Span{
+		S: guide.FiscalYearBegins(thisYear - *years),
+		E: asOf,
+	}
+	log.Printf("main: fiscal year %d, reading donations from %v\n", thisYear, guide.Span.S)
+
+	//Activities can be modified after the as-of date.  Read through now.
+	ts := report.NewTimeSpan(guide.Span.S, time.Now())
+	a, err := report.CollectFundraising(e, guide, ts)
+	if err != nil {
+		return err
+	}
+	lybunt, sybunt := guide.Classify(guide.Donors(a), asOf)
+	log.Printf("main: %d LYBUNT donors, %d SYBUNT donors\n", len(lybunt), len(sybunt))
+
+	err = guide.Store(opt, *lybuntFile, lybunt)
+	if err != nil {
+		return err
+	}
+	err = guide.Store(opt, *sybuntFile, sybunt)
+	if err != nil {
+		return err
+	}
+	err = Assign(e, *lybuntSegment, lybunt)
+	if err != nil {
+		return err
+	}
+	err = Assign(e, *sybuntSegment, sybunt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
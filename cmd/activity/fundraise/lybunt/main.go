@@ -0,0 +1,16 @@
+package main
+
+//Runs the lybunt app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	lybunt "github.com/salsalabs/goengage/cmd/activity/fundraise/lybunt/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("lybunt", lybunt.Main, os.Args[1:])
+}
@@ -0,0 +1,321 @@
+package rollup
+
+//Application to summarize donations by fund, campaign, appeal, designation
+//and/or month.  Each summary row has gift count, gross, fees, net,
+//deductible amounts and refunds.  Output goes to a file in the output
+//format and to a JSON file.
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	report "github.com/salsalabs/goengage/pkg/report"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	//ReaderCount is the number of Engage readers to start.
+	ReaderCount = 3
+
+	//MonthFormat is used to show the month for a transaction.
+	MonthFormat = "2006-01"
+)
+
+// Dimensions that can be used to pivot the summary.
+const (
+	Fund        = "fund"
+	Campaign    = "campaign"
+	Appeal      = "appeal"
+	Designation = "designation"
+	Month       = "month"
+)
+
+// Rollup contains the totals for a single combination of dimensions.
+// Dimensions that are not being used are empty.
+type Rollup struct {
+	Fund        string  `json:"fund,omitempty"`
+	Campaign    string  `json:"campaign,omitempty"`
+	Appeal      string  `json:"appeal,omitempty"`
+	Designation string  `json:"designation,omitempty"`
+	Month       string  `json:"month,omitempty"`
+	Gifts       int     `json:"gifts"`
+	Gross       float64 `json:"gross"`
+	Fees        float64 `json:"fees"`
+	Net         float64 `json:"net"`
+	Deductible  float64 `json:"deductible"`
+	Refunds     int     `json:"refunds"`
+	Refunded    float64 `json:"refunded"`
+}
+
+// RollupGuide is the report.Source proxy.
+type RollupGuide struct {
+	Span       report.Span
+	Timezone   *time.Location
+	By         []string
+	ReadOffset int32
+}
+
+// NewRollupGuide returns an initialized RollupGuide.  Repeated dimensions
+// are only used once.
+func NewRollupGuide(span report.Span, location *time.Location, by []string, readOffset int32) RollupGuide {
+	seen := make(map[string]bool)
+	var dims []string
+	for _, b := range by {
+		if !seen[b] {
+			seen[b] = true
+			dims = append(dims, b)
+		}
+	}
+	return RollupGuide{
+		Span:       span,
+		Timezone:   location,
+		By:         dims,
+		ReadOffset: readOffset,
+	}
+}
+
+// TypeActivity returns the kind of activity being read.
+// Implements goengage.report.Source.
+func (g RollupGuide) TypeActivity() string {
+	return goengage.FundraiseType
+}
+
+// Filter returns true if the record has at least one transaction
+// in the time span. Implements goengage.report.Source.
+func (g RollupGuide) Filter(f goengage.Fundraise) bool {
+	for _, t := range f.Transactions {
+		if g.InSpan(t.Date) {
+			return true
+		}
+	}
+	return false
+}
+
+// Readers returns the number of readers to start.
+// Implements goengage.report.Source.
+func (g RollupGuide) Readers() int {
+	return ReaderCount
+}
+
+// Offset returns the offset for the first read.
+// Implements goengage.report.Source.
+func (g RollupGuide) Offset() int32 {
+	return g.ReadOffset
+}
+
+// LookupSupporters returns false.  Summaries don't need supporter records.
+// Implements goengage.report.Lookup.
+func (g RollupGuide) LookupSupporters() bool {
+	return false
+}
+
+// InSpan returns true if the provided time is in the guide's span.
+func (g RollupGuide) InSpan(t *time.Time) bool {
+	return t != nil && !t.Before(g.Span.S) && !t.After(g.Span.E)
+}
+
+// Key returns an empty Rollup with the dimensions that the guide
+// uses filled in from the provided donation and transaction.
+func (g RollupGuide) Key(f goengage.Fundraise, t goengage.Transaction) Rollup {
+	var r Rollup
+	for _, b := range g.By {
+		switch b {
+		case Fund:
+			r.Fund = f.Fund
+		case Campaign:
+			r.Campaign = f.Campaign
+		case Appeal:
+			r.Appeal = f.Appeal
+		case Designation:
+			r.Designation = f.Designation
+		case Month:
+			r.Month = t.Date.In(g.Timezone).Format(MonthFormat)
+		}
+	}
+	return r
+}
+
+// Summarize accumulates the transactions in the span into rollups.
+// Rollups are returned sorted by their dimensions.
+func (g RollupGuide) Summarize(a []goengage.Fundraise) []*Rollup {
+	m := make(map[Rollup]*Rollup)
+	for _, f := range a {
+		for _, t := range f.Transactions {
+			if !g.InSpan(t.Date) {
+				continue
+			}
+			k := g.Key(f, t)
+			r, ok := m[k]
+			if !ok {
+				x := k
+				r = &x
+				m[k] = r
+			}
+			switch t.Type {
+			case goengage.Charge:
+				r.Gifts++
+				r.Gross += t.Amount
+				r.Fees += t.FeesPaid
+				r.Deductible += t.DeductibleAmount
+			case goengage.Refund:
+				r.Refunds++
+				r.Refunded += t.Amount
+			}
+			r.Net = r.Gross - r.Fees - r.Refunded
+		}
+	}
+	var rollups []*Rollup
+	for _, r := range m {
+		rollups = append(rollups, r)
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		return strings.Join(g.Values(rollups[i]), "\t") < strings.Join(g.Values(rollups[j]), "\t")
+	})
+	return rollups
+}
+
+// Values returns the dimension values for a rollup in the order
+// that the dimensions were specified.
+func (g RollupGuide) Values(r *Rollup) []string {
+	var a []string
+	for _, b := range g.By {
+		switch b {
+		case Fund:
+			a = append(a, r.Fund)
+		case Campaign:
+			a = append(a, r.Campaign)
+		case Appeal:
+			a = append(a, r.Appeal)
+		case Designation:
+			a = append(a, r.Designation)
+		case Month:
+			a = append(a, r.Month)
+		}
+	}
+	return a
+}
+
+// Headers returns column headers for the CSV file.
+func (g RollupGuide) Headers() []string {
+	var a []string
+	for _, b := range g.By {
+		a = append(a, goengage.ToTitle(b))
+	}
+	return append(a,
+		"Gifts",
+		"Gross",
+		"Fees",
+		"Net",
+		"Deductible",
+		"Refunds",
+		"Refunded")
+}
+
+// Line returns an output row for a rollup.
+func (g RollupGuide) Line(r *Rollup) []string {
+	return append(g.Values(r),
+		fmt.Sprintf("%d", r.Gifts),
+		fmt.Sprintf("%.2f", r.Gross),
+		fmt.Sprintf("%.2f", r.Fees),
+		fmt.Sprintf("%.2f", r.Net),
+		fmt.Sprintf("%.2f", r.Deductible),
+		fmt.Sprintf("%d", r.Refunds),
+		fmt.Sprintf("%.2f", r.Refunded))
+}
+
+// Store writes the rollups to a file in the output format.
+func (g RollupGuide) Store(opt output.Options, fn string, rollups []*Rollup) error {
+	w, err := opt.Create(opt.Filename(fn), g.Headers())
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, r := range rollups {
+		err = w.Write(g.Line(r))
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// WriteJSON writes the rollups to a JSON file.
+func (g RollupGuide) WriteJSON(fn string, rollups []*Rollup) error {
+	b, err := json.MarshalIndent(rollups, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fn, b, 0644)
+}
+
+// DefaultDates computes the default start and end dates.  The default
+// is the first through the last day of last month.
+func DefaultDates() (start, end string) {
+	now := time.Now()
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	startTime := first.AddDate(0, -1, 0)
+	endTime := first.AddDate(0, 0, -1)
+	start = startTime.Format(report.BriefFormat)
+	end = endTime.Format(report.BriefFormat)
+	return start, end
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	start, end := DefaultDates()
+	var (
+		app        = kingpin.New("rollup", "Summarize donations by fund, campaign, appeal, designation and/or month")
+		login      = app.Flag("login", "YAML file with API token").Required().String()
+		startDate  = app.Flag("startDate", "Start date, YYYY-MM-DD, default is the first day of last month").Default(start).String()
+		endDate    = app.Flag("endDate", "End date, YYYY-MM-DD, default is the last day of last month").Default(end).String()
+		timeZone   = app.Flag("timezone", "Client's timezone, defaults to EST/EDT").Default("America/New_York").String()
+		by         = app.Flag("by", "Pivot by this dimension.  Repeat for more dimensions").Default(Fund).Enums(Fund, Campaign, Appeal, Designation, Month)
+		csvFile    = app.Flag("csv", "CSV filename for the summary").Default("rollup.csv").String()
+		jsonFile   = app.Flag("json", "JSON filename for the summary").Default("rollup.json").String()
+		readOffset = app.Flag("readOffset", "Start reading here, useful for restarts").Default("0").Int32()
+		format     = app.Flag("format", "Output format for --csv, changes the file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip       = app.Flag("gzip", "Compress the --csv output with gzip").Bool()
+	)
+	app.Parse(args)
+
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		return err
+	}
+	span := report.ValidateSpan(*startDate, *endDate, location)
+	guide := NewRollupGuide(span, location, *by, *readOffset)
+
+	//Refunds modify the original donation.  Read through now to see them.
+	ts := report.NewTimeSpan(span.S, time.Now())
+	a, err := report.CollectFundraising(e, guide, ts)
+	if err != nil {
+		return err
+	}
+	rollups := guide.Summarize(a)
+	log.Printf("main: %d donations, %d summary rows\n", len(a), len(rollups))
+
+	err = guide.Store(opt, *csvFile, rollups)
+	if err != nil {
+		return err
+	}
+	err = guide.WriteJSON(*jsonFile, rollups)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package main
+
+//Runs the rollup app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	rollup "github.com/salsalabs/goengage/cmd/activity/fundraise/rollup/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("rollup", rollup.Main, os.Args[1:])
+}
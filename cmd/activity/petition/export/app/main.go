@@ -0,0 +1,458 @@
+package export
+
+//Application to export petition signatures for delivery.  Signers are
+//written to a CSV with their address and district information.  Signers
+//can be filtered by moderation state and by whether they agreed to show
+//their signature publicly.  A deliverable signature list is written as
+//HTML or as plain text.  The list is grouped by state, then by district.
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	report "github.com/salsalabs/goengage/pkg/report"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Districts that can be used to group signers.
+const (
+	Federal      = "federal"
+	StateHouse   = "stateHouse"
+	StateSenate  = "stateSenate"
+	County       = "county"
+	Municipality = "municipality"
+)
+
+// Group names for signers without a state or district.
+const (
+	NoState    = "(no state)"
+	NoDistrict = "(no district)"
+)
+
+// Formats for the deliverable.
+const (
+	HTMLFormat = "html"
+	TextFormat = "text"
+)
+
+// Extensions are the default filename extensions for the deliverable.
+var Extensions = map[string]string{
+	HTMLFormat: ".html",
+	TextFormat: ".txt",
+}
+
+// DeliverableBase is the default filename for the deliverable, without
+// the extension.
+const DeliverableBase = "petition_signatures"
+
+// DateFormat is used to show signature dates.
+const DateFormat = "2006-01-02"
+
+// Signer is a petition signature and the supporter that signed it.
+type Signer struct {
+	Petition  goengage.Petition
+	Supporter goengage.Supporter
+	Address   goengage.Address
+	Email     string
+	District  string
+}
+
+// Name returns the signer's full name.
+func (s Signer) Name() string {
+	a := []string{s.Supporter.FirstName, s.Supporter.LastName}
+	n := strings.TrimSpace(strings.Join(a, " "))
+	if len(n) == 0 {
+		n = s.Petition.PersonName
+	}
+	return n
+}
+
+// Date returns the signature date.
+func (s Signer) Date() string {
+	if s.Petition.ActivityDate == nil {
+		return ""
+	}
+	return s.Petition.ActivityDate.Format(DateFormat)
+}
+
+// Comment returns the signer's comment if it can be shown publicly.
+func (s Signer) Comment() string {
+	if !s.Petition.DisplayCommentPublicly {
+		return ""
+	}
+	return s.Petition.Comment
+}
+
+// Group is the signers for a single district in a state.
+type Group struct {
+	State    string
+	District string
+	Signers  []Signer
+}
+
+// Deliverable is the data used to write the signature list.
+type Deliverable struct {
+	Title    string
+	Date     string
+	Total    int
+	Groups   []Group
+	Comments bool
+}
+
+// Runtime holds the stuff that this app needs.
+type Runtime struct {
+	Env        *goengage.Environment
+	Span       report.Span
+	FormID     string
+	FormName   string
+	States     map[string]bool
+	PublicOnly bool
+	District   string
+	Logger     *goengage.UtilLogger
+}
+
+// Keep returns true if a petition should be exported.  Form IDs are
+// filtered by the search.
+func (rt *Runtime) Keep(p goengage.Petition) bool {
+	if len(rt.FormName) != 0 && p.ActivityFormName != rt.FormName {
+		return false
+	}
+	if len(rt.States) != 0 && !rt.States[p.ModerationState] {
+		return false
+	}
+	if rt.PublicOnly && !p.DisplaySignaturePublicly {
+		return false
+	}
+	return true
+}
+
+// DistrictFor returns the district for an address.
+func (rt *Runtime) DistrictFor(a goengage.Address) string {
+	var s string
+	switch rt.District {
+	case Federal:
+		s = a.FederalHouseDistrict
+	case StateHouse:
+		s = a.StateHouseDistrict
+	case StateSenate:
+		s = a.StateSenateDistrict
+	case County:
+		s = a.CountyDistrict
+	case Municipality:
+		s = a.MunicipalityDistrict
+	}
+	if len(s) == 0 {
+		s = NoDistrict
+	}
+	return s
+}
+
+// Signers reads petitions, filters them, then joins them to supporters.
+func (rt *Runtime) Signers() ([]Signer, error) {
+	ts := report.NewTimeSpan(rt.Span.S, rt.Span.E)
+	payload := goengage.ActivityRequestPayload{
+		ModifiedFrom: ts.Start,
+		ModifiedTo:   ts.End,
+	}
+	if len(rt.FormID) != 0 {
+		payload.ActivityFormIDs = []string{rt.FormID}
+	}
+	petitions, err := goengage.Petitions(rt.Env, payload, rt.Logger)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Signers: %d petition activities\n", len(petitions))
+	var kept []goengage.Petition
+	var ids []string
+	seen := make(map[string]bool)
+	for _, p := range petitions {
+		if !rt.Keep(p) {
+			continue
+		}
+		kept = append(kept, p)
+		if !seen[p.SupporterID] {
+			seen[p.SupporterID] = true
+			ids = append(ids, p.SupporterID)
+		}
+	}
+	log.Printf("Signers: %d signatures after filtering\n", len(kept))
+	supporters, err := goengage.SupportersByID(rt.Env, ids, rt.Logger)
+	if err != nil {
+		return nil, err
+	}
+	var a []Signer
+	for _, p := range kept {
+		s := Signer{
+			Petition:  p,
+			Supporter: supporters[p.SupporterID],
+			Email:     p.PersonEmail,
+		}
+		if s.Supporter.Address != nil {
+			s.Address = *s.Supporter.Address
+		}
+		if len(s.Email) == 0 {
+			if e := goengage.FirstEmail(s.Supporter); e != nil {
+				s.Email = *e
+			}
+		}
+		s.District = rt.DistrictFor(s.Address)
+		a = append(a, s)
+	}
+	return a, nil
+}
+
+// Groups returns signers grouped by state and district.  Groups and
+// signers are sorted.
+func Groups(a []Signer) []Group {
+	m := make(map[string]*Group)
+	var keys []string
+	for _, s := range a {
+		state := s.Address.State
+		if len(state) == 0 {
+			state = NoState
+		}
+		k := state + "\t" + s.District
+		g, ok := m[k]
+		if !ok {
+			g = &Group{State: state, District: s.District}
+			m[k] = g
+			keys = append(keys, k)
+		}
+		g.Signers = append(g.Signers, s)
+	}
+	sort.Strings(keys)
+	var groups []Group
+	for _, k := range keys {
+		g := m[k]
+		sort.Slice(g.Signers, func(i, j int) bool {
+			a, b := g.Signers[i].Supporter, g.Signers[j].Supporter
+			if a.LastName != b.LastName {
+				return a.LastName < b.LastName
+			}
+			return a.FirstName < b.FirstName
+		})
+		groups = append(groups, *g)
+	}
+	return groups
+}
+
+// WriteSigners writes signers to a file in the output format.
+func WriteSigners(opt output.Options, fn string, a []Signer) error {
+	headers := []string{
+		"ActivityID",
+		"ActivityDate",
+		"ActivityFormName",
+		"SupporterID",
+		"FirstName",
+		"LastName",
+		"Email",
+		"AddressLine1",
+		"AddressLine2",
+		"City",
+		"State",
+		"PostalCode",
+		"County",
+		"FederalHouseDistrict",
+		"StateHouseDistrict",
+		"StateSenateDistrict",
+		"CountyDistrict",
+		"MunicipalityDistrict",
+		"ModerationState",
+		"DisplaySignaturePublicly",
+		"DisplayCommentPublicly",
+		"Comment",
+	}
+	w, err := opt.Create(opt.Filename(fn), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, s := range a {
+		p := s.Petition
+		x := s.Address
+		record := []string{
+			p.ActivityID,
+			s.Date(),
+			p.ActivityFormName,
+			p.SupporterID,
+			s.Supporter.FirstName,
+			s.Supporter.LastName,
+			s.Email,
+			x.AddressLine1,
+			x.AddressLine2,
+			x.City,
+			x.State,
+			x.PostalCode,
+			x.County,
+			x.FederalHouseDistrict,
+			x.StateHouseDistrict,
+			x.StateSenateDistrict,
+			x.CountyDistrict,
+			x.MunicipalityDistrict,
+			p.ModerationState,
+			fmt.Sprintf("%v", p.DisplaySignaturePublicly),
+			fmt.Sprintf("%v", p.DisplayCommentPublicly),
+			p.Comment,
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// htmlTemplate formats the deliverable as a web page.
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Total}} signatures as of {{.Date}}</p>
+{{range .Groups}}
+<h2>{{.State}}, district {{.District}}</h2>
+<p>{{len .Signers}} signatures</p>
+<table>
+<tr><th>Name</th><th>City</th><th>Postal code</th><th>Date</th>{{if $.Comments}}<th>Comment</th>{{end}}</tr>
+{{range .Signers}}<tr><td>{{.Name}}</td><td>{{.Address.City}}</td><td>{{.Address.PostalCode}}</td><td>{{.Date}}</td>{{if $.Comments}}<td>{{.Comment}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`
+
+// WriteHTML writes the deliverable as HTML.
+func WriteHTML(w io.Writer, d Deliverable) error {
+	t, err := template.New("deliverable").Parse(htmlTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, d)
+}
+
+// WriteText writes the deliverable as plain text.
+func WriteText(w io.Writer, d Deliverable) error {
+	fmt.Fprintf(w, "%s\n%d signatures as of %s\n", d.Title, d.Total, d.Date)
+	for _, g := range d.Groups {
+		fmt.Fprintf(w, "\n%s, district %s (%d signatures)\n", g.State, g.District, len(g.Signers))
+		fmt.Fprintln(w, strings.Repeat("-", 72))
+		for _, s := range g.Signers {
+			_, err := fmt.Fprintf(w, "%-32s %-24s %-10s %s\n", s.Name(), s.Address.City, s.Address.PostalCode, s.Date())
+			if err != nil {
+				return err
+			}
+			if c := s.Comment(); d.Comments && len(c) != 0 {
+				fmt.Fprintf(w, "    \"%s\"\n", c)
+			}
+		}
+	}
+	return nil
+}
+
+// WriteDeliverable writes the signature list in the requested format.
+func WriteDeliverable(fn string, format string, d Deliverable) error {
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if format == HTMLFormat {
+		return WriteHTML(f, d)
+	}
+	return WriteText(f, d)
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app         = kingpin.New("export", "Export petition signatures with addresses and districts for delivery")
+		login       = app.Flag("login", "YAML file with API token").Required().String()
+		formID      = app.Flag("formId", "Only export signatures for this petition form ID").String()
+		formName    = app.Flag("formName", "Only export signatures for this petition name").String()
+		startDate   = app.Flag("startDate", "Start date, YYYY-MM-DD").Default("2000-01-01").String()
+		endDate     = app.Flag("endDate", "End date, YYYY-MM-DD, default is today").Default(time.Now().Format(report.BriefFormat)).String()
+		timeZone    = app.Flag("timezone", "Client's timezone, defaults to EST/EDT").Default("America/New_York").String()
+		moderation  = app.Flag("moderation", "Only export this moderation state.  Repeat for more states").Enums(goengage.Display, goengage.DontDisplay, goengage.Pending)
+		publicOnly  = app.Flag("publicOnly", "Only export signers that agreed to show their signature publicly").Bool()
+		district    = app.Flag("district", "Group signers by this district").Default(Federal).Enum(Federal, StateHouse, StateSenate, County, Municipality)
+		csvFile     = app.Flag("csv", "CSV filename for signers").Default("petition_signers.csv").String()
+		format      = app.Flag("format", "Output format for signers, changes the --csv file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip        = app.Flag("gzip", "Compress the signers with gzip").Bool()
+		deliverable = app.Flag("deliverable", "Filename for the signature list, default is "+DeliverableBase+" with the format's extension").String()
+		delivery    = app.Flag("deliverable-format", "Format for the signature list").Default(HTMLFormat).Enum(HTMLFormat, TextFormat)
+		title       = app.Flag("title", "Title for the signature list").Default("Petition signatures").String()
+		comments    = app.Flag("comments", "Include comments that signers agreed to show publicly").Bool()
+		verbose     = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+	)
+	app.Parse(args)
+	if len(*deliverable) == 0 {
+		*deliverable = DeliverableBase + Extensions[*delivery]
+	}
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		return err
+	}
+	rt := Runtime{
+		Env:        e,
+		Span:       report.ValidateSpan(*startDate, *endDate, location),
+		FormID:     *formID,
+		FormName:   *formName,
+		States:     make(map[string]bool),
+		PublicOnly: *publicOnly,
+		District:   *district,
+	}
+	for _, s := range *moderation {
+		rt.States[s] = true
+	}
+	if *verbose {
+		rt.Logger, err = goengage.NewUtilLogger()
+		if err != nil {
+			return err
+		}
+	}
+	signers, err := rt.Signers()
+	if err != nil {
+		return err
+	}
+	err = WriteSigners(opt, *csvFile, signers)
+	if err != nil {
+		return err
+	}
+	d := Deliverable{
+		Title:    *title,
+		Date:     time.Now().In(location).Format(DateFormat),
+		Total:    len(signers),
+		Groups:   Groups(signers),
+		Comments: *comments,
+	}
+	err = WriteDeliverable(*deliverable, *delivery, d)
+	if err != nil {
+		return err
+	}
+	log.Printf("main: %d signers in %d groups, wrote %s and %s\n", len(signers), len(d.Groups), opt.Filename(*csvFile), *deliverable)
+	return nil
+}
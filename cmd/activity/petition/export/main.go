@@ -0,0 +1,16 @@
+package main
+
+//Runs the export app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	petitionexport "github.com/salsalabs/goengage/cmd/activity/petition/export/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("export", petitionexport.Main, os.Args[1:])
+}
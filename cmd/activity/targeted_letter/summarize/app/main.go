@@ -0,0 +1,90 @@
+package summarize
+
+//Application scan the activities database from top to bottom and write them
+//to the console.
+import (
+	"fmt"
+	"sort"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+func seeBaseResponse(resp goengage.BaseResponse) {
+	var unsorted []string
+	for _, a := range resp.Payload.Activities {
+		date := a.ActivityDate.Format("2006-01-02")
+		s := fmt.Sprintf("%-10v %-52v %-15v\n",
+			//(i + 1),
+			date,
+			a.ActivityFormName,
+			a.SupporterID)
+		unsorted = append(unsorted, s)
+	}
+	sort.Strings(unsorted)
+	for i, s := range unsorted {
+		fmt.Printf("%02d %s", i+1, s)
+	}
+}
+
+func process(e *goengage.Environment, offset int32) (int32, error) {
+	payload := goengage.ActivityRequestPayload{
+		Type:   goengage.TargetedLetterType,
+		Offset: int32(offset),
+		Count:  e.BatchSize(),
+		//Pagination does *not* work with activityFormIds at this writing.
+		ModifiedFrom: "2006-09-01T00:00:00.0Z",
+		ModifiedTo:   "2021-10-31T00:00:00.0Z",
+	}
+	rqt := goengage.ActivityRequest{
+		Header:  goengage.RequestHeader{RefID: "cmd/activity/targeted_letter/summarize"},
+		Payload: payload,
+	}
+	var resp goengage.BaseResponse
+	logger, err := goengage.NewUtilLogger()
+	if err != nil {
+		return 0, err
+	}
+	n := goengage.NetOp{
+		Host:     e.Host,
+		Method:   goengage.SearchMethod,
+		Endpoint: goengage.SearchActivity,
+		Token:    e.Token,
+		Request:  &rqt,
+		Response: &resp,
+		Logger:   logger,
+	}
+	err = n.Do()
+	if err != nil {
+		return 0, err
+	}
+	fmt.Printf("process:  offset: %2d, requested: %2d, total: %2d, returned %2d\n",
+		offset,
+		rqt.Payload.Count,
+		resp.Payload.Total,
+		resp.Payload.Count)
+	return resp.Payload.Count, nil
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app   = kingpin.New("activity-see", "List all activities")
+		login = app.Flag("login", "YAML file with API token").Required().String()
+	)
+	app.Parse(args)
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	offset := int32(0)
+	count := int32(e.BatchSize())
+	for count > 0 {
+		count, err = process(e, offset)
+		if err != nil {
+			return err
+		}
+		offset += count
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+package targets
+
+//Application to summarize targeted letter outcomes.  Every letter that a
+//supporter sends to a target counts as one action.  Actions are pivoted by
+//target and by district.  Each summary row shows delivery channel counts,
+//call outcomes, total call minutes and how often supporters edited the
+//subject or the message.  Output is a file of targets and a file of
+//districts.
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	report "github.com/salsalabs/goengage/pkg/report"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// CallResults are the call outcomes, in the order that they appear in
+// the output.
+var CallResults = []string{
+	goengage.CallCompleted,
+	goengage.CallBusy,
+	goengage.CallNoAnswer,
+	goengage.CallMachine,
+	goengage.CallFailed,
+	goengage.CallCancelled,
+	goengage.CallOverBudget,
+	goengage.CallSkipped,
+	goengage.CallNoCall,
+}
+
+// Key identifies a summary row.  Fields that are not used by a pivot
+// are empty.
+type Key struct {
+	TargetID       string
+	TargetName     string
+	TargetTitle    string
+	PoliticalParty string
+	TargetType     string
+	State          string
+	DistrictName   string
+}
+
+// Outcome holds the totals for a single target or district.
+type Outcome struct {
+	Key
+	Actions         int
+	Supporters      map[string]bool
+	Email           int
+	Facebook        int
+	Twitter         int
+	Calls           int
+	CallSeconds     int64
+	CallResults     map[string]int
+	SubjectModified int
+	MessageModified int
+}
+
+// Pivot accumulates outcomes for one way of grouping targets.
+type Pivot struct {
+	Key func(t goengage.Target) Key
+	m   map[Key]*Outcome
+}
+
+// NewPivot returns a pivot that groups targets using the provided key.
+func NewPivot(key func(t goengage.Target) Key) *Pivot {
+	return &Pivot{
+		Key: key,
+		m:   make(map[Key]*Outcome),
+	}
+}
+
+// Add accumulates a single letter to a single target.
+func (p *Pivot) Add(supporterID string, l goengage.Letter, t goengage.Target) {
+	k := p.Key(t)
+	x, ok := p.m[k]
+	if !ok {
+		x = &Outcome{
+			Key:         k,
+			Supporters:  make(map[string]bool),
+			CallResults: make(map[string]int),
+		}
+		p.m[k] = x
+	}
+	x.Actions++
+	x.Supporters[supporterID] = true
+	if t.SentEmail {
+		x.Email++
+	}
+	if t.SentFacebook {
+		x.Facebook++
+	}
+	if t.SentTwitter {
+		x.Twitter++
+	}
+	if t.MadeCall {
+		x.Calls++
+		x.CallSeconds += t.CallDurationSeconds
+	}
+	if len(t.CallResult) != 0 {
+		x.CallResults[t.CallResult]++
+	}
+	if l.SubjectWasModified {
+		x.SubjectModified++
+	}
+	if l.MessageWasModified {
+		x.MessageModified++
+	}
+}
+
+// Outcomes returns the outcomes sorted by state, district, then name.
+func (p *Pivot) Outcomes() []*Outcome {
+	var a []*Outcome
+	for _, x := range p.m {
+		a = append(a, x)
+	}
+	sort.Slice(a, func(i, j int) bool {
+		ki := strings.Join([]string{a[i].State, a[i].DistrictName, a[i].TargetType, a[i].TargetName}, "\t")
+		kj := strings.Join([]string{a[j].State, a[j].DistrictName, a[j].TargetType, a[j].TargetName}, "\t")
+		return ki < kj
+	})
+	return a
+}
+
+// ByTarget groups actions by target.
+func ByTarget(t goengage.Target) Key {
+	return Key{
+		TargetID:       t.TargetID,
+		TargetName:     t.TargetName,
+		TargetTitle:    t.TargetTitle,
+		PoliticalParty: t.PoliticalParty,
+		TargetType:     t.TargetType,
+		State:          t.State,
+		DistrictName:   t.DistrictName,
+	}
+}
+
+// ByDistrict groups actions by target type, state and district.
+func ByDistrict(t goengage.Target) Key {
+	return Key{
+		TargetType:   t.TargetType,
+		State:        t.State,
+		DistrictName: t.DistrictName,
+	}
+}
+
+// Runtime holds the stuff that this app needs.
+type Runtime struct {
+	Env         *goengage.Environment
+	Span        report.Span
+	FormID      string
+	FormName    string
+	TargetTypes map[string]bool
+	Logger      *goengage.UtilLogger
+}
+
+// Keep returns true if the activity should be used.  Form IDs are
+// filtered by the search.
+func (rt *Runtime) Keep(r goengage.TargetedLetter) bool {
+	return len(rt.FormName) == 0 || r.ActivityFormName == rt.FormName
+}
+
+// Summarize reads targeted letters and accumulates them into the
+// provided pivots.  Returns the number of activities used.
+func (rt *Runtime) Summarize(pivots ...*Pivot) (int, error) {
+	ts := report.NewTimeSpan(rt.Span.S, rt.Span.E)
+	payload := goengage.ActivityRequestPayload{
+		ModifiedFrom: ts.Start,
+		ModifiedTo:   ts.End,
+	}
+	if len(rt.FormID) != 0 {
+		payload.ActivityFormIDs = []string{rt.FormID}
+	}
+	a, err := goengage.TargetedLetters(rt.Env, payload, rt.Logger)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, r := range a {
+		if !rt.Keep(r) {
+			continue
+		}
+		n++
+		for _, l := range r.Letters {
+			for _, t := range l.Targets {
+				if len(rt.TargetTypes) != 0 && !rt.TargetTypes[t.TargetType] {
+					continue
+				}
+				for _, p := range pivots {
+					p.Add(r.SupporterID, l, t)
+				}
+			}
+		}
+	}
+	return n, nil
+}
+
+// WriteOutcomes writes outcomes to a file in the output format.  The key
+// columns are the provided headers.  The key function returns the matching
+// values.
+func WriteOutcomes(opt output.Options, fn string, headers []string, key func(k Key) []string, a []*Outcome) error {
+	h := append([]string{}, headers...)
+	h = append(h,
+		"Actions",
+		"Supporters",
+		"Email",
+		"Facebook",
+		"Twitter",
+		"Calls",
+		"CallMinutes")
+	for _, c := range CallResults {
+		h = append(h, "Call"+strings.Replace(goengage.ToTitle(c), "_", "", -1))
+	}
+	h = append(h,
+		"SubjectModified",
+		"SubjectModifiedRate",
+		"MessageModified",
+		"MessageModifiedRate")
+	w, err := opt.Create(opt.Filename(fn), h)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	rate := func(n, d int) string {
+		if d == 0 {
+			return "0.0000"
+		}
+		return fmt.Sprintf("%.4f", float64(n)/float64(d))
+	}
+	for _, x := range a {
+		record := key(x.Key)
+		record = append(record,
+			fmt.Sprintf("%d", x.Actions),
+			fmt.Sprintf("%d", len(x.Supporters)),
+			fmt.Sprintf("%d", x.Email),
+			fmt.Sprintf("%d", x.Facebook),
+			fmt.Sprintf("%d", x.Twitter),
+			fmt.Sprintf("%d", x.Calls),
+			fmt.Sprintf("%.1f", float64(x.CallSeconds)/60.0))
+		for _, c := range CallResults {
+			record = append(record, fmt.Sprintf("%d", x.CallResults[c]))
+		}
+		record = append(record,
+			fmt.Sprintf("%d", x.SubjectModified),
+			rate(x.SubjectModified, x.Actions),
+			fmt.Sprintf("%d", x.MessageModified),
+			rate(x.MessageModified, x.Actions))
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	targetTypes := []string{
+		goengage.FederalExecutive,
+		goengage.FederalSenate,
+		goengage.FederalHouse,
+		goengage.StateExecutive,
+		goengage.StateSenate,
+		goengage.StateHouse,
+		goengage.USCounty,
+		goengage.USMunicipality,
+		goengage.CustomTarget,
+	}
+	var (
+		app          = kingpin.New("targets", "Summarize targeted letter outcomes by target and by district")
+		login        = app.Flag("login", "YAML file with API token").Required().String()
+		formID       = app.Flag("formId", "Only summarize this targeted letter form ID").String()
+		formName     = app.Flag("formName", "Only summarize this targeted letter name").String()
+		startDate    = app.Flag("startDate", "Start date, YYYY-MM-DD").Default("2000-01-01").String()
+		endDate      = app.Flag("endDate", "End date, YYYY-MM-DD, default is today").Default(time.Now().Format(report.BriefFormat)).String()
+		timeZone     = app.Flag("timezone", "Client's timezone, defaults to EST/EDT").Default("America/New_York").String()
+		targetType   = app.Flag("targetType", "Only summarize this target type.  Repeat for more types").Enums(targetTypes...)
+		targetFile   = app.Flag("targets", "CSV filename for the target summary").Default("letter_targets.csv").String()
+		districtFile = app.Flag("districts", "CSV filename for the district summary").Default("letter_districts.csv").String()
+		verbose      = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format       = app.Flag("format", "Output format, changes the output files' extensions").Default(output.CSV).Enum(output.Formats...)
+		gzip         = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		return err
+	}
+	rt := Runtime{
+		Env:         e,
+		Span:        report.ValidateSpan(*startDate, *endDate, location),
+		FormID:      *formID,
+		FormName:    *formName,
+		TargetTypes: make(map[string]bool),
+	}
+	for _, t := range *targetType {
+		rt.TargetTypes[t] = true
+	}
+	if *verbose {
+		rt.Logger, err = goengage.NewUtilLogger()
+		if err != nil {
+			return err
+		}
+	}
+
+	targets := NewPivot(ByTarget)
+	districts := NewPivot(ByDistrict)
+	n, err := rt.Summarize(targets, districts)
+	if err != nil {
+		return err
+	}
+	t := targets.Outcomes()
+	d := districts.Outcomes()
+	log.Printf("main: %d activities, %d targets, %d districts\n", n, len(t), len(d))
+
+	err = WriteOutcomes(opt, *targetFile,
+		[]string{"TargetID", "TargetName", "TargetTitle", "PoliticalParty", "TargetType", "State", "DistrictName"},
+		func(k Key) []string {
+			return []string{k.TargetID, k.TargetName, k.TargetTitle, k.PoliticalParty, k.TargetType, k.State, k.DistrictName}
+		},
+		t)
+	if err != nil {
+		return err
+	}
+	err = WriteOutcomes(opt, *districtFile,
+		[]string{"TargetType", "State", "DistrictName"},
+		func(k Key) []string {
+			return []string{k.TargetType, k.State, k.DistrictName}
+		},
+		d)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package main
+
+//Runs the targets app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	lettertargets "github.com/salsalabs/goengage/cmd/activity/targeted_letter/targets/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("targets", lettertargets.Main, os.Args[1:])
+}
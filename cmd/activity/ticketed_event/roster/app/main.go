@@ -0,0 +1,322 @@
+package roster
+
+//Application to write an attendee roster for a ticketed event.  The roster
+//has a line for each attendee of each ticket.  Purchasers and guests are
+//marked.  Answers to custom questions are pivoted into columns.  The
+//roster has an empty "CheckedIn" column for use at the door.
+//
+//A revenue summary shows the tickets sold for each ticket type.  Tickets
+//that were refunded or cancelled and refunded are subtracted from the
+//gross to get net revenue.
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	report "github.com/salsalabs/goengage/pkg/report"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Entry is a single line in the roster.
+type Entry struct {
+	Event    goengage.TicketedEvent
+	Ticket   goengage.Ticket
+	Attendee goengage.Attendee
+	Answers  map[string]string
+}
+
+// Summary holds the revenue for a single ticket type.
+type Summary struct {
+	TicketName string
+	Tickets    int
+	ByStatus   map[string]int
+	Gross      float64
+	Refunded   float64
+	Deductible float64
+}
+
+// Net returns gross revenue less refunds.
+func (s *Summary) Net() float64 {
+	return s.Gross - s.Refunded
+}
+
+// Statuses are the ticket statuses, in the order that they appear in the
+// summary.
+var Statuses = []string{
+	goengage.Valid,
+	goengage.Refunded,
+	goengage.Cancelled,
+	goengage.CancelledAndRefunded,
+	goengage.ValidAndRefunded,
+}
+
+// IsRefunded returns true if the ticket's money went back to the
+// purchaser.
+func IsRefunded(status string) bool {
+	switch status {
+	case goengage.Refunded, goengage.CancelledAndRefunded, goengage.ValidAndRefunded:
+		return true
+	}
+	return false
+}
+
+// Runtime holds the stuff that this app needs.
+type Runtime struct {
+	Env       *goengage.Environment
+	Span      report.Span
+	FormID    string
+	Logger    *goengage.UtilLogger
+	Questions []string
+}
+
+// Events returns the activities for the event form.
+func (rt *Runtime) Events() ([]goengage.TicketedEvent, error) {
+	ts := report.NewTimeSpan(rt.Span.S, rt.Span.E)
+	payload := goengage.ActivityRequestPayload{
+		ActivityFormIDs: []string{rt.FormID},
+		ModifiedFrom:    ts.Start,
+		ModifiedTo:      ts.End,
+	}
+	a, err := goengage.TicketedEvents(rt.Env, payload, rt.Logger)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Events: %d activities for form %s\n", len(a), rt.FormID)
+	return a, nil
+}
+
+// Roster returns the roster entries for the events.  Tickets without
+// attendees get one entry with an empty attendee.  Question texts are
+// remembered in the order that they were first seen.
+func (rt *Runtime) Roster(events []goengage.TicketedEvent) []Entry {
+	seen := make(map[string]bool)
+	answer := func(m map[string]string, questions []goengage.Question) {
+		for _, q := range questions {
+			if !seen[q.Question] {
+				seen[q.Question] = true
+				rt.Questions = append(rt.Questions, q.Question)
+			}
+			m[q.Question] = q.Answer
+		}
+	}
+	var a []Entry
+	for _, r := range events {
+		for _, t := range r.Tickets {
+			attendees := t.Attendees
+			if len(attendees) == 0 {
+				attendees = []goengage.Attendee{{}}
+			}
+			for _, x := range attendees {
+				m := make(map[string]string)
+				answer(m, t.Questions)
+				answer(m, x.Questions)
+				a = append(a, Entry{
+					Event:    r,
+					Ticket:   t,
+					Attendee: x,
+					Answers:  m,
+				})
+			}
+		}
+	}
+	sort.SliceStable(a, func(i, j int) bool {
+		if a[i].Attendee.LastName != a[j].Attendee.LastName {
+			return a[i].Attendee.LastName < a[j].Attendee.LastName
+		}
+		return a[i].Attendee.FirstName < a[j].Attendee.FirstName
+	})
+	return a
+}
+
+// Summarize returns the revenue summary by ticket name.  The last
+// summary is the total for all tickets.
+func Summarize(events []goengage.TicketedEvent) []*Summary {
+	m := make(map[string]*Summary)
+	total := &Summary{TicketName: "Total", ByStatus: make(map[string]int)}
+	for _, r := range events {
+		for _, t := range r.Tickets {
+			s, ok := m[t.TicketName]
+			if !ok {
+				s = &Summary{TicketName: t.TicketName, ByStatus: make(map[string]int)}
+				m[t.TicketName] = s
+			}
+			for _, x := range []*Summary{s, total} {
+				x.Tickets++
+				x.ByStatus[t.TicketStatus]++
+				x.Gross += t.TicketCost
+				if IsRefunded(t.TicketStatus) {
+					x.Refunded += t.TicketCost
+				} else {
+					x.Deductible += t.DeductibleAmount
+				}
+			}
+		}
+	}
+	var a []*Summary
+	for _, s := range m {
+		a = append(a, s)
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].TicketName < a[j].TicketName })
+	return append(a, total)
+}
+
+// WriteRoster writes the roster to a file in the output format.
+func (rt *Runtime) WriteRoster(opt output.Options, fn string, a []Entry) error {
+	headers := []string{
+		"CheckedIn",
+		"LastName",
+		"FirstName",
+		"AttendeeType",
+		"AttendeeStatus",
+		"Email",
+		"Phone",
+		"AddressLine1",
+		"AddressLine2",
+		"City",
+		"State",
+		"IsCurrentSupporter",
+		"TicketName",
+		"TicketStatus",
+		"TicketCost",
+		"TicketID",
+		"AttendeeID",
+		"ActivityID",
+		"ActivityDate",
+		"PurchaserSupporterID",
+	}
+	headers = append(headers, rt.Questions...)
+	w, err := opt.Create(opt.Filename(fn), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, x := range a {
+		p := x.Attendee
+		date := ""
+		if x.Event.ActivityDate != nil {
+			date = x.Event.ActivityDate.Format(report.BriefFormat)
+		}
+		record := []string{
+			"",
+			p.LastName,
+			p.FirstName,
+			goengage.ToTitle(p.Type),
+			p.Status,
+			p.Email,
+			p.Phone,
+			p.AdressLine1,
+			p.AdressLine2,
+			p.City,
+			p.State,
+			fmt.Sprintf("%v", p.IsCurrentSupporter),
+			x.Ticket.TicketName,
+			x.Ticket.TicketStatus,
+			fmt.Sprintf("%.2f", x.Ticket.TicketCost),
+			x.Ticket.TicketID,
+			p.AttendeeID,
+			x.Event.ActivityID,
+			date,
+			x.Event.SupporterID,
+		}
+		for _, q := range rt.Questions {
+			record = append(record, x.Answers[q])
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// WriteSummary writes the revenue summary to a file in the output format.
+func WriteSummary(opt output.Options, fn string, a []*Summary) error {
+	headers := []string{"TicketName", "Tickets"}
+	for _, s := range Statuses {
+		headers = append(headers, goengage.ToTitle(s))
+	}
+	headers = append(headers, "Gross", "Refunded", "Net", "Deductible")
+	w, err := opt.Create(opt.Filename(fn), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, s := range a {
+		record := []string{s.TicketName, fmt.Sprintf("%d", s.Tickets)}
+		for _, x := range Statuses {
+			record = append(record, fmt.Sprintf("%d", s.ByStatus[x]))
+		}
+		record = append(record,
+			fmt.Sprintf("%.2f", s.Gross),
+			fmt.Sprintf("%.2f", s.Refunded),
+			fmt.Sprintf("%.2f", s.Net()),
+			fmt.Sprintf("%.2f", s.Deductible))
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app         = kingpin.New("roster", "Write an attendee roster and a revenue summary for a ticketed event")
+		login       = app.Flag("login", "YAML file with API token").Required().String()
+		formID      = app.Flag("formId", "Ticketed event form ID").Required().String()
+		startDate   = app.Flag("startDate", "Start date, YYYY-MM-DD").Default("2000-01-01").String()
+		endDate     = app.Flag("endDate", "End date, YYYY-MM-DD, default is today").Default(time.Now().Format(report.BriefFormat)).String()
+		timeZone    = app.Flag("timezone", "Client's timezone, defaults to EST/EDT").Default("America/New_York").String()
+		rosterFile  = app.Flag("roster", "CSV filename for the attendee roster").Default("roster.csv").String()
+		summaryFile = app.Flag("summary", "CSV filename for the revenue summary").Default("roster_revenue.csv").String()
+		verbose     = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format      = app.Flag("format", "Output format, changes the output files' extensions").Default(output.CSV).Enum(output.Formats...)
+		gzip        = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		return err
+	}
+	rt := Runtime{
+		Env:    e,
+		Span:   report.ValidateSpan(*startDate, *endDate, location),
+		FormID: *formID,
+	}
+	if *verbose {
+		rt.Logger, err = goengage.NewUtilLogger()
+		if err != nil {
+			return err
+		}
+	}
+	events, err := rt.Events()
+	if err != nil {
+		return err
+	}
+	roster := rt.Roster(events)
+	err = rt.WriteRoster(opt, *rosterFile, roster)
+	if err != nil {
+		return err
+	}
+	summary := Summarize(events)
+	err = WriteSummary(opt, *summaryFile, summary)
+	if err != nil {
+		return err
+	}
+	total := summary[len(summary)-1]
+	log.Printf("main: %d attendees, %d tickets, net %.2f\n", len(roster), total.Tickets, total.Net())
+	return nil
+}
@@ -0,0 +1,16 @@
+package main
+
+//Runs the roster app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	eventroster "github.com/salsalabs/goengage/cmd/activity/ticketed_event/roster/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("roster", eventroster.Main, os.Args[1:])
+}
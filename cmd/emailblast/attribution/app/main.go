@@ -0,0 +1,464 @@
+package attribution
+
+// Application to attribute donation revenue to email blasts and comm
+// series.  Recipients of blasts and comm series are read for touches.  A
+// touch is a click or a conversion.  Donations in the date range are read
+// with their transactions.  Each donation is credited to the last touch
+// or to the first touch in a window before the donation date.  A
+// conversion whose activity ID matches the donation is always a touch.
+//
+// Revenue is the sum of charges less refunds in the date range.  A
+// donation is in the date range if it has a charge in the date range.
+// Donations are credited to the touch that preceded their first charge
+// in the date range.  Recurring gifts that started before the date range
+// are included, and are credited by their first installment in the date
+// range.  Later installments count for the same blast.
+//
+// Output is a CSV with a line for each blast and a CSV with a line for
+// each attributed donation.
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	report "github.com/salsalabs/goengage/pkg/report"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	//ReaderCount is the number of Engage readers to start.
+	ReaderCount = 3
+)
+
+// Attribution models.
+const (
+	LastTouch  = "last"
+	FirstTouch = "first"
+)
+
+// Touch is an interaction between a supporter and a blast that can
+// lead to a donation.
+type Touch struct {
+	BlastID     string
+	BlastName   string
+	BlastType   string
+	SeriesName  string
+	SupporterID string
+	ActivityID  string
+	Date        time.Time
+	Converted   bool
+}
+
+// Attribution is a donation and the touch that gets credit for it.
+// Charged is the donation's first charge in the span.
+type Attribution struct {
+	Fundraise goengage.Fundraise
+	Charged   *time.Time
+	Touch     *Touch
+	Revenue   float64
+}
+
+// Totals are the attributed results for a single blast.
+type Totals struct {
+	BlastID          string
+	BlastName        string
+	BlastType        string
+	Donations        int
+	OneTime          int
+	Recurring        int
+	Direct           int
+	OneTimeRevenue   float64
+	RecurringRevenue float64
+}
+
+// AttributionGuide is the report.Source proxy.
+type AttributionGuide struct {
+	Span       report.Span
+	Model      string
+	Window     time.Duration
+	ReadOffset int32
+	Logger     *goengage.UtilLogger
+	Touches    map[string][]*Touch
+}
+
+// TypeActivity returns the kind of activity being read.
+// Implements goengage.report.Source.
+func (g *AttributionGuide) TypeActivity() string {
+	return goengage.FundraiseType
+}
+
+// Filter returns true if the donation has a charge in the time span.
+// Implements goengage.report.Source.
+func (g *AttributionGuide) Filter(f goengage.Fundraise) bool {
+	return g.FirstCharge(f) != nil
+}
+
+// FirstCharge returns the date of the donation's first charge in the
+// time span, or nil if there isn't one.
+func (g *AttributionGuide) FirstCharge(f goengage.Fundraise) *time.Time {
+	var first *time.Time
+	for _, t := range f.Transactions {
+		if t.Type != goengage.Charge || t.Date == nil || t.Date.Before(g.Span.S) || t.Date.After(g.Span.E) {
+			continue
+		}
+		if first == nil || t.Date.Before(*first) {
+			first = t.Date
+		}
+	}
+	return first
+}
+
+// Readers returns the number of readers to start.
+// Implements goengage.report.Source.
+func (g *AttributionGuide) Readers() int {
+	return ReaderCount
+}
+
+// Offset returns the offset for the first read.
+// Implements goengage.report.Source.
+func (g *AttributionGuide) Offset() int32 {
+	return g.ReadOffset
+}
+
+// LookupSupporters returns false.  Attribution only needs supporter IDs.
+// Implements goengage.report.Lookup.
+func (g *AttributionGuide) LookupSupporters() bool {
+	return false
+}
+
+// ReadTouches reads blasts of the provided type that were published
+// between the start of the window and the end of the span.  Clicks and
+// conversions are stored as touches.
+func (g *AttributionGuide) ReadTouches(e *goengage.Environment, blastType string) error {
+	ts := report.NewTimeSpan(g.Span.S.Add(-g.Window), g.Span.E)
+	payload := goengage.EmailBlastSearchRequestPayload{
+		PublishedFrom: ts.Start,
+		PublishedTo:   ts.End,
+		Type:          blastType,
+	}
+	blasts, err := goengage.EmailBlasts(e, payload, g.Logger)
+	if err != nil {
+		return err
+	}
+	log.Printf("ReadTouches: %d %s blasts\n", len(blasts), blastType)
+	for _, b := range blasts {
+		n := 0
+		err := goengage.BlastRecipients(e, b.ID, blastType, g.Logger, func(r goengage.SingleBlastRecipient) error {
+			touch := func(date string, activityID string, converted bool) {
+				t, err := goengage.Date(date)
+				if err != nil || t == nil {
+					return
+				}
+				x := Touch{
+					BlastID:     b.ID,
+					BlastName:   b.Name,
+					BlastType:   blastType,
+					SeriesName:  r.EmailSeriesName,
+					SupporterID: r.SupporterID,
+					ActivityID:  activityID,
+					Date:        *t,
+					Converted:   converted,
+				}
+				g.Touches[r.SupporterID] = append(g.Touches[r.SupporterID], &x)
+				n++
+			}
+			for _, c := range r.ConversionData {
+				if c.ActivityType == goengage.FundraiseType || len(c.DonationType) != 0 {
+					touch(c.ConversionDate, c.ActivityID, true)
+				}
+			}
+			if r.Clicked && len(r.ConversionData) == 0 {
+				touch(r.TimeSent, "", false)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("ReadTouches: %s %s, %d touches\n", b.ID, b.Name, n)
+	}
+	return nil
+}
+
+// Credit returns the touch that gets credit for a donation, or nil if
+// there is no touch.  A conversion for the donation's activity wins.
+// Otherwise the model picks the first or last touch in the window before
+// the donation's first charge in the span.
+func (g *AttributionGuide) Credit(f goengage.Fundraise) *Touch {
+	first := g.FirstCharge(f)
+	if first == nil {
+		return nil
+	}
+	when := *first
+	var best *Touch
+	for _, t := range g.Touches[f.SupporterID] {
+		if t.Converted && t.ActivityID == f.ActivityID {
+			return t
+		}
+		if t.Date.After(when) || t.Date.Before(when.Add(-g.Window)) {
+			continue
+		}
+		switch {
+		case best == nil:
+			best = t
+		case g.Model == FirstTouch && t.Date.Before(best.Date):
+			best = t
+		case g.Model == LastTouch && t.Date.After(best.Date):
+			best = t
+		}
+	}
+	return best
+}
+
+// Revenue returns charges less refunds in the span.
+func (g *AttributionGuide) Revenue(f goengage.Fundraise) float64 {
+	var sum float64
+	for _, t := range f.Transactions {
+		if t.Date == nil || t.Date.Before(g.Span.S) || t.Date.After(g.Span.E) {
+			continue
+		}
+		switch t.Type {
+		case goengage.Charge:
+			sum += t.Amount
+		case goengage.Refund:
+			sum -= t.Amount
+		}
+	}
+	return sum
+}
+
+// Attribute credits donations to touches.  Donations without a touch
+// are returned with a nil Touch.
+func (g *AttributionGuide) Attribute(a []goengage.Fundraise) []Attribution {
+	var b []Attribution
+	for _, f := range a {
+		b = append(b, Attribution{
+			Fundraise: f,
+			Charged:   g.FirstCharge(f),
+			Touch:     g.Credit(f),
+			Revenue:   g.Revenue(f),
+		})
+	}
+	return b
+}
+
+// Summarize totals attributions by blast.  Returns the totals sorted by
+// revenue, highest first.
+func Summarize(a []Attribution) []*Totals {
+	m := make(map[string]*Totals)
+	for _, x := range a {
+		if x.Touch == nil {
+			continue
+		}
+		t, ok := m[x.Touch.BlastID]
+		if !ok {
+			t = &Totals{
+				BlastID:   x.Touch.BlastID,
+				BlastName: x.Touch.BlastName,
+				BlastType: x.Touch.BlastType,
+			}
+			m[x.Touch.BlastID] = t
+		}
+		t.Donations++
+		if x.Touch.Converted && x.Touch.ActivityID == x.Fundraise.ActivityID {
+			t.Direct++
+		}
+		if x.Fundraise.DonationType == goengage.Recurring {
+			t.Recurring++
+			t.RecurringRevenue += x.Revenue
+		} else {
+			t.OneTime++
+			t.OneTimeRevenue += x.Revenue
+		}
+	}
+	var b []*Totals
+	for _, t := range m {
+		b = append(b, t)
+	}
+	sort.Slice(b, func(i, j int) bool {
+		ri := b[i].OneTimeRevenue + b[i].RecurringRevenue
+		rj := b[j].OneTimeRevenue + b[j].RecurringRevenue
+		if ri != rj {
+			return ri > rj
+		}
+		return b[i].BlastName < b[j].BlastName
+	})
+	return b
+}
+
+// WriteTotals writes blast totals to a file in the output format.
+func WriteTotals(opt output.Options, fn string, a []*Totals) error {
+	headers := []string{
+		"BlastID",
+		"BlastName",
+		"BlastType",
+		"Donations",
+		"DirectConversions",
+		"OneTimeDonations",
+		"RecurringDonations",
+		"OneTimeRevenue",
+		"RecurringRevenue",
+		"TotalRevenue",
+	}
+	w, err := opt.Create(opt.Filename(fn), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, t := range a {
+		record := []string{
+			t.BlastID,
+			t.BlastName,
+			t.BlastType,
+			fmt.Sprintf("%d", t.Donations),
+			fmt.Sprintf("%d", t.Direct),
+			fmt.Sprintf("%d", t.OneTime),
+			fmt.Sprintf("%d", t.Recurring),
+			fmt.Sprintf("%.2f", t.OneTimeRevenue),
+			fmt.Sprintf("%.2f", t.RecurringRevenue),
+			fmt.Sprintf("%.2f", t.OneTimeRevenue+t.RecurringRevenue),
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// WriteDetails writes a line for each donation to a file in the output
+// format.  The date is the first charge in the span.  Unattributed donations
+// have empty blast columns.
+func WriteDetails(opt output.Options, fn string, a []Attribution) error {
+	headers := []string{
+		"ActivityID",
+		"ChargeDate",
+		"SupporterID",
+		"ActivityFormName",
+		"DonationType",
+		"Revenue",
+		"BlastID",
+		"BlastName",
+		"BlastType",
+		"SeriesName",
+		"TouchDate",
+		"Converted",
+	}
+	w, err := opt.Create(opt.Filename(fn), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, x := range a {
+		r := x.Fundraise
+		record := []string{
+			r.ActivityID,
+			"",
+			r.SupporterID,
+			r.ActivityFormName,
+			r.DonationType,
+			fmt.Sprintf("%.2f", x.Revenue),
+			"",
+			"",
+			"",
+			"",
+			"",
+			"",
+		}
+		if x.Charged != nil {
+			record[1] = x.Charged.Format(time.RFC3339)
+		}
+		if t := x.Touch; t != nil {
+			record[6] = t.BlastID
+			record[7] = t.BlastName
+			record[8] = t.BlastType
+			record[9] = t.SeriesName
+			record[10] = t.Date.Format(time.RFC3339)
+			record[11] = fmt.Sprintf("%v", t.Converted)
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app         = kingpin.New("attribution", "Attribute donation revenue to email blasts and comm series")
+		login       = app.Flag("login", "YAML file with API token").Required().String()
+		startDate   = app.Flag("startDate", "Start date, YYYY-MM-DD").Required().String()
+		endDate     = app.Flag("endDate", "End date, YYYY-MM-DD, default is today").Default(time.Now().Format(report.BriefFormat)).String()
+		timeZone    = app.Flag("timezone", "Client's timezone, defaults to EST/EDT").Default("America/New_York").String()
+		model       = app.Flag("model", "Credit the first or the last touch").Default(LastTouch).Enum(FirstTouch, LastTouch)
+		windowDays  = app.Flag("window", "Days before a donation that a touch can get credit").Default("30").Int()
+		totalsFile  = app.Flag("csv", "CSV filename for blast totals").Default("attribution.csv").String()
+		detailsFile = app.Flag("details", "CSV filename for donation details").Default("attribution_details.csv").String()
+		readOffset  = app.Flag("readOffset", "Start reading donations here, useful for restarts").Default("0").Int32()
+		verbose     = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format      = app.Flag("format", "Output format, changes the output files' extensions").Default(output.CSV).Enum(output.Formats...)
+		gzip        = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	if *windowDays < 1 {
+		return fmt.Errorf("--window must be at least 1, not %d", *windowDays)
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		return err
+	}
+	guide := &AttributionGuide{
+		Span:       report.ValidateSpan(*startDate, *endDate, location),
+		Model:      *model,
+		Window:     time.Duration(*windowDays) * 24 * time.Hour,
+		ReadOffset: *readOffset,
+		Touches:    make(map[string][]*Touch),
+	}
+	if *verbose {
+		guide.Logger, err = goengage.NewUtilLogger()
+		if err != nil {
+			return err
+		}
+	}
+	for _, t := range []string{goengage.EmailType, goengage.CommSeriesType} {
+		err = guide.ReadTouches(e, t)
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("main: touches for %d supporters\n", len(guide.Touches))
+
+	//Refunds modify the original donation.  Read through now to see them.
+	ts := report.NewTimeSpan(guide.Span.S, time.Now())
+	a, err := report.CollectFundraising(e, guide, ts)
+	if err != nil {
+		return err
+	}
+	attributions := guide.Attribute(a)
+	totals := Summarize(attributions)
+	log.Printf("main: %d donations, %d blasts credited\n", len(a), len(totals))
+
+	err = WriteTotals(opt, *totalsFile, totals)
+	if err != nil {
+		return err
+	}
+	err = WriteDetails(opt, *detailsFile, attributions)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package main
+
+//Runs the attribution app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	attribution "github.com/salsalabs/goengage/cmd/emailblast/attribution/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("attribution", attribution.Main, os.Args[1:])
+}
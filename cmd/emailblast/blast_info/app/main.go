@@ -0,0 +1,153 @@
+package blastinfo
+
+// An application to read all email blasts and write detailed info
+// into a file for each blast.  Uses the getBlastList endpoint from
+// Engage;s Web Developer API.
+//
+// See: https://api.salsalabs.org/help/web-dev#operation/getBlastList
+
+import (
+	"errors"
+	"fmt"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	report "github.com/salsalabs/goengage/pkg/report"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Runtime contains the configuration parts that this app needs.
+type Runtime struct {
+	Env            *goengage.Environment
+	Headers        []string
+	RequestPayload goengage.BlastListRequest
+	ResultChan     chan goengage.BlastListResult
+	DoneChan       chan bool
+	BlastOffset    int32
+	CSVWriter      output.Writer
+}
+
+// VisitContent formats and writes a BlastContent record.  Errors terminate.
+// Implements goengage.BlastListGuide.
+func (rt *Runtime) VisitContent(s goengage.BlastListResult, t goengage.BlastContent) error {
+	record := []string{
+		s.ID,
+		s.Name,
+		s.Status,
+		s.PublishDate.Format(goengage.EngageDateFormat),
+		t.Subject,
+		t.PageURL,
+	}
+	err := rt.CSVWriter.Write(record)
+	if err != nil {
+		return err
+	}
+	return rt.CSVWriter.Flush()
+}
+
+// Finalize is called after all blasts have been processed.
+// Implements goengage.BlastListGuide.
+func (rt *Runtime) Finalize() error {
+	return rt.CSVWriter.Close()
+}
+
+// Payload is a convenience method to define which blasts to return.
+// Each item is turned into a URL query at execution time.
+// Implements goengage.BlastListGuide.
+func (rt *Runtime) Payload() *goengage.BlastListRequest {
+	return &rt.RequestPayload
+}
+
+// ResultChannel is the listener channel for blast info.
+// Implements goengage.BlastListGuide.
+func (rt *Runtime) ResultChannel() chan goengage.BlastListResult {
+	return rt.ResultChan
+}
+
+// DoneChannel() receives a true when the listener is done.
+// Implements goengage.BlastListGuide.
+func (rt *Runtime) DoneChannel() chan bool {
+	return rt.DoneChan
+}
+
+//Offset() returns the offset to start reading.  Useful for
+//restarting after a service interruption.
+// Implements goengage.BlastListGuide.
+
+func (rt *Runtime) Offset() int32 {
+	return rt.BlastOffset
+}
+
+//Writer() returns the writer for the output file.
+
+func (rt *Runtime) Writer() output.Writer {
+	return rt.CSVWriter
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app          = kingpin.New("blast_urls", "Write email blast info (including URLs) to a CSV")
+		login        = app.Flag("login", "YAML file with API token").Required().String()
+		blastCSVFile = app.Flag("blast-csv", "CSV filename to store blast info").Default("email_blast_info.csv").String()
+		offset       = app.Flag("blast-offset", "Start here if you lose network connectivity").Default("0").Int32()
+		startDate    = app.Flag("start-date", "Engage-formatted start date").Default("2010-01-01T00:00:00.000Z").String()
+		endDate      = app.Flag("end-date", "Engage-formatted end date").Default("2030-01-01T00:00:00.000Z").String()
+		sortField    = app.Flag("sort-field", "Sort blasts by this field").Default(goengage.BlastSortName).Enum(goengage.BlastSortName, goengage.BlastSortDescription, goengage.BlastSortPublishDate, goengage.BlastSortStatus)
+		sortOrder    = app.Flag("sort-order", "Sort order").Default(goengage.Ascending).Enum(goengage.Ascending, goengage.Descending)
+		format       = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip         = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	if blastCSVFile == nil || len(*blastCSVFile) == 0 {
+		return errors.New("--blast-csv is required")
+	}
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+
+	requestPayload := goengage.BlastListRequest{
+		StartDate: *startDate,
+		EndDate:   *endDate,
+	}
+	requestPayload.SetSort(*sortField, *sortOrder)
+
+	rtx := Runtime{
+		Env: e,
+		Headers: []string{
+			"ID",
+			"Name",
+			"Status",
+			"PublishDate",
+			"Subject",
+			"PageURL",
+		},
+		ResultChan:     make(chan goengage.BlastListResult, 1000),
+		RequestPayload: requestPayload,
+		DoneChan:       make(chan bool),
+		BlastOffset:    *offset,
+	}
+	fn := opt.Filename(*blastCSVFile)
+	rtx.CSVWriter, err = opt.Create(fn, rtx.Headers)
+	if err != nil {
+		return fmt.Errorf("%v on %v", err, fn)
+	}
+	defer rtx.CSVWriter.Close()
+
+	//Start running.  The Guide does everything for this app.
+	err = report.ReportBlastLists(e, &rtx)
+	if err != nil {
+		return fmt.Errorf("%v running ReportBlastLists", err)
+	}
+	return nil
+}
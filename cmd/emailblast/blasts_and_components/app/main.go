@@ -0,0 +1,217 @@
+package blastsandcomponents
+
+// An application to read all email blasts and write CSVs for
+// the blasts and for components.  Components are said to be
+// valid only for comm series.  We'll test that.  If no CSV
+// appears for components, then we'll know that assertion to
+// be the truth.
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	pipeline "github.com/salsalabs/goengage/pkg/pipeline"
+	report "github.com/salsalabs/goengage/pkg/report"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Runtime contains the configuration parts that this app needs.
+type Runtime struct {
+	Env              *goengage.Environment
+	BlastOffset      int32
+	BlastCursor      *string
+	BlastCSVFile     string
+	ComponentCSVFile string
+	CommSeries       bool
+	Check            *goengage.Checkpoint
+	Out              output.Options
+	BlastCSV         output.Writer
+	ComponentCSV     output.Writer
+}
+
+// Payload is the request payload defining which supporters to retrieve.
+// Implements goengage.EmailBlastReader.
+func (rt *Runtime) Payload() goengage.EmailBlastSearchRequestPayload {
+	emailType := goengage.Email
+	log.Printf("Payload: comm series flag is %v\n", rt.CommSeries)
+	if rt.CommSeries {
+		emailType = goengage.CommSeries
+	}
+	payload := goengage.EmailBlastSearchRequestPayload{
+		Type:          emailType,
+		PublishedFrom: "2000-01-01T00:00:00.000Z",
+		PublishedTo:   "2030-01-01T00:00:00.000Z",
+	}
+	return payload
+}
+
+// Offset returns the offset to start reading.
+// Implements goengage.EmailBlastReader.
+func (rt *Runtime) Offset() int32 {
+	return rt.BlastOffset
+}
+
+// Checkpoint returns the checkpoint for restarts.
+// Implements goengage.report.Resumable.
+func (rt *Runtime) Checkpoint() *goengage.Checkpoint {
+	return rt.Check
+}
+
+// Open opens both output files and writes their headers.  Resumed runs
+// append to the files and skip blasts and components that were already
+// written.  Out's Window limits the blast rows that are checked.
+func (rt *Runtime) Open() (err error) {
+	headers := []string{
+		"ID",
+		"Topic",
+		"Name",
+		"Description",
+		"PublishDate",
+	}
+	opt := rt.Out
+	opt.Keys = []string{"ID"}
+	rt.BlastCSV, err = opt.Open(opt.Filename(rt.BlastCSVFile), headers, rt.Check.Resuming())
+	if err != nil {
+		return err
+	}
+	headers = []string{
+		"EmailActivityID",
+		"ContentID",
+		"Message",
+	}
+	//Blasts have any number of components, so every component row is
+	//matched.
+	opt = rt.Out
+	opt.Keys = []string{"EmailActivityID", "ContentID"}
+	opt.Window = 0
+	rt.ComponentCSV, err = opt.Open(opt.Filename(rt.ComponentCSVFile), headers, rt.Check.Resuming())
+	if err != nil {
+		rt.BlastCSV.Close()
+	}
+	return err
+}
+
+// Close closes both output files.
+func (rt *Runtime) Close() error {
+	err := rt.BlastCSV.Close()
+	if cerr := rt.ComponentCSV.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// WriteBlasts is a pipeline stage.  It writes a blast to the blast file,
+// then emits the blast for WriteComponents.
+func (rt *Runtime) WriteBlasts(ctx context.Context, x interface{}, emit pipeline.Emit) error {
+	r := x.(goengage.EmailActivity)
+	row := []string{
+		r.ID,
+		r.Topic,
+		r.Name,
+		r.Description,
+		r.PublishDate,
+	}
+	err := rt.BlastCSV.Write(row)
+	if err != nil {
+		return err
+	}
+	return emit(r)
+}
+
+// WriteComponents is the pipeline sink.  It writes any components for
+// a blast to the component file.
+func (rt *Runtime) WriteComponents(ctx context.Context, x interface{}) error {
+	r := x.(goengage.EmailActivity)
+	if r.Components != nil && len(*r.Components) > 0 {
+		for _, c := range *r.Components {
+			row := []string{
+				r.ID,
+				c.ContentID,
+				c.MessageNumber,
+			}
+			err := rt.ComponentCSV.Write(row)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app              = kingpin.New("blasts_and_components", "Write all email activity (blast) and component info to CSV files")
+		login            = app.Flag("login", "YAML file with API token").Required().String()
+		blastCSVFile     = app.Flag("blast-csv", "CSV filename to store blast info").Default("email_activity.csv").String()
+		componentCSVFile = app.Flag("component-csv", "CSV filename to store component info").Default("email_component.csv").String()
+		offset           = app.Flag("blast-offset", "Start here if you lose network connectivity").Default("0").Int32()
+		commSeries       = app.Flag("commseries", "Report on comm series and not on blasts").Bool()
+		resume           = app.Flag("resume", "Continue where the last run stopped and append to the CSVs").Bool()
+		format           = app.Flag("format", "Output format, changes the output files' extensions").Default(output.CSV).Enum(output.Formats...)
+		gzip             = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	log.Printf("main: commSeries is %v\n", *commSeries)
+	app.Parse(args)
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	if blastCSVFile == nil || len(*blastCSVFile) == 0 {
+		return errors.New("--blast-csv is required")
+	}
+	if componentCSVFile == nil || len(*componentCSVFile) == 0 {
+		return errors.New("--csv is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	cp, err := goengage.OpenCheckpoint(goengage.CheckpointFile(*blastCSVFile), *resume)
+	if err != nil {
+		return err
+	}
+
+	rt := &Runtime{
+		Env:              e,
+		BlastOffset:      *offset,
+		BlastCursor:      nil,
+		BlastCSVFile:     *blastCSVFile,
+		ComponentCSVFile: *componentCSVFile,
+		CommSeries:       *commSeries,
+		Check:            cp,
+		Out:              output.Options{Format: *format, Gzip: *gzip},
+	}
+
+	//Blasts flow from the reader to the blast CSV, then to the
+	//component CSV.
+	p := pipeline.NewPipeline("blasts_and_components").
+		Source("ReadEmailBlasts", report.EmailBlastSource(rt.Env, rt)).
+		Stage("WriteBlasts", 1, rt.WriteBlasts).
+		Sink("WriteComponents", rt.WriteComponents)
+	cp.Rewind = int32(p.Capacity())
+
+	//A resumed blast CSV can have the rewound blasts and the page that
+	//was being read when the last run stopped.
+	rt.Out.Window = int(cp.Rewind) + 2*int(e.BatchSize())
+	err = rt.Open()
+	if err != nil {
+		return err
+	}
+	err = p.Run(context.Background())
+	cerr := rt.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	err = cp.Finish()
+	if err != nil {
+		return err
+	}
+	log.Printf("main: done")
+	return nil
+}
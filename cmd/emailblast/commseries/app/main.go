@@ -0,0 +1,250 @@
+package commseries
+
+// Application to report on comm series (autoresponders).  Each series is
+// listed with its components in message number order.  Recipients are
+// read for each component to show per-step recipients, opens, clicks and
+// the drop-off from the previous step.  Series without components are
+// split into steps using the recipients' email series name.
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Step holds the statistics for a single step in a comm series.
+type Step struct {
+	MessageNumber string
+	ContentID     string
+	Name          string
+	Stats         *goengage.BlastStats
+	DropOff       int
+	DropOffRate   float64
+}
+
+// Series is a comm series and its steps.
+type Series struct {
+	Blast goengage.EmailActivity
+	Steps []*Step
+}
+
+// Runtime is the internal data store for this app.
+type Runtime struct {
+	Env           *goengage.Environment
+	PublishedFrom string
+	PublishedTo   string
+	Logger        *goengage.UtilLogger
+}
+
+// MessageOrder returns a sortable value for a message number.  Numbers
+// that don't parse sort last.
+func MessageOrder(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return int(^uint(0) >> 1)
+	}
+	return n
+}
+
+// Components returns a series' components sorted by message number.
+func Components(b goengage.EmailActivity) []goengage.EmailComponent {
+	if b.Components == nil {
+		return nil
+	}
+	a := make([]goengage.EmailComponent, len(*b.Components))
+	copy(a, *b.Components)
+	sort.SliceStable(a, func(i, j int) bool {
+		return MessageOrder(a[i].MessageNumber) < MessageOrder(a[j].MessageNumber)
+	})
+	return a
+}
+
+// ByComponent reads the recipients for each component in a series.
+func (rt *Runtime) ByComponent(b goengage.EmailActivity, components []goengage.EmailComponent) ([]*Step, error) {
+	var a []*Step
+	for _, c := range components {
+		step := Step{
+			MessageNumber: c.MessageNumber,
+			ContentID:     c.ContentID,
+			Stats:         goengage.NewBlastStats(b.ID, b.Name, ""),
+		}
+		err := goengage.ComponentRecipients(rt.Env, b.ID, c.ContentID, goengage.CommSeriesType, rt.Logger, func(r goengage.SingleBlastRecipient) error {
+			if len(step.Name) == 0 {
+				step.Name = r.EmailSeriesName
+			}
+			step.Stats.Add(r)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, &step)
+	}
+	return a, nil
+}
+
+// BySeriesName reads all recipients for a series and makes a step for
+// each email series name.  Steps are in the order that names are first
+// seen.
+func (rt *Runtime) BySeriesName(b goengage.EmailActivity) ([]*Step, error) {
+	var a []*Step
+	m := make(map[string]*Step)
+	err := goengage.BlastRecipients(rt.Env, b.ID, goengage.CommSeriesType, rt.Logger, func(r goengage.SingleBlastRecipient) error {
+		step, ok := m[r.EmailSeriesName]
+		if !ok {
+			step = &Step{
+				MessageNumber: fmt.Sprintf("%d", len(a)+1),
+				Name:          r.EmailSeriesName,
+				Stats:         goengage.NewBlastStats(b.ID, b.Name, ""),
+			}
+			m[r.EmailSeriesName] = step
+			a = append(a, step)
+		}
+		step.Stats.Add(r)
+		return nil
+	})
+	return a, err
+}
+
+// OneSeries reads the steps for a series and computes drop-off.
+func (rt *Runtime) OneSeries(b goengage.EmailActivity) (*Series, error) {
+	log.Printf("OneSeries: %s, %s\n", b.ID, b.Name)
+	s := Series{Blast: b}
+	var err error
+	components := Components(b)
+	if len(components) != 0 {
+		s.Steps, err = rt.ByComponent(b, components)
+	} else {
+		s.Steps, err = rt.BySeriesName(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(s.Steps); i++ {
+		prev := s.Steps[i-1].Stats.Sent
+		step := s.Steps[i]
+		step.DropOff = prev - step.Stats.Sent
+		if prev > 0 {
+			step.DropOffRate = float64(step.DropOff) / float64(prev)
+		}
+	}
+	return &s, nil
+}
+
+// WriteSteps writes a line for each step in each series to a file in the
+// output format.
+func WriteSteps(opt output.Options, fn string, a []*Series) error {
+	headers := []string{
+		"SeriesID",
+		"SeriesName",
+		"PublishDate",
+		"MessageNumber",
+		"ContentID",
+		"StepName",
+		"Recipients",
+		"Delivered",
+		"Opened",
+		"Clicked",
+		"Unsubscribed",
+		"Bounced",
+		"OpenRate",
+		"ClickRate",
+		"DropOff",
+		"DropOffRate",
+	}
+	w, err := opt.Create(opt.Filename(fn), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, s := range a {
+		for _, step := range s.Steps {
+			x := step.Stats
+			record := []string{
+				s.Blast.ID,
+				s.Blast.Name,
+				s.Blast.PublishDate,
+				step.MessageNumber,
+				step.ContentID,
+				step.Name,
+				fmt.Sprintf("%d", x.Sent),
+				fmt.Sprintf("%d", x.Delivered),
+				fmt.Sprintf("%d", x.Opened),
+				fmt.Sprintf("%d", x.Clicked),
+				fmt.Sprintf("%d", x.Unsubscribed),
+				fmt.Sprintf("%d", x.Bounced),
+				fmt.Sprintf("%.4f", x.OpenRate()),
+				fmt.Sprintf("%.4f", x.ClickRate()),
+				fmt.Sprintf("%d", step.DropOff),
+				fmt.Sprintf("%.4f", step.DropOffRate),
+			}
+			err = w.Write(record)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return w.Close()
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app           = kingpin.New("commseries", "Report per-step results for comm series")
+		login         = app.Flag("login", "YAML file with API token").Required().String()
+		csvFile       = app.Flag("csv", "CSV filename for the report").Default("commseries.csv").String()
+		publishedFrom = app.Flag("published-from", "Engage-formatted start date").Default("2000-01-01T00:00:00.000Z").String()
+		publishedTo   = app.Flag("published-to", "Engage-formatted end date, optional").String()
+		verbose       = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format        = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip          = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	rt := Runtime{
+		Env:           e,
+		PublishedFrom: *publishedFrom,
+		PublishedTo:   *publishedTo,
+	}
+	if *verbose {
+		rt.Logger, err = goengage.NewUtilLogger()
+		if err != nil {
+			return err
+		}
+	}
+	payload := goengage.EmailBlastSearchRequestPayload{
+		PublishedFrom: rt.PublishedFrom,
+		PublishedTo:   rt.PublishedTo,
+		Type:          goengage.CommSeriesType,
+	}
+	blasts, err := goengage.EmailBlasts(rt.Env, payload, rt.Logger)
+	if err != nil {
+		return err
+	}
+	log.Printf("main: %d comm series\n", len(blasts))
+	var a []*Series
+	for _, b := range blasts {
+		s, err := rt.OneSeries(b)
+		if err != nil {
+			return err
+		}
+		a = append(a, s)
+	}
+	err = WriteSteps(opt, *csvFile, a)
+	if err != nil {
+		return err
+	}
+	return nil
+}
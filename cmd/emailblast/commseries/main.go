@@ -0,0 +1,16 @@
+package main
+
+//Runs the commseries app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	commseries "github.com/salsalabs/goengage/cmd/emailblast/commseries/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("commseries", commseries.Main, os.Args[1:])
+}
@@ -0,0 +1,124 @@
+package engagement
+
+// Application to score supporter email engagement.  Recipients of every
+// blast published in a window are read into per-supporter profiles.  Each
+// profile gets a recency/frequency score from 0 to 100.  Profiles are
+// written to a file, lowest scores first.  Scores can optionally be stored
+// in a supporter custom field so that segments can suppress the
+// chronically unengaged.
+import (
+	"fmt"
+	"log"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	engagement "github.com/salsalabs/goengage/pkg/engagement"
+	output "github.com/salsalabs/goengage/pkg/output"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// WriteProfiles writes profiles to a file in the output format.
+func WriteProfiles(opt output.Options, fn string, a []*engagement.Profile) error {
+	headers := []string{
+		"SupporterID",
+		"Email",
+		"Sends",
+		"Opens",
+		"Clicks",
+		"Conversions",
+		"LastSend",
+		"LastOpen",
+		"Recency",
+		"Frequency",
+		"Score",
+	}
+	w, err := opt.Create(opt.Filename(fn), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	date := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	for _, x := range a {
+		record := []string{
+			x.SupporterID,
+			x.Email,
+			fmt.Sprintf("%d", x.Sends),
+			fmt.Sprintf("%d", x.Opens),
+			fmt.Sprintf("%d", x.Clicks),
+			fmt.Sprintf("%d", x.Conversions),
+			date(x.LastSend),
+			date(x.LastOpen),
+			fmt.Sprintf("%.4f", x.Recency),
+			fmt.Sprintf("%.4f", x.Frequency),
+			fmt.Sprintf("%d", x.Score),
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app           = kingpin.New("engagement", "Score supporter email engagement for blasts published in a window")
+		login         = app.Flag("login", "YAML file with API token").Required().String()
+		publishedFrom = app.Flag("published-from", "Engage-formatted start date").Required().String()
+		publishedTo   = app.Flag("published-to", "Engage-formatted end date, optional").String()
+		halfLife      = app.Flag("half-life", "Days for the recency score to fall by half").Default("90").Int()
+		recencyWeight = app.Flag("recency-weight", "Weight of recency in the score, 0 through 1").Default("0.5").Float64()
+		csvFile       = app.Flag("csv", "CSV filename for the profiles").Default("engagement.csv").String()
+		fieldID       = app.Flag("field-id", "Optional custom field ID to receive the score").String()
+		fieldName     = app.Flag("field-name", "Name of the custom field that receives the score").Default("Engagement Score").String()
+		verbose       = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format        = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip          = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	if *halfLife < 1 {
+		return fmt.Errorf("--half-life must be at least 1, not %d", *halfLife)
+	}
+	if *recencyWeight < 0 || *recencyWeight > 1 {
+		return fmt.Errorf("--recency-weight must be between 0 and 1, not %v", *recencyWeight)
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	var logger *goengage.UtilLogger
+	if *verbose {
+		logger, err = goengage.NewUtilLogger()
+		if err != nil {
+			return err
+		}
+	}
+
+	p := engagement.NewProfiles(time.Now(), time.Duration(*halfLife)*24*time.Hour, *recencyWeight)
+	err = p.Collect(e, *publishedFrom, *publishedTo, logger)
+	if err != nil {
+		return err
+	}
+	a := p.All()
+	err = WriteProfiles(opt, *csvFile, a)
+	if err != nil {
+		return err
+	}
+	log.Printf("main: %d profiles written to %s\n", len(a), opt.Filename(*csvFile))
+
+	if len(*fieldID) == 0 {
+		return nil
+	}
+	return engagement.WriteScores(e, a, *fieldID, *fieldName, logger)
+}
@@ -0,0 +1,16 @@
+package main
+
+//Runs the engagement app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	engagement "github.com/salsalabs/goengage/cmd/emailblast/engagement/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("engagement", engagement.Main, os.Args[1:])
+}
@@ -0,0 +1,410 @@
+package hygiene
+
+// Application to keep email lists clean.  Recipients of blasts published
+// since a watermark date are scanned for bounces.  Supporters with a hard
+// bounce go into a "Hard bounce" segment.  Supporters with repeated soft
+// bounces go into a "Repeat soft bounce" segment.  A report shows
+// every supporter that was classified.  Use --dry-run to see the report
+// without changing segments.
+//
+// The watermark is the publish date of the earliest blast to scan.  It
+// comes from --since or from the watermark file.  The watermark file is
+// updated after segments are successfully assigned.
+//
+// Soft bounces are counted across runs.  The history file holds the soft
+// bounces for supporters that were not classified yet, and is updated
+// with the watermark.  The history isn't read when --since is used, so
+// rescanning old blasts doesn't count their bounces twice.
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Classifications for supporters.
+const (
+	HardBounce       = "Hard bounce"
+	RepeatSoftBounce = "Repeat soft bounce"
+)
+
+// Bouncer holds the bounce history for a single supporter.
+type Bouncer struct {
+	SupporterID    string
+	Email          string
+	Hard           int
+	Soft           int
+	Unsubscribed   bool
+	LastCategory   string
+	LastCode       string
+	LastBlastName  string
+	LastBounceDate string
+	Classification string
+}
+
+// Runtime is the internal data store for this app.
+type Runtime struct {
+	Env       *goengage.Environment
+	Since     string
+	SoftLimit int
+	Logger    *goengage.UtilLogger
+	Bouncers  map[string]*Bouncer
+}
+
+// HistoryHeaders are the columns in the soft bounce history file.
+var HistoryHeaders = []string{
+	"SupporterID",
+	"Email",
+	"SoftBounces",
+	"LastBounceCategory",
+	"LastBounceCode",
+	"LastBlastName",
+	"LastBounceDate",
+}
+
+// IsHard returns true if a bounce category is a hard bounce.
+func IsHard(category string) bool {
+	return strings.Contains(strings.ToUpper(category), "HARD")
+}
+
+// ReadWatermark returns the watermark from a file.  A missing file
+// returns an empty string.
+func ReadWatermark(fn string) (string, error) {
+	b, err := os.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// WriteWatermark writes a watermark to a file.
+func WriteWatermark(fn string, t time.Time) error {
+	s := t.UTC().Format(goengage.EngageDateFormat) + "\n"
+	return os.WriteFile(fn, []byte(s), 0644)
+}
+
+// ReadHistory adds the soft bounces from earlier runs to the bouncers.
+// A missing file is not an error.
+func (rt *Runtime) ReadHistory(fn string) error {
+	f, err := os.Open(fn)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return fmt.Errorf("%s: %v", fn, err)
+	}
+	for i, r := range records {
+		if i == 0 {
+			continue
+		}
+		if len(r) != len(HistoryHeaders) {
+			return fmt.Errorf("%s: line %d has %d fields, not %d", fn, i+1, len(r), len(HistoryHeaders))
+		}
+		n, err := strconv.Atoi(r[2])
+		if err != nil {
+			return fmt.Errorf("%s: line %d: %v", fn, i+1, err)
+		}
+		rt.Bouncers[r[0]] = &Bouncer{
+			SupporterID:    r[0],
+			Email:          r[1],
+			Soft:           n,
+			LastCategory:   r[3],
+			LastCode:       r[4],
+			LastBlastName:  r[5],
+			LastBounceDate: r[6],
+		}
+	}
+	log.Printf("ReadHistory: soft bounces for %d supporters from %s\n", len(records)-1, fn)
+	return nil
+}
+
+// WriteHistory writes the soft bounces for supporters that haven't been
+// classified.  Classified supporters are in a segment and start over.
+func (rt *Runtime) WriteHistory(fn string) error {
+	var a []*Bouncer
+	for _, x := range rt.Bouncers {
+		if x.Soft > 0 && x.Hard == 0 && len(x.Classification) == 0 {
+			a = append(a, x)
+		}
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].SupporterID < a[j].SupporterID })
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	err = w.Write(HistoryHeaders)
+	if err != nil {
+		return err
+	}
+	for _, x := range a {
+		record := []string{
+			x.SupporterID,
+			x.Email,
+			fmt.Sprintf("%d", x.Soft),
+			x.LastCategory,
+			x.LastCode,
+			x.LastBlastName,
+			x.LastBounceDate,
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Scan reads the recipients of all blasts published since the watermark
+// and records the bounces.
+func (rt *Runtime) Scan() error {
+	payload := goengage.EmailBlastSearchRequestPayload{
+		PublishedFrom: rt.Since,
+		Type:          goengage.EmailType,
+	}
+	blasts, err := goengage.EmailBlasts(rt.Env, payload, rt.Logger)
+	if err != nil {
+		return err
+	}
+	log.Printf("Scan: %d blasts since %s\n", len(blasts), rt.Since)
+	for _, b := range blasts {
+		n := 0
+		err := goengage.BlastRecipients(rt.Env, b.ID, goengage.EmailType, rt.Logger, func(r goengage.SingleBlastRecipient) error {
+			if len(r.BounceCategory) == 0 || len(r.SupporterID) == 0 {
+				return nil
+			}
+			n++
+			x, ok := rt.Bouncers[r.SupporterID]
+			if !ok {
+				x = &Bouncer{
+					SupporterID: r.SupporterID,
+					Email:       r.SupporterEmail,
+				}
+				rt.Bouncers[r.SupporterID] = x
+			}
+			if IsHard(r.BounceCategory) {
+				x.Hard++
+			} else {
+				x.Soft++
+			}
+			x.Unsubscribed = x.Unsubscribed || r.Unsubscribed
+			if r.TimeSent >= x.LastBounceDate {
+				x.LastCategory = r.BounceCategory
+				x.LastCode = r.BounceCode
+				x.LastBlastName = b.Name
+				x.LastBounceDate = r.TimeSent
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Scan: blast %s, %s, %d bounces\n", b.ID, b.Name, n)
+	}
+	return nil
+}
+
+// Classify decides which segment, if any, each bouncer belongs in.
+// Returns the classified bouncers sorted by email.
+func (rt *Runtime) Classify() []*Bouncer {
+	var a []*Bouncer
+	for _, x := range rt.Bouncers {
+		switch {
+		case x.Hard > 0:
+			x.Classification = HardBounce
+		case x.Soft >= rt.SoftLimit:
+			x.Classification = RepeatSoftBounce
+		default:
+			continue
+		}
+		a = append(a, x)
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].Email < a[j].Email })
+	return a
+}
+
+// Assign puts the bouncers with a classification into a segment.  Does
+// nothing if the segment ID is empty.
+func (rt *Runtime) Assign(segmentID string, classification string, a []*Bouncer) error {
+	if len(segmentID) == 0 {
+		log.Printf("Assign: no segment for %s, skipping\n", classification)
+		return nil
+	}
+	var ids []string
+	for _, x := range a {
+		if x.Classification == classification {
+			ids = append(ids, x.SupporterID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	results, err := goengage.AssignSupporters(rt.Env, segmentID, ids, rt.Logger)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		if r.Result != goengage.Added && r.Result != goengage.Updated {
+			log.Printf("Assign: %s, supporter %s, %s\n", classification, r.SupporterID, r.Result)
+		}
+	}
+	log.Printf("Assign: %s, segment %s, %d supporters\n", classification, segmentID, len(ids))
+	return nil
+}
+
+// WriteReport writes the classified bouncers to a file in the output
+// format.
+func WriteReport(opt output.Options, fn string, a []*Bouncer) error {
+	headers := []string{
+		"SupporterID",
+		"Email",
+		"Classification",
+		"HardBounces",
+		"SoftBounces",
+		"Unsubscribed",
+		"LastBounceCategory",
+		"LastBounceCode",
+		"LastBlastName",
+		"LastBounceDate",
+	}
+	w, err := opt.Create(opt.Filename(fn), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, x := range a {
+		record := []string{
+			x.SupporterID,
+			x.Email,
+			x.Classification,
+			fmt.Sprintf("%d", x.Hard),
+			fmt.Sprintf("%d", x.Soft),
+			fmt.Sprintf("%v", x.Unsubscribed),
+			x.LastCategory,
+			x.LastCode,
+			x.LastBlastName,
+			x.LastBounceDate,
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app           = kingpin.New("hygiene", "Put hard bounces and repeated soft bounces into segments")
+		login         = app.Flag("login", "YAML file with API token").Required().String()
+		since         = app.Flag("since", "Engage-formatted watermark date, overrides the watermark file.  The watermark and history files are not changed").String()
+		watermarkFile = app.Flag("watermark", "File that holds the watermark between runs").Default("hygiene_watermark.txt").String()
+		historyFile   = app.Flag("history", "CSV file that holds soft bounce counts between runs").Default("hygiene_history.csv").String()
+		hardSegment   = app.Flag("hard-segment", "Segment ID for the \"Hard bounce\" segment").String()
+		softSegment   = app.Flag("soft-segment", "Segment ID for the \"Repeat soft bounce\" segment").String()
+		softLimit     = app.Flag("soft-limit", "Soft bounces needed to be a repeat soft bounce").Default("3").Int()
+		csvFile       = app.Flag("csv", "CSV filename for the report").Default("hygiene.csv").String()
+		dryRun        = app.Flag("dry-run", "Write the report but don't change segments").Bool()
+		verbose       = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format        = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip          = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	if *softLimit < 1 {
+		return fmt.Errorf("--soft-limit must be at least 1, not %d", *softLimit)
+	}
+	if !*dryRun && len(*hardSegment) == 0 && len(*softSegment) == 0 {
+		return errors.New("use --hard-segment and/or --soft-segment, or use --dry-run")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	rt := Runtime{
+		Env:       e,
+		Since:     *since,
+		SoftLimit: *softLimit,
+		Bouncers:  make(map[string]*Bouncer),
+	}
+	if len(rt.Since) == 0 {
+		rt.Since, err = ReadWatermark(*watermarkFile)
+		if err != nil {
+			return err
+		}
+		err = rt.ReadHistory(*historyFile)
+		if err != nil {
+			return err
+		}
+	}
+	if len(rt.Since) == 0 {
+		return fmt.Errorf("no watermark in %s, use --since", *watermarkFile)
+	}
+	if *verbose {
+		rt.Logger, err = goengage.NewUtilLogger()
+		if err != nil {
+			return err
+		}
+	}
+
+	started := time.Now()
+	err = rt.Scan()
+	if err != nil {
+		return err
+	}
+	a := rt.Classify()
+	err = WriteReport(opt, *csvFile, a)
+	if err != nil {
+		return err
+	}
+	log.Printf("main: %d bouncers, %d classified, see %s\n", len(rt.Bouncers), len(a), opt.Filename(*csvFile))
+	if *dryRun {
+		log.Println("main: dry run, segments not changed")
+		return nil
+	}
+	err = rt.Assign(*hardSegment, HardBounce, a)
+	if err != nil {
+		return err
+	}
+	err = rt.Assign(*softSegment, RepeatSoftBounce, a)
+	if err != nil {
+		return err
+	}
+	if len(*since) != 0 {
+		log.Println("main: --since, watermark and history not changed")
+		return nil
+	}
+	err = rt.WriteHistory(*historyFile)
+	if err != nil {
+		return err
+	}
+	err = WriteWatermark(*watermarkFile, started)
+	if err != nil {
+		return err
+	}
+	return nil
+}
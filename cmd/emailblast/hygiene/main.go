@@ -0,0 +1,16 @@
+package main
+
+//Runs the hygiene app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	hygiene "github.com/salsalabs/goengage/cmd/emailblast/hygiene/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("hygiene", hygiene.Main, os.Args[1:])
+}
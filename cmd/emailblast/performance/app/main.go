@@ -0,0 +1,258 @@
+package performance
+
+// Application to summarize email blast performance.  Recipient data is
+// accumulated for each blast and for each split in a blast.  Output
+// contains counts, rates, conversion revenue and time-to-first-open
+// distributions.  Results are written to a file in the output format and
+// to a JSON file.
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Runtime is the internal data store for this app.
+type Runtime struct {
+	Env           *goengage.Environment
+	PublishedFrom string
+	PublishedTo   string
+	BlastID       string
+	BlastType     string
+	Logger        *goengage.UtilLogger
+}
+
+// Performance holds the statistics for one blast and its splits.
+type Performance struct {
+	Blast  *goengage.BlastStats   `json:"blast"`
+	Splits []*goengage.BlastStats `json:"splits,omitempty"`
+}
+
+// Blasts returns the blasts to summarize.
+func (rt *Runtime) Blasts() ([]goengage.EmailActivity, error) {
+	payload := goengage.EmailBlastSearchRequestPayload{
+		ID:            rt.BlastID,
+		PublishedFrom: rt.PublishedFrom,
+		PublishedTo:   rt.PublishedTo,
+		Type:          rt.BlastType,
+	}
+	return goengage.EmailBlasts(rt.Env, payload, rt.Logger)
+}
+
+// OneBlast reads the recipients for a blast and accumulates statistics
+// for the blast and for each split.
+func (rt *Runtime) OneBlast(r goengage.EmailActivity) (*Performance, error) {
+	log.Printf("OneBlast: blast ID: %s, Name: %s\n", r.ID, r.Name)
+	p := Performance{
+		Blast: goengage.NewBlastStats(r.ID, r.Name, ""),
+	}
+	splits := make(map[string]*goengage.BlastStats)
+	err := goengage.BlastRecipients(rt.Env, r.ID, rt.BlastType, rt.Logger, func(x goengage.SingleBlastRecipient) error {
+		p.Blast.Add(x)
+		if len(x.SplitName) != 0 {
+			s, ok := splits[x.SplitName]
+			if !ok {
+				s = goengage.NewBlastStats(r.ID, r.Name, x.SplitName)
+				splits[x.SplitName] = s
+			}
+			s.Add(x)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range splits {
+		p.Splits = append(p.Splits, s)
+	}
+	sort.Slice(p.Splits, func(i, j int) bool {
+		return p.Splits[i].SplitName < p.Splits[j].SplitName
+	})
+	return &p, nil
+}
+
+// BounceCategories returns the sorted list of bounce categories
+// that appear in the statistics.
+func BounceCategories(a []*Performance) []string {
+	m := make(map[string]bool)
+	for _, p := range a {
+		for k := range p.Blast.Bounces {
+			m[k] = true
+		}
+	}
+	var b []string
+	for k := range m {
+		b = append(b, k)
+	}
+	sort.Strings(b)
+	return b
+}
+
+// Headers returns the CSV headers.  Bounce categories and time-to-open
+// buckets each get a column.
+func Headers(categories []string) []string {
+	a := []string{
+		"BlastID",
+		"BlastName",
+		"SplitName",
+		"Sent",
+		"Delivered",
+		"Opened",
+		"Clicked",
+		"Converted",
+		"Unsubscribed",
+		"Bounced",
+		"OpenRate",
+		"ClickRate",
+		"ClickToOpenRate",
+		"ConversionRate",
+		"UnsubscribeRate",
+		"BounceRate",
+		"Conversions",
+		"Revenue",
+		"MedianMinutesToOpen",
+	}
+	for _, c := range categories {
+		a = append(a, fmt.Sprintf("Bounced%s", goengage.ToTitle(c)))
+	}
+	for _, x := range goengage.OpenBuckets {
+		a = append(a, fmt.Sprintf("Opened%s", x.Name))
+	}
+	return a
+}
+
+// Line returns a CSV row for a set of statistics.
+func Line(s *goengage.BlastStats, categories []string) []string {
+	a := []string{
+		s.BlastID,
+		s.BlastName,
+		s.SplitName,
+		fmt.Sprintf("%d", s.Sent),
+		fmt.Sprintf("%d", s.Delivered),
+		fmt.Sprintf("%d", s.Opened),
+		fmt.Sprintf("%d", s.Clicked),
+		fmt.Sprintf("%d", s.Converted),
+		fmt.Sprintf("%d", s.Unsubscribed),
+		fmt.Sprintf("%d", s.Bounced),
+		fmt.Sprintf("%.4f", s.OpenRate()),
+		fmt.Sprintf("%.4f", s.ClickRate()),
+		fmt.Sprintf("%.4f", s.ClickToOpenRate()),
+		fmt.Sprintf("%.4f", s.ConversionRate()),
+		fmt.Sprintf("%.4f", s.UnsubscribeRate()),
+		fmt.Sprintf("%.4f", s.BounceRate()),
+		fmt.Sprintf("%d", s.Conversions),
+		fmt.Sprintf("%.2f", s.Revenue),
+		fmt.Sprintf("%.1f", s.MedianTimeToOpen().Minutes()),
+	}
+	for _, c := range categories {
+		a = append(a, fmt.Sprintf("%d", s.Bounces[c]))
+	}
+	for _, x := range goengage.OpenBuckets {
+		a = append(a, fmt.Sprintf("%d", s.FirstOpens[x.Name]))
+	}
+	return a
+}
+
+// WriteSummary writes a line for each blast, followed by a line for
+// each of the blast's splits.  The file is in the output format.
+func WriteSummary(opt output.Options, fn string, a []*Performance) error {
+	categories := BounceCategories(a)
+	w, err := opt.Create(opt.Filename(fn), Headers(categories))
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, p := range a {
+		err = w.Write(Line(p.Blast, categories))
+		if err != nil {
+			return err
+		}
+		for _, s := range p.Splits {
+			err = w.Write(Line(s, categories))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return w.Close()
+}
+
+// WriteJSON writes the statistics to a JSON file.
+func WriteJSON(fn string, a []*Performance) error {
+	b, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fn, b, 0644)
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app           = kingpin.New("performance", "Summarize email blast performance for blasts published in a date range")
+		login         = app.Flag("login", "YAML file with API token").Required().String()
+		csvFile       = app.Flag("csv", "CSV filename for the summary").Default("blast_performance.csv").String()
+		jsonFile      = app.Flag("json", "JSON filename for the summary").Default("blast_performance.json").String()
+		publishedFrom = app.Flag("published-from", "Engage-formatted start date").Default("2021-03-12T00:00:00.000Z").String()
+		publishedTo   = app.Flag("published-to", "Engage-formatted end date, optional").String()
+		blastID       = app.Flag("blast", "Only summarize this blast ID").String()
+		commSeries    = app.Flag("commseries", "Summarize comm series and not blasts").Bool()
+		verbose       = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format        = app.Flag("format", "Output format for --csv, changes the file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip          = app.Flag("gzip", "Compress the --csv output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	rt := Runtime{
+		Env:           e,
+		PublishedFrom: *publishedFrom,
+		PublishedTo:   *publishedTo,
+		BlastID:       *blastID,
+		BlastType:     goengage.EmailType,
+	}
+	if *commSeries {
+		rt.BlastType = goengage.CommSeriesType
+	}
+	if *verbose {
+		rt.Logger, err = goengage.NewUtilLogger()
+		if err != nil {
+			return err
+		}
+	}
+	blasts, err := rt.Blasts()
+	if err != nil {
+		return err
+	}
+	log.Printf("main: %d blasts\n", len(blasts))
+	var a []*Performance
+	for _, r := range blasts {
+		p, err := rt.OneBlast(r)
+		if err != nil {
+			return err
+		}
+		a = append(a, p)
+	}
+	err = WriteSummary(opt, *csvFile, a)
+	if err != nil {
+		return err
+	}
+	err = WriteJSON(*jsonFile, a)
+	if err != nil {
+		return err
+	}
+	log.Printf("main: wrote %s and %s\n", opt.Filename(*csvFile), *jsonFile)
+	return nil
+}
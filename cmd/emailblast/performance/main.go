@@ -0,0 +1,16 @@
+package main
+
+//Runs the performance app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	performance "github.com/salsalabs/goengage/cmd/emailblast/performance/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("performance", performance.Main, os.Args[1:])
+}
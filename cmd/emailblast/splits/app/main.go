@@ -0,0 +1,282 @@
+package splits
+
+// Application to analyze an A/B split test for an email blast.  Recipients
+// are grouped by split.  Open, click and conversion rates are compared with
+// confidence intervals.  The leading split for each rate is tested against
+// each of the others using a two-proportion z-test.  A split wins a rate
+// when its lead over every other split is significant.
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Rates compared between splits.
+const (
+	Opens       = "Opens"
+	Clicks      = "Clicks"
+	Conversions = "Conversions"
+)
+
+// Metric returns a function that returns the numerator for a rate.
+// The denominator is always the delivered count.
+var Metric = map[string]func(s *goengage.BlastStats) int{
+	Opens:       func(s *goengage.BlastStats) int { return s.Opened },
+	Clicks:      func(s *goengage.BlastStats) int { return s.Clicked },
+	Conversions: func(s *goengage.BlastStats) int { return s.Converted },
+}
+
+// Comparison is the result for a single split and a single rate.
+type Comparison struct {
+	Metric      string
+	Split       *goengage.BlastStats
+	Count       int
+	Rate        float64
+	Low         float64
+	High        float64
+	Leader      bool
+	Z           float64
+	PValue      float64
+	Significant bool
+}
+
+// Runtime is the internal data store for this app.
+type Runtime struct {
+	Env        *goengage.Environment
+	BlastID    string
+	BlastType  string
+	Confidence float64
+	Logger     *goengage.UtilLogger
+}
+
+// Splits reads the recipients for the blast and returns statistics for
+// each split, sorted by split name.  Recipients without a split name
+// are ignored.
+func (rt *Runtime) Splits() ([]*goengage.BlastStats, error) {
+	m := make(map[string]*goengage.BlastStats)
+	err := goengage.BlastRecipients(rt.Env, rt.BlastID, rt.BlastType, rt.Logger, func(x goengage.SingleBlastRecipient) error {
+		if len(x.SplitName) == 0 {
+			return nil
+		}
+		s, ok := m[x.SplitName]
+		if !ok {
+			s = goengage.NewBlastStats(rt.BlastID, "", x.SplitName)
+			m[x.SplitName] = s
+		}
+		s.Add(x)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var a []*goengage.BlastStats
+	for _, s := range m {
+		a = append(a, s)
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].SplitName < a[j].SplitName })
+	return a, nil
+}
+
+// ZScore returns the two-sided critical value for a confidence level.
+// For example, 0.95 returns about 1.96.
+func ZScore(confidence float64) float64 {
+	return math.Sqrt2 * math.Erfinv(confidence)
+}
+
+// Interval returns the normal-approximation confidence interval for
+// x successes in n trials.  The interval is clipped to [0, 1].
+func Interval(x, n int, z float64) (low, high float64) {
+	if n == 0 {
+		return 0, 0
+	}
+	p := float64(x) / float64(n)
+	m := z * math.Sqrt(p*(1-p)/float64(n))
+	return math.Max(0, p-m), math.Min(1, p+m)
+}
+
+// TwoProportion returns the z statistic and two-sided p-value for the
+// hypothesis that x1/n1 and x2/n2 have the same underlying rate.  The
+// p-value is 1 when the test can't be performed.
+func TwoProportion(x1, n1, x2, n2 int) (z, p float64) {
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+	p1 := float64(x1) / float64(n1)
+	p2 := float64(x2) / float64(n2)
+	pooled := float64(x1+x2) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 0, 1
+	}
+	z = (p1 - p2) / se
+	p = math.Erfc(math.Abs(z) / math.Sqrt2)
+	return z, p
+}
+
+// Compare returns comparisons for one rate.  The leader is the split with
+// the highest rate.  Every other split is tested against the leader.  The
+// leader's result holds the weakest of its tests.  Returns the winning
+// split name, or an empty string if no split wins.
+func (rt *Runtime) Compare(metric string, splits []*goengage.BlastStats) ([]Comparison, string) {
+	count := Metric[metric]
+	z := ZScore(rt.Confidence)
+	alpha := 1 - rt.Confidence
+
+	var a []Comparison
+	leader := -1
+	for i, s := range splits {
+		c := Comparison{
+			Metric: metric,
+			Split:  s,
+			Count:  count(s),
+			Rate:   s.Rate(count(s)),
+		}
+		c.Low, c.High = Interval(c.Count, s.Delivered, z)
+		a = append(a, c)
+		if leader < 0 || c.Rate > a[leader].Rate {
+			leader = i
+		}
+	}
+	if leader < 0 {
+		return a, ""
+	}
+	lead := &a[leader]
+	lead.Leader = true
+	lead.Significant = len(a) > 1
+	for i := range a {
+		if i == leader {
+			continue
+		}
+		c := &a[i]
+		c.Z, c.PValue = TwoProportion(lead.Count, lead.Split.Delivered, c.Count, c.Split.Delivered)
+		c.Significant = c.PValue < alpha
+		if c.PValue >= lead.PValue {
+			lead.Z, lead.PValue = c.Z, c.PValue
+		}
+		if !c.Significant {
+			lead.Significant = false
+		}
+	}
+	if !lead.Significant {
+		return a, ""
+	}
+	return a, lead.Split.SplitName
+}
+
+// WriteComparisons writes the comparisons to a file in the output format.
+func WriteComparisons(opt output.Options, fn string, a []Comparison, winners map[string]string) error {
+	headers := []string{
+		"Metric",
+		"SplitName",
+		"Delivered",
+		"Count",
+		"Rate",
+		"Low",
+		"High",
+		"Leader",
+		"ZVsLeader",
+		"PValueVsLeader",
+		"Significant",
+		"Winner",
+	}
+	w, err := opt.Create(opt.Filename(fn), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, c := range a {
+		record := []string{
+			c.Metric,
+			c.Split.SplitName,
+			fmt.Sprintf("%d", c.Split.Delivered),
+			fmt.Sprintf("%d", c.Count),
+			fmt.Sprintf("%.4f", c.Rate),
+			fmt.Sprintf("%.4f", c.Low),
+			fmt.Sprintf("%.4f", c.High),
+			fmt.Sprintf("%v", c.Leader),
+			fmt.Sprintf("%.3f", c.Z),
+			fmt.Sprintf("%.4f", c.PValue),
+			fmt.Sprintf("%v", c.Significant),
+			fmt.Sprintf("%v", winners[c.Metric] == c.Split.SplitName),
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app        = kingpin.New("splits", "Analyze an A/B split test for an email blast")
+		login      = app.Flag("login", "YAML file with API token").Required().String()
+		blastID    = app.Flag("blast", "Blast ID to analyze").Required().String()
+		commSeries = app.Flag("commseries", "The blast ID is for a comm series").Bool()
+		confidence = app.Flag("confidence", "Confidence level for intervals and tests").Default("0.95").Float64()
+		csvFile    = app.Flag("csv", "CSV filename for the analysis").Default("splits.csv").String()
+		verbose    = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format     = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip       = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	if *confidence <= 0 || *confidence >= 1 {
+		return fmt.Errorf("confidence must be between 0 and 1, not %v", *confidence)
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	rt := Runtime{
+		Env:        e,
+		BlastID:    *blastID,
+		BlastType:  goengage.EmailType,
+		Confidence: *confidence,
+	}
+	if *commSeries {
+		rt.BlastType = goengage.CommSeriesType
+	}
+	if *verbose {
+		rt.Logger, err = goengage.NewUtilLogger()
+		if err != nil {
+			return err
+		}
+	}
+	splits, err := rt.Splits()
+	if err != nil {
+		return err
+	}
+	if len(splits) < 2 {
+		return fmt.Errorf("blast %s has %d splits, need at least two", rt.BlastID, len(splits))
+	}
+	log.Printf("main: %d splits\n", len(splits))
+
+	var a []Comparison
+	winners := make(map[string]string)
+	for _, m := range []string{Opens, Clicks, Conversions} {
+		b, winner := rt.Compare(m, splits)
+		a = append(a, b...)
+		winners[m] = winner
+		if len(winner) == 0 {
+			log.Printf("main: %-11s no significant winner\n", m)
+		} else {
+			log.Printf("main: %-11s winner is %s\n", m, winner)
+		}
+	}
+	err = WriteComparisons(opt, *csvFile, a, winners)
+	if err != nil {
+		return err
+	}
+	return nil
+}
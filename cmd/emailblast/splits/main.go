@@ -0,0 +1,16 @@
+package main
+
+//Runs the splits app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	splits "github.com/salsalabs/goengage/cmd/emailblast/splits/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("splits", splits.Main, os.Args[1:])
+}
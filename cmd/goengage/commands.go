@@ -0,0 +1,371 @@
+package main
+
+import (
+	basehowmany "github.com/salsalabs/goengage/cmd/activity/base/how_many/app"
+	basesee "github.com/salsalabs/goengage/cmd/activity/base/see/app"
+	dedication "github.com/salsalabs/goengage/cmd/activity/fundraise/dedication/app"
+	lybunt "github.com/salsalabs/goengage/cmd/activity/fundraise/lybunt/app"
+	rollup "github.com/salsalabs/goengage/cmd/activity/fundraise/rollup/app"
+	fundraisesee "github.com/salsalabs/goengage/cmd/activity/fundraise/see/app"
+	fundraisesupporter "github.com/salsalabs/goengage/cmd/activity/fundraise/supporter/app"
+	petitionexport "github.com/salsalabs/goengage/cmd/activity/petition/export/app"
+	petitionsee "github.com/salsalabs/goengage/cmd/activity/petition/see/app"
+	petitionsummarize "github.com/salsalabs/goengage/cmd/activity/petition/summarize/app"
+	lettersee "github.com/salsalabs/goengage/cmd/activity/targeted_letter/see/app"
+	lettersummarize "github.com/salsalabs/goengage/cmd/activity/targeted_letter/summarize/app"
+	lettertargets "github.com/salsalabs/goengage/cmd/activity/targeted_letter/targets/app"
+	eventroster "github.com/salsalabs/goengage/cmd/activity/ticketed_event/roster/app"
+	eventsee "github.com/salsalabs/goengage/cmd/activity/ticketed_event/see/app"
+	attribution "github.com/salsalabs/goengage/cmd/emailblast/attribution/app"
+	blastinfo "github.com/salsalabs/goengage/cmd/emailblast/blast_info/app"
+	blastsandcomponents "github.com/salsalabs/goengage/cmd/emailblast/blasts_and_components/app"
+	commseries "github.com/salsalabs/goengage/cmd/emailblast/commseries/app"
+	engagement "github.com/salsalabs/goengage/cmd/emailblast/engagement/app"
+	hygiene "github.com/salsalabs/goengage/cmd/emailblast/hygiene/app"
+	performance "github.com/salsalabs/goengage/cmd/emailblast/performance/app"
+	recipients "github.com/salsalabs/goengage/cmd/emailblast/recipients/app"
+	splits "github.com/salsalabs/goengage/cmd/emailblast/splits/app"
+	metrics "github.com/salsalabs/goengage/cmd/metrics/app"
+	donationimport "github.com/salsalabs/goengage/cmd/offline_donation/import/app"
+	report "github.com/salsalabs/goengage/cmd/report/app"
+	segments "github.com/salsalabs/goengage/cmd/segments/app"
+	segmentstates "github.com/salsalabs/goengage/cmd/segments/one_segment_states/app"
+	segmentsupporters "github.com/salsalabs/goengage/cmd/segments/one_segment_supporters/app"
+	segmentxref "github.com/salsalabs/goengage/cmd/segments/one_segment_xref/app"
+	seesegments "github.com/salsalabs/goengage/cmd/segments/see_segments/app"
+	segmentsandsupporters "github.com/salsalabs/goengage/cmd/segments/segments_and_supporters/app"
+	customfielddistribution "github.com/salsalabs/goengage/cmd/supporter/custom_field_distribution/app"
+	findcustomfield "github.com/salsalabs/goengage/cmd/supporter/find_custom_field/app"
+	fixkludgedfields "github.com/salsalabs/goengage/cmd/supporter/fix_kludged_fields/app"
+	phonenumbers "github.com/salsalabs/goengage/cmd/supporter/phone_numbers/app"
+	searchbyemail "github.com/salsalabs/goengage/cmd/supporter/search_by_email/app"
+	searchbyid "github.com/salsalabs/goengage/cmd/supporter/search_by_id/app"
+	searchbylastmodified "github.com/salsalabs/goengage/cmd/supporter/search_by_last_modified/app"
+	seedistrictsall "github.com/salsalabs/goengage/cmd/supporter/see_districts_all/app"
+	segmentsforall "github.com/salsalabs/goengage/cmd/supporter/segments_for_all/app"
+	segmentsforsome "github.com/salsalabs/goengage/cmd/supporter/segments_for_some/app"
+	supporterupdatecustomfield "github.com/salsalabs/goengage/cmd/supporter/update_custom_field/app"
+	zipcitystatelookup "github.com/salsalabs/goengage/cmd/supporter/zip_city_state_lookup/app"
+	transactionactivityform "github.com/salsalabs/goengage/cmd/transaction/activity_form/app"
+	reconcile "github.com/salsalabs/goengage/cmd/transaction/reconcile/app"
+	transactionsupporter "github.com/salsalabs/goengage/cmd/transaction/supporter/app"
+	templates "github.com/salsalabs/goengage/cmd/transaction/templates/app"
+	updatecustomfield "github.com/salsalabs/goengage/cmd/update_custom_field/app"
+)
+
+// Global flags that commands can accept.  Every command accepts --login.
+// TimezoneFlag is set from the profile.
+const (
+	LoginFlag    = "login"
+	VerboseFlag  = "verbose"
+	OutputFlag   = "output"
+	FormatFlag   = "format"
+	TimezoneFlag = "timezone"
+	ResumeFlag   = "resume"
+	GzipFlag     = "gzip"
+)
+
+// Command is a single command in the goengage command tree.  Flags maps
+// the global flags that the command supports to the command's own flag
+// names.
+type Command struct {
+	Path  []string
+	Dir   string
+	Main  func(args []string) error
+	Flags map[string]string
+}
+
+// Commands is the goengage command tree.  Each command is the app in
+// Dir/app.  Paths mirror the cmd directory.
+var Commands = []Command{
+	{
+		Path: []string{"activity", "base", "how-many"},
+		Dir:  "activity/base/how_many",
+		Main: basehowmany.Main,
+	},
+	{
+		Path:  []string{"activity", "base", "see"},
+		Dir:   "activity/base/see",
+		Main:  basesee.Main,
+		Flags: map[string]string{OutputFlag: "output", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"activity", "fundraise", "dedication"},
+		Dir:   "activity/fundraise/dedication",
+		Main:  dedication.Main,
+		Flags: map[string]string{TimezoneFlag: "timezone", ResumeFlag: "resume", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"activity", "fundraise", "lybunt"},
+		Dir:   "activity/fundraise/lybunt",
+		Main:  lybunt.Main,
+		Flags: map[string]string{OutputFlag: "lybunt", TimezoneFlag: "timezone", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"activity", "fundraise", "rollup"},
+		Dir:   "activity/fundraise/rollup",
+		Main:  rollup.Main,
+		Flags: map[string]string{OutputFlag: "csv", TimezoneFlag: "timezone", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"activity", "fundraise", "see"},
+		Dir:   "activity/fundraise/see",
+		Main:  fundraisesee.Main,
+		Flags: map[string]string{TimezoneFlag: "timezone", ResumeFlag: "resume", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"activity", "fundraise", "supporter"},
+		Dir:   "activity/fundraise/supporter",
+		Main:  fundraisesupporter.Main,
+		Flags: map[string]string{TimezoneFlag: "timezone", ResumeFlag: "resume", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"activity", "petition", "export"},
+		Dir:   "activity/petition/export",
+		Main:  petitionexport.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "csv", TimezoneFlag: "timezone", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path: []string{"activity", "petition", "see"},
+		Dir:  "activity/petition/see",
+		Main: petitionsee.Main,
+	},
+	{
+		Path:  []string{"activity", "petition", "summarize"},
+		Dir:   "activity/petition/summarize",
+		Main:  petitionsummarize.Main,
+		Flags: map[string]string{OutputFlag: "output", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path: []string{"activity", "targeted-letter", "see"},
+		Dir:  "activity/targeted_letter/see",
+		Main: lettersee.Main,
+	},
+	{
+		Path: []string{"activity", "targeted-letter", "summarize"},
+		Dir:  "activity/targeted_letter/summarize",
+		Main: lettersummarize.Main,
+	},
+	{
+		Path:  []string{"activity", "targeted-letter", "targets"},
+		Dir:   "activity/targeted_letter/targets",
+		Main:  lettertargets.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "targets", TimezoneFlag: "timezone", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"activity", "ticketed-event", "roster"},
+		Dir:   "activity/ticketed_event/roster",
+		Main:  eventroster.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "roster", TimezoneFlag: "timezone", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path: []string{"activity", "ticketed-event", "see"},
+		Dir:  "activity/ticketed_event/see",
+		Main: eventsee.Main,
+	},
+	{
+		Path:  []string{"emailblast", "attribution"},
+		Dir:   "emailblast/attribution",
+		Main:  attribution.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "csv", TimezoneFlag: "timezone", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"emailblast", "blast-info"},
+		Dir:   "emailblast/blast_info",
+		Main:  blastinfo.Main,
+		Flags: map[string]string{OutputFlag: "blast-csv", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"emailblast", "blasts-and-components"},
+		Dir:   "emailblast/blasts_and_components",
+		Main:  blastsandcomponents.Main,
+		Flags: map[string]string{OutputFlag: "blast-csv", ResumeFlag: "resume", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"emailblast", "commseries"},
+		Dir:   "emailblast/commseries",
+		Main:  commseries.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "csv", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"emailblast", "engagement"},
+		Dir:   "emailblast/engagement",
+		Main:  engagement.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "csv", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"emailblast", "hygiene"},
+		Dir:   "emailblast/hygiene",
+		Main:  hygiene.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "csv", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"emailblast", "performance"},
+		Dir:   "emailblast/performance",
+		Main:  performance.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "csv", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"emailblast", "recipients"},
+		Dir:   "emailblast/recipients",
+		Main:  recipients.Main,
+		Flags: map[string]string{OutputFlag: "recipients", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"emailblast", "splits"},
+		Dir:   "emailblast/splits",
+		Main:  splits.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "csv", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path: []string{"metrics"},
+		Dir:  "metrics",
+		Main: metrics.Main,
+	},
+	{
+		Path:  []string{"offline-donation", "import"},
+		Dir:   "offline_donation/import",
+		Main:  donationimport.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "results", TimezoneFlag: "timezone", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"report"},
+		Dir:   "report",
+		Main:  report.Main,
+		Flags: map[string]string{OutputFlag: "output", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"segments", "summarize"},
+		Dir:   "segments",
+		Main:  segments.Main,
+		Flags: map[string]string{OutputFlag: "csv", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"segments", "states"},
+		Dir:   "segments/one_segment_states",
+		Main:  segmentstates.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"segments", "supporters"},
+		Dir:   "segments/one_segment_supporters",
+		Main:  segmentsupporters.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "results", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"segments", "xref"},
+		Dir:   "segments/one_segment_xref",
+		Main:  segmentxref.Main,
+		Flags: map[string]string{OutputFlag: "csv", ResumeFlag: "resume"},
+	},
+	{
+		Path:  []string{"segments", "see"},
+		Dir:   "segments/see_segments",
+		Main:  seesegments.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "csv", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"segments", "with-supporters"},
+		Dir:   "segments/segments_and_supporters",
+		Main:  segmentsandsupporters.Main,
+		Flags: map[string]string{OutputFlag: "csv", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"supporter", "custom-field-distribution"},
+		Dir:   "supporter/custom_field_distribution",
+		Main:  customfielddistribution.Main,
+		Flags: map[string]string{FormatFlag: "format"},
+	},
+	{
+		Path:  []string{"supporter", "find-custom-field"},
+		Dir:   "supporter/find_custom_field",
+		Main:  findcustomfield.Main,
+		Flags: map[string]string{FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"supporter", "fix-kludged-fields"},
+		Dir:   "supporter/fix_kludged_fields",
+		Main:  fixkludgedfields.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "results", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"supporter", "phone-numbers"},
+		Dir:   "supporter/phone_numbers",
+		Main:  phonenumbers.Main,
+		Flags: map[string]string{OutputFlag: "output", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path: []string{"supporter", "search-by-email"},
+		Dir:  "supporter/search_by_email",
+		Main: searchbyemail.Main,
+	},
+	{
+		Path: []string{"supporter", "search-by-id"},
+		Dir:  "supporter/search_by_id",
+		Main: searchbyid.Main,
+	},
+	{
+		Path:  []string{"supporter", "search-by-last-modified"},
+		Dir:   "supporter/search_by_last_modified",
+		Main:  searchbylastmodified.Main,
+		Flags: map[string]string{FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"supporter", "see-districts-all"},
+		Dir:   "supporter/see_districts_all",
+		Main:  seedistrictsall.Main,
+		Flags: map[string]string{FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"supporter", "segments-for-all"},
+		Dir:   "supporter/segments_for_all",
+		Main:  segmentsforall.Main,
+		Flags: map[string]string{OutputFlag: "output", ResumeFlag: "resume", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"supporter", "segments-for-some"},
+		Dir:   "supporter/segments_for_some",
+		Main:  segmentsforsome.Main,
+		Flags: map[string]string{OutputFlag: "output", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path: []string{"supporter", "update-custom-field"},
+		Dir:  "supporter/update_custom_field",
+		Main: supporterupdatecustomfield.Main,
+	},
+	{
+		Path:  []string{"supporter", "zip-city-state-lookup"},
+		Dir:   "supporter/zip_city_state_lookup",
+		Main:  zipcitystatelookup.Main,
+		Flags: map[string]string{OutputFlag: "csv", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"transaction", "activity-form"},
+		Dir:   "transaction/activity_form",
+		Main:  transactionactivityform.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "csv", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"transaction", "reconcile"},
+		Dir:   "transaction/reconcile",
+		Main:  reconcile.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "csv", TimezoneFlag: "timezone", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"transaction", "supporter"},
+		Dir:   "transaction/supporter",
+		Main:  transactionsupporter.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "csv", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path:  []string{"transaction", "templates"},
+		Dir:   "transaction/templates",
+		Main:  templates.Main,
+		Flags: map[string]string{VerboseFlag: "verbose", OutputFlag: "templates", TimezoneFlag: "timezone", FormatFlag: "format", GzipFlag: "gzip"},
+	},
+	{
+		Path: []string{"update-custom-field"},
+		Dir:  "update_custom_field",
+		Main: updatecustomfield.Main,
+	},
+}
@@ -0,0 +1,269 @@
+package main
+
+// Application that runs all of the goengage apps as subcommands.  The
+// command tree mirrors the cmd directory.  For example,
+//
+//	goengage --login login.yaml supporter search-by-email --email x@y.org
+//
+// runs the app in cmd/supporter/search_by_email.  Global flags appear
+// before the command.  They are passed to the command using the
+// command's own flag names.  Everything after the command goes to the
+// command unchanged.
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// DefaultConfig is the credentials file in the home directory that is
+// used when --login isn't provided.
+const DefaultConfig = ".goengage.yaml"
+
+// Name returns the command's name as it appears on the command line.
+func (c Command) Name() string {
+	return strings.Join(c.Path, " ")
+}
+
+// Args returns the arguments for the command.  Global flags that were
+// used are converted to the command's flags and go before the command's
+// arguments.  Returns an error if a global flag was used and the command
+// doesn't support it.  Defaults come from the profile.  They are only
+// used if the command supports them and they aren't in the arguments.
+func (c Command) Args(global map[string]string, defaults map[string]string, args []string) ([]string, error) {
+	flag := func(k string) (string, bool) {
+		if k == LoginFlag {
+			return LoginFlag, true
+		}
+		name, ok := c.Flags[k]
+		return name, ok
+	}
+	var a []string
+	for _, k := range Keys(global) {
+		name, ok := flag(k)
+		if !ok {
+			return nil, fmt.Errorf("%s does not support --%s", c.Name(), k)
+		}
+		a = append(a, fmt.Sprintf("--%s=%s", name, global[k]))
+	}
+	for _, k := range Keys(defaults) {
+		if _, ok := global[k]; ok {
+			continue
+		}
+		name, ok := flag(k)
+		if !ok || Has(args, name) {
+			continue
+		}
+		a = append(a, fmt.Sprintf("--%s=%s", name, defaults[k]))
+	}
+	return append(a, args...), nil
+}
+
+// Keys returns the keys of a map in alphabetical order.
+func Keys(m map[string]string) []string {
+	var a []string
+	for k := range m {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+	return a
+}
+
+// Has returns true if a flag appears in the arguments.
+func Has(args []string, name string) bool {
+	for _, s := range args {
+		if s == "--"+name || strings.HasPrefix(s, "--"+name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultLogin returns the credentials file to use when --login isn't
+// provided.  That's the file in GOENGAGE_CONFIG, then DefaultConfig in
+// the home directory if it exists.  Returns an empty string if there's
+// no file.
+func DefaultLogin() string {
+	fn := os.Getenv(goengage.ConfigEnv)
+	if len(fn) != 0 {
+		return fn
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	fn = filepath.Join(home, DefaultConfig)
+	if _, err := os.Stat(fn); err != nil {
+		return ""
+	}
+	return fn
+}
+
+// Find returns the command whose path is the longest prefix of the
+// provided words, and the words that follow the path.  Returns nil if
+// no command matches.
+func Find(words []string) (*Command, []string) {
+	var found *Command
+	for i := range Commands {
+		c := &Commands[i]
+		if len(c.Path) > len(words) {
+			continue
+		}
+		match := true
+		for j, p := range c.Path {
+			if words[j] != p {
+				match = false
+				break
+			}
+		}
+		if match && (found == nil || len(c.Path) > len(found.Path)) {
+			found = c
+		}
+	}
+	if found == nil {
+		return nil, words
+	}
+	return found, words[len(found.Path):]
+}
+
+// Tree returns the commands that start with the provided words, one per
+// line, with the app directory for each.
+func Tree(words []string) string {
+	prefix := strings.Join(words, " ")
+	var a []string
+	for _, c := range Commands {
+		name := c.Name()
+		if len(prefix) != 0 && name != prefix && !strings.HasPrefix(name, prefix+" ") {
+			continue
+		}
+		a = append(a, fmt.Sprintf("    %-45s cmd/%s", name, c.Dir))
+	}
+	return strings.Join(a, "\n")
+}
+
+// Budget sets the API call budget.  The calls left in the quota come
+// from the profile's metrics.  Returns an error if the metrics can't be
+// read.
+func Budget(maxCalls int, p goengage.Profile) error {
+	goengage.APICalls.SetBudget(maxCalls)
+	if len(p.Token) == 0 || p.MaxBatchSize != 0 {
+		return nil
+	}
+	e, err := goengage.OpenEnvironment(p.Hostname(), p.Token)
+	if err != nil {
+		return err
+	}
+	m, err := e.CurrentMetrics()
+	if err != nil {
+		return err
+	}
+	goengage.APICalls.SetQuota(m)
+	log.Printf("Budget: %d calls, %d left in quota, %d calls per minute\n", maxCalls, m.CurrentRateLimit, m.RateLimit)
+	return nil
+}
+
+// Program entry point.
+func main() {
+	var (
+		app      = kingpin.New("goengage", "Run goengage apps as commands.  Use \"goengage help\" to see this message.\n\nCommands:\n\n"+Tree(nil))
+		login    = app.Flag(LoginFlag, "YAML file with API token, default is GOENGAGE_CONFIG or ~/"+DefaultConfig).String()
+		profile  = app.Flag("profile", "Profile in the YAML file, default is GOENGAGE_PROFILE or the file's default").String()
+		offline  = app.Flag("max-batch-size", "Work offline with this batch size instead of reading metrics").Int32()
+		maxCalls = app.Flag("max-calls", "Stop before using more than this many API calls").Int()
+		verbose  = app.Flag(VerboseFlag, "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		output   = app.Flag(OutputFlag, "Filename for the command's primary output").String()
+		format   = app.Flag(FormatFlag, "Output format, for commands that have one").String()
+		resume   = app.Flag(ResumeFlag, "Continue the last run of a long export and append to its output").Bool()
+		gzip     = app.Flag(GzipFlag, "Compress the output with gzip, for commands that support it").Bool()
+		words    = app.Arg("command", "Command, then the command's flags.  Use \"--help\" after the command to see them").Strings()
+	)
+	app.Interspersed(false)
+	app.Parse(os.Args[1:])
+	if len(*words) != 0 && (*words)[0] == "help" {
+		app.Usage(nil)
+		return
+	}
+	c, args := Find(*words)
+	if c == nil {
+		if len(*words) == 0 {
+			app.Usage(nil)
+			os.Exit(1)
+		}
+		t := Tree(*words)
+		if len(t) == 0 {
+			log.Fatalf("main: unknown command \"%s\", use \"goengage help\"\n", strings.Join(*words, " "))
+		}
+		fmt.Fprintf(os.Stderr, "Commands:\n\n%s\n", t)
+		os.Exit(1)
+	}
+
+	if len(*profile) != 0 {
+		os.Setenv(goengage.ProfileEnv, *profile)
+	}
+	if *offline != 0 {
+		os.Setenv(goengage.MaxBatchSizeEnv, fmt.Sprintf("%d", *offline))
+	}
+	global := make(map[string]string)
+	defaults := make(map[string]string)
+	if len(*login) != 0 {
+		global[LoginFlag] = *login
+	} else {
+		fn := DefaultLogin()
+		if len(fn) != 0 || len(os.Getenv(goengage.TokenEnv)) != 0 {
+			defaults[LoginFlag] = fn
+		}
+	}
+	var p goengage.Profile
+	if fn, ok := defaults[LoginFlag]; ok || len(*login) != 0 {
+		if !ok {
+			fn = *login
+		}
+		x, err := goengage.LoadProfile(fn, "")
+		if err != nil {
+			log.Fatalf("main: %v\n", err)
+		}
+		p = *x
+	}
+	if len(p.Timezone) != 0 {
+		defaults[TimezoneFlag] = p.Timezone
+	}
+	if *verbose {
+		global[VerboseFlag] = "true"
+	}
+	if len(*output) != 0 {
+		fn := *output
+		if len(p.OutputDir) != 0 && !filepath.IsAbs(fn) {
+			fn = filepath.Join(p.OutputDir, fn)
+		}
+		global[OutputFlag] = fn
+	}
+	if len(*format) != 0 {
+		global[FormatFlag] = *format
+	}
+	if *resume {
+		global[ResumeFlag] = "true"
+	}
+	if *gzip {
+		global[GzipFlag] = "true"
+	}
+	args, err := c.Args(global, defaults, args)
+	if err != nil {
+		log.Fatalf("main: %v\n", err)
+	}
+	if *maxCalls > 0 {
+		err = Budget(*maxCalls, p)
+		if err != nil {
+			log.Fatalf("main: %v\n", err)
+		}
+	}
+	goengage.Run(c.Name(), func(args []string) error {
+		err := c.Main(args)
+		goengage.APICalls.Report(os.Stderr)
+		return err
+	}, args)
+}
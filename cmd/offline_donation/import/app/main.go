@@ -0,0 +1,492 @@
+package donationimport
+
+//Application to import offline donations from a CSV.  A YAML file maps
+//CSV columns to donation and supporter fields.  Each row is validated,
+//matched to a supporter (or a new supporter is created), then sent to
+//Engage in batches.  A results file shows the activity ID or the errors
+//for each row.
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Fields that can appear in the mapping file.  Donation fields come first,
+// then supporter fields.
+var Fields = []string{
+	"amount",
+	"date",
+	"type",
+	"deductibleAmount",
+	"feesPaid",
+	"fund",
+	"campaign",
+	"appeal",
+	"dedicationType",
+	"dedication",
+	"activityFormName",
+	"gatewayTransactionId",
+	"gatewayAuthorizationCode",
+	"salsaTrack",
+	"accountType",
+	"accountNumber",
+	"accountProvider",
+	"supporterId",
+	"externalId",
+	"email",
+	"firstName",
+	"lastName",
+	"addressLine1",
+	"addressLine2",
+	"city",
+	"state",
+	"postalCode",
+	"country",
+	"phone",
+}
+
+// Results for rows that don't have a result from Engage.
+const (
+	//Valid is a row that passed validation on a dry run.
+	Valid = "VALID"
+	//SendFailed is a row in a batch that Engage didn't accept.  Check
+	//Engage before sending the row again.
+	SendFailed = "SEND_FAILED"
+	//NoResult is a row that was sent, but Engage didn't report on it.
+	NoResult = "NO_RESULT"
+	//NotSent is a row that was never sent because the import stopped.
+	NotSent = "NOT_SENT"
+)
+
+// Row is a single input row with its donation and its outcome.
+type Row struct {
+	Number     int
+	Values     map[string]string
+	Donation   goengage.Donation
+	ActivityID string
+	Result     string
+	Errors     []string
+}
+
+// Runtime holds the stuff that this app needs.
+type Runtime struct {
+	Env        *goengage.Environment
+	Mapping    map[string]string
+	DateFormat string
+	Location   *time.Location
+	BatchSize  int
+	DryRun     bool
+	Logger     *goengage.UtilLogger
+}
+
+// ReadMapping reads a YAML file that maps field names to CSV column
+// headers.  Fields that are not in the file map to a column with the
+// same name as the field.
+func ReadMapping(fn string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, f := range Fields {
+		m[f] = f
+	}
+	if len(fn) == 0 {
+		return m, nil
+	}
+	raw, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var x map[string]string
+	err = yaml.Unmarshal(raw, &x)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range x {
+		if _, ok := m[k]; !ok {
+			return nil, fmt.Errorf("'%s' is not a valid field name", k)
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+// ReadRows reads the input CSV and maps each line to field values.
+func (rt *Runtime) ReadRows(fn string) ([]*Row, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	headers, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int)
+	for i, h := range headers {
+		index[strings.TrimSpace(h)] = i
+	}
+	for _, k := range []string{"amount", "date"} {
+		if _, ok := index[rt.Mapping[k]]; !ok {
+			return nil, fmt.Errorf("required column '%s' for '%s' is not in %s", rt.Mapping[k], k, fn)
+		}
+	}
+	var a []*Row
+	for n := 2; ; n++ {
+		line, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := Row{
+			Number: n,
+			Values: make(map[string]string),
+		}
+		for k, v := range rt.Mapping {
+			if i, ok := index[v]; ok && i < len(line) {
+				row.Values[k] = strings.TrimSpace(line[i])
+			}
+		}
+		a = append(a, &row)
+	}
+	return a, nil
+}
+
+// Money parses a currency amount.  Dollar signs and commas are ignored.
+func Money(s string) (float64, error) {
+	s = strings.Replace(s, "$", "", -1)
+	s = strings.Replace(s, ",", "", -1)
+	if len(s) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// Validate checks the values in a row and builds the row's donation.
+// Problems are recorded as row errors.
+func (rt *Runtime) Validate(row *Row) {
+	v := row.Values
+	fail := func(format string, a ...interface{}) {
+		row.Errors = append(row.Errors, fmt.Sprintf(format, a...))
+	}
+
+	amount, err := Money(v["amount"])
+	if err != nil || amount <= 0 {
+		fail("invalid amount '%s'", v["amount"])
+	}
+	deductible, err := Money(v["deductibleAmount"])
+	if err != nil || deductible < 0 || deductible > amount {
+		fail("invalid deductible amount '%s'", v["deductibleAmount"])
+	}
+	fees, err := Money(v["feesPaid"])
+	if err != nil || fees < 0 || fees > amount {
+		fail("invalid fees paid '%s'", v["feesPaid"])
+	}
+	var date time.Time
+	if len(v["date"]) == 0 {
+		fail("date is required")
+	} else {
+		date, err = time.ParseInLocation(rt.DateFormat, v["date"], rt.Location)
+		if err != nil {
+			fail("invalid date '%s'", v["date"])
+		} else if date.After(time.Now()) {
+			fail("date '%s' is in the future", v["date"])
+		}
+	}
+	date = date.UTC()
+
+	t := strings.ToUpper(v["type"])
+	switch t {
+	case "":
+		t = goengage.Charge
+	case goengage.Charge, goengage.Refund:
+	default:
+		fail("invalid type '%s'", v["type"])
+	}
+	d := strings.ToUpper(v["dedicationType"])
+	switch d {
+	case "", goengage.None, goengage.InHonorOf, goengage.InMemoryOf:
+	default:
+		fail("invalid dedication type '%s'", v["dedicationType"])
+	}
+	if len(v["supporterId"]) == 0 && len(v["email"]) == 0 {
+		fail("either supporterId or email is required")
+	}
+
+	s := goengage.Supporter{
+		SupporterID:      v["supporterId"],
+		ExternalSystemID: v["externalId"],
+		FirstName:        v["firstName"],
+		LastName:         v["lastName"],
+	}
+	if len(v["email"]) != 0 {
+		s.Contacts = append(s.Contacts, goengage.Contact{Type: goengage.ContactEmail, Value: v["email"]})
+	}
+	if len(v["phone"]) != 0 {
+		s.Contacts = append(s.Contacts, goengage.Contact{Type: goengage.ContactHome, Value: v["phone"]})
+	}
+	if len(v["addressLine1"]+v["city"]+v["state"]+v["postalCode"]) != 0 {
+		s.Address = &goengage.Address{
+			AddressLine1: v["addressLine1"],
+			AddressLine2: v["addressLine2"],
+			City:         v["city"],
+			State:        v["state"],
+			PostalCode:   v["postalCode"],
+			Country:      v["country"],
+		}
+	}
+	row.Donation = goengage.Donation{
+		AccountType:              v["accountType"],
+		AccountNumber:            v["accountNumber"],
+		AccountProvider:          v["accountProvider"],
+		Fund:                     v["fund"],
+		Campaign:                 v["campaign"],
+		Appeal:                   v["appeal"],
+		DedicationType:           d,
+		Dedication:               v["dedication"],
+		Type:                     t,
+		Date:                     goengage.TimeStamp{Time: &date},
+		Amount:                   amount,
+		DeductibleAmount:         deductible,
+		FeesPaid:                 fees,
+		OfflineTrackingCode:      v["salsaTrack"],
+		GatewayTransactionID:     v["gatewayTransactionId"],
+		GatewayAuthorizationCode: v["gatewayAuthorizationCode"],
+		ActivityFormName:         v["activityFormName"],
+		Supporter:                s,
+	}
+}
+
+// Match finds the supporter for a row by supporter ID or by email.  If
+// Engage doesn't have the email, then a supporter is created.  Failed
+// searches are errors so that a bad connection doesn't create duplicates.
+func (rt *Runtime) Match(row *Row) error {
+	s := &row.Donation.Supporter
+	if len(s.SupporterID) != 0 {
+		x, err := goengage.SupporterByID(rt.Env, s.SupporterID)
+		if err != nil {
+			return err
+		}
+		if x == nil {
+			return fmt.Errorf("supporter ID %s not found", s.SupporterID)
+		}
+		return nil
+	}
+	email := row.Values["email"]
+	x, err := goengage.SupporterByEmail(rt.Env, email)
+	if err != nil && !errors.Is(err, goengage.ErrEmailNotFound) {
+		return err
+	}
+	if err == nil && x != nil {
+		s.SupporterID = x.SupporterID
+		return nil
+	}
+	if rt.DryRun {
+		return nil
+	}
+	x, err = goengage.SupporterUpsert(rt.Env, s, rt.Logger)
+	if err != nil {
+		return err
+	}
+	log.Printf("Match: row %d, created supporter %s for %s\n", row.Number, x.SupporterID, email)
+	s.SupporterID = x.SupporterID
+	return nil
+}
+
+// Send posts a batch of rows to Engage and records the results.  If
+// the batch fails, then its rows are marked with the error.
+func (rt *Runtime) Send(batch []*Row) error {
+	var a []goengage.Donation
+	for _, row := range batch {
+		a = append(a, row.Donation)
+	}
+	results, err := goengage.OfflineDonations(rt.Env, a, rt.Logger)
+	if err != nil {
+		for _, row := range batch {
+			row.Result = SendFailed
+			row.Errors = append(row.Errors, err.Error())
+		}
+		return err
+	}
+	for i, row := range batch {
+		if i >= len(results) {
+			row.Result = NoResult
+			row.Errors = append(row.Errors, "no result returned by Engage")
+			continue
+		}
+		r := results[i]
+		row.ActivityID = r.ActivityID
+		row.Result = r.Result
+		for _, x := range r.Errors {
+			row.Errors = append(row.Errors, fmt.Sprintf("%s %s", x.FieldName, x.Message))
+		}
+	}
+	return nil
+}
+
+// Run validates, matches and sends all rows.  Rows with errors
+// are not sent.  Rows start out as NotSent, so the rows that were
+// left when an error stopped the run still say so.
+func (rt *Runtime) Run(rows []*Row) error {
+	for _, row := range rows {
+		row.Result = NotSent
+	}
+	var batch []*Row
+	for _, row := range rows {
+		rt.Validate(row)
+		if len(row.Errors) == 0 {
+			err := rt.Match(row)
+			if err != nil {
+				row.Errors = append(row.Errors, err.Error())
+			}
+		}
+		if len(row.Errors) != 0 {
+			row.Result = goengage.ValidationError
+			continue
+		}
+		if rt.DryRun {
+			row.Result = Valid
+			continue
+		}
+		batch = append(batch, row)
+		if len(batch) == rt.BatchSize {
+			err := rt.Send(batch)
+			if err != nil {
+				return err
+			}
+			log.Printf("Run: sent through row %d\n", row.Number)
+			batch = nil
+		}
+	}
+	if len(batch) != 0 {
+		return rt.Send(batch)
+	}
+	return nil
+}
+
+// WriteResults writes the outcome for each row to a file in the output
+// format.
+func WriteResults(opt output.Options, fn string, rows []*Row) error {
+	headers := []string{
+		"Row",
+		"SupporterID",
+		"Email",
+		"Amount",
+		"Date",
+		"ActivityID",
+		"Result",
+		"Errors",
+	}
+	w, err := opt.Create(opt.Filename(fn), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, row := range rows {
+		record := []string{
+			fmt.Sprintf("%d", row.Number),
+			row.Donation.Supporter.SupporterID,
+			row.Values["email"],
+			row.Values["amount"],
+			row.Values["date"],
+			row.ActivityID,
+			row.Result,
+			strings.Join(row.Errors, "; "),
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app         = kingpin.New("import", "Import offline donations from a CSV")
+		login       = app.Flag("login", "YAML file with API token").Required().String()
+		inputFile   = app.Flag("input", "CSV file of offline donations").Required().String()
+		mappingFile = app.Flag("mapping", "YAML file that maps field names to CSV columns").String()
+		resultsFile = app.Flag("results", "CSV filename for results").Default("offline_donation_results.csv").String()
+		dateFormat  = app.Flag("dateFormat", "Go date format for the date column").Default("2006-01-02").String()
+		timeZone    = app.Flag("timezone", "Client's timezone, defaults to EST/EDT").Default("America/New_York").String()
+		batchSize   = app.Flag("batchSize", "Donations per request, default is the Engage maximum").Int()
+		dryRun      = app.Flag("dryRun", "Validate and match, but don't create supporters or donations").Bool()
+		verbose     = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format      = app.Flag("format", "Output format for --results, changes the file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip        = app.Flag("gzip", "Compress the --results output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		return err
+	}
+	mapping, err := ReadMapping(*mappingFile)
+	if err != nil {
+		return err
+	}
+	size := int(e.BatchSize())
+	if *batchSize > 0 && *batchSize < size {
+		size = *batchSize
+	}
+	if size <= 0 {
+		return errors.New("batch size must be greater than zero")
+	}
+	rt := Runtime{
+		Env:        e,
+		Mapping:    mapping,
+		DateFormat: *dateFormat,
+		Location:   location,
+		BatchSize:  size,
+		DryRun:     *dryRun,
+	}
+	if *verbose {
+		rt.Logger, err = goengage.NewUtilLogger()
+		if err != nil {
+			return err
+		}
+	}
+	rows, err := rt.ReadRows(*inputFile)
+	if err != nil {
+		return err
+	}
+	log.Printf("main: %d rows\n", len(rows))
+	runErr := rt.Run(rows)
+	err = WriteResults(opt, *resultsFile, rows)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]int)
+	for _, row := range rows {
+		counts[row.Result]++
+	}
+	for _, r := range []string{SendFailed, NoResult, NotSent} {
+		if counts[r] != 0 {
+			log.Printf("main: %d rows are %s\n", counts[r], r)
+		}
+	}
+	log.Printf("main: results are in %s\n", opt.Filename(*resultsFile))
+	return runErr
+}
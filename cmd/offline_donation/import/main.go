@@ -0,0 +1,16 @@
+package main
+
+//Runs the import app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	donationimport "github.com/salsalabs/goengage/cmd/offline_donation/import/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("import", donationimport.Main, os.Args[1:])
+}
@@ -0,0 +1,79 @@
+package report
+
+//Application to run a report that is defined in a YAML spec.  See
+//pkg/spec and the examples in cmd/report/specs.
+
+import (
+	"fmt"
+	"log"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	spec "github.com/salsalabs/goengage/pkg/spec"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app       = kingpin.New("report", "Write a report that is defined in a YAML spec")
+		login     = app.Flag("login", "YAML file with API token").Required().String()
+		specFile  = app.Flag("spec", "YAML file that defines the report").Required().String()
+		outFile   = app.Flag("output", "Output filename, default is the spec's output or name").String()
+		format    = app.Flag("format", "Output format, default is the spec's format or csv").String()
+		gzip      = app.Flag("gzip", "Compress the output with gzip").Bool()
+		timeZone  = app.Flag("timezone", "Timezone, replaces the spec's timezone").String()
+		startDate = app.Flag("startDate", "Start date, YYYY-MM-DD, replaces the spec's start date").String()
+		endDate   = app.Flag("endDate", "End date, YYYY-MM-DD, replaces the spec's end date").String()
+	)
+	app.Parse(args)
+
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	s, err := spec.Load(*specFile)
+	if err != nil {
+		return err
+	}
+	if len(*timeZone) != 0 {
+		s.Timezone = *timeZone
+	}
+	if len(*startDate) != 0 {
+		s.Source.Start = *startDate
+	}
+	if len(*endDate) != 0 {
+		s.Source.End = *endDate
+	}
+	err = s.Validate()
+	if err != nil {
+		return fmt.Errorf("%s: %v", *specFile, err)
+	}
+
+	fn := *outFile
+	if len(fn) == 0 {
+		fn = s.Output
+	}
+	if len(fn) == 0 {
+		fn = s.Name + ".csv"
+	}
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	if len(opt.Format) == 0 {
+		opt.Format = s.Format
+	}
+	fn = opt.Filename(fn)
+	w, err := opt.Create(fn, s.Headers())
+	if err != nil {
+		return fmt.Errorf("%s: %v", fn, err)
+	}
+	err = s.Run(e, w)
+	cerr := w.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", fn, err)
+	}
+	log.Printf("main: done, wrote %s\n", fn)
+	return nil
+}
@@ -0,0 +1,16 @@
+package main
+
+//Runs the report app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	report "github.com/salsalabs/goengage/cmd/report/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("report", report.Main, os.Args[1:])
+}
@@ -0,0 +1,91 @@
+package segments
+
+import (
+	"errors"
+	"log"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Main is the program entry point.  Summarize segments.  No details.
+func Main(args []string) error {
+	var (
+		app     = kingpin.New("segments", "A command-line app to summarize segments.")
+		login   = app.Flag("login", "YAML file with API token").Required().String()
+		csvFile = app.Flag("csv", "CSV filename to store segment info").Default("segments.csv").String()
+		format  = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip    = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	if csvFile == nil || len(*csvFile) == 0 {
+		return errors.New("--csv is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	headers := []string{"ID",
+		"GroupName",
+	}
+	w, err := opt.Create(opt.Filename(*csvFile), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	//Read segments and save them.
+	count := e.BatchSize()
+	offset := int32(0)
+	for count > 0 {
+		payload := goengage.SegmentSearchRequestPayload{
+			Offset: offset,
+			Count:  count,
+		}
+		rqt := goengage.SegmentSearchRequest{
+			Header:  goengage.RequestHeader{},
+			Payload: payload,
+		}
+		var resp goengage.SegmentSearchResponse
+
+		n := goengage.NetOp{
+			Host:     e.Host,
+			Method:   goengage.SearchMethod,
+			Endpoint: goengage.SearchSegment,
+			Token:    e.Token,
+			Request:  &rqt,
+			Response: &resp,
+		}
+		err = n.Do()
+		if err != nil {
+			return err
+		}
+		for _, s := range resp.Payload.Segments {
+			var a []string
+			a = append(a, s.SegmentID)
+			a = append(a, s.Name)
+			err = w.Write(a)
+			if err != nil {
+				return err
+			}
+		}
+		count = resp.Payload.Count
+		log.Printf("Main: read %3d from offset %4d\n", count, offset)
+		offset += int32(count)
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	log.Printf("Done.  Output is in %v\n", opt.Filename(*csvFile))
+	return nil
+}
@@ -0,0 +1,234 @@
+// App to retrieve segment (group) members and write their State fields
+// to a file.  Raw data for segment-based demographic analysis.
+package onesegmentstates
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	//ListenerCount is the number of go tasks listening for
+	//supporters.  Trivial in this version.
+	ListenerCount = 1
+
+	//SettleTime is the number of seconds to wait for the processes
+	//to get started.
+	SettleTime = "2s"
+)
+
+// Cache is the content that appears in the output file.
+type Cache map[string]int32
+
+// Runtime area for this app.
+type Runtime struct {
+	E         *goengage.Environment
+	InChan    chan goengage.Supporter
+	Cache     Cache
+	DoneChan  chan bool
+	SegmentID string
+	Results   string
+	Output    output.Options
+	Logger    *goengage.UtilLogger
+}
+
+// NewRuntime populates a new runtime.
+func NewRuntime(env *goengage.Environment, segmentID string, results string, opt output.Options, verbose bool) Runtime {
+	r := Runtime{
+		E:         env,
+		InChan:    make(chan goengage.Supporter),
+		Cache:     make(Cache),
+		DoneChan:  make(chan bool),
+		SegmentID: segmentID,
+		Results:   results,
+		Output:    opt,
+	}
+	if verbose {
+		logger, err := goengage.NewUtilLogger()
+		if err != nil {
+			log.Fatalf("unable to create logger, %v", err)
+		}
+		r.Logger = logger
+	}
+	return r
+}
+
+// Drive finds all of the supporters in the specified group and writes
+// them to the input channel.  The channel is closed when reading stops,
+// even for an error, so that the listeners can finish.
+func (rt Runtime) Drive() (err error) {
+	log.Println("Drive: begin")
+	defer close(rt.InChan)
+	count := rt.E.BatchSize()
+	offset := int32(0)
+	for count == rt.E.BatchSize() {
+		payload := goengage.SegmentMembershipRequestPayload{
+			SegmentID: rt.SegmentID,
+			Offset:    offset,
+			Count:     count,
+		}
+		rqt := goengage.SegmentMembershipRequest{
+			Header:  goengage.RequestHeader{},
+			Payload: payload,
+		}
+		var resp goengage.SegmentMembershipResponse
+
+		n := goengage.NetOp{
+			Host:     rt.E.Host,
+			Method:   goengage.SearchMethod,
+			Endpoint: goengage.SegmentSearchMembers,
+			Token:    rt.E.Token,
+			Request:  &rqt,
+			Response: &resp,
+		}
+		err = n.Do()
+		if err != nil {
+			return err
+		}
+		if offset%100 == 0 {
+			log.Printf("Drive: Read %4d of %4d\n", offset, resp.Payload.Total)
+		}
+		for _, s := range resp.Payload.Supporters {
+			rt.InChan <- s
+		}
+		count = int32(len(resp.Payload.Supporters))
+		offset += count
+	}
+	log.Println("Drive: end")
+	return nil
+}
+
+// SaveCache writes an alphabetized list of states and counts to
+// the output file.
+func (rt Runtime) SaveCache() (err error) {
+	headers := []string{
+		"SegmentID",
+		"State",
+		"Members",
+	}
+	writer, err := rt.Output.Create(rt.Output.Filename(rt.Results), headers)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	keys := make([]string, len(rt.Cache))
+	for k := range rt.Cache {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := rt.Cache[k]
+		record := []string{
+			rt.SegmentID,
+			k,
+			fmt.Sprintf("%v", v),
+		}
+		err = writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// Update retrieves Supporters from the input channel and updates
+// the cache in the runtime.  Writes the output file at end of data.
+func (rt Runtime) Update() (err error) {
+	log.Println("Update: start")
+	for {
+		s, okay := <-rt.InChan
+		if !okay {
+			break
+		}
+		if s.Address != nil {
+			v, ok := rt.Cache[s.Address.State]
+			if ok {
+				rt.Cache[s.Address.State] = v + 1
+			} else {
+				rt.Cache[s.Address.State] = 1
+			}
+		}
+	}
+	rt.SaveCache()
+	log.Println("Update: end")
+	rt.DoneChan <- true
+	return nil
+}
+
+// Main is the program entry point.  Look for supporters with an email.  Errors are noisy and fatal.
+func Main(args []string) error {
+	var (
+		app       = kingpin.New("one_segment_states", "Tabulate segment member counts by state")
+		login     = app.Flag("login", "YAML file with API token").Required().String()
+		segmentID = app.Flag("segment-id", "segmentID for the group").Default("f4be4a19-b85f-4d69-baae-e027a86fd676").String()
+		verbose   = app.Flag("verbose", "Log contents of all network actions. *Really* noisy").Default("false").Bool()
+		format    = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip      = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+
+	csvFile := fmt.Sprintf("%v.csv", *segmentID)
+	rt := NewRuntime(e, *segmentID, csvFile, opt, *verbose)
+	var fe goengage.FirstError
+	var wg sync.WaitGroup
+
+	//Start Update task. More than one leads to multiple output files.
+	for i := 1; i <= ListenerCount; i++ {
+		wg.Add(1)
+		go (func(rt Runtime, wg *sync.WaitGroup, i int) {
+			defer wg.Done()
+			err := rt.Update()
+			if err != nil {
+				panic(err)
+			}
+		})(rt, &wg, i)
+	}
+	//Start done listener.
+	wg.Add(1)
+	go (func(rt Runtime, n int, wg *sync.WaitGroup) {
+		defer wg.Done()
+		goengage.DoneListener(rt.DoneChan, n)
+	})(rt, ListenerCount, &wg)
+
+	//Start supporter reader.
+	wg.Add(1)
+	go (func(rt Runtime, wg *sync.WaitGroup) {
+		defer wg.Done()
+		err := rt.Drive()
+		if err != nil {
+			fe.Set(fmt.Errorf("driver error: %w", err))
+		}
+	})(rt, &wg)
+
+	d, err := time.ParseDuration(SettleTime)
+	if err != nil {
+		return err
+	}
+	log.Printf("main: sleeping for %s", d)
+	time.Sleep(d)
+	log.Printf("main:  waiting...")
+	wg.Wait()
+	log.Printf("main: done")
+	return fe.Err()
+}
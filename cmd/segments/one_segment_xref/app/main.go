@@ -0,0 +1,358 @@
+package onesegmentxref
+
+//Application to accept a segmentId and output the supporters that belong
+//to the segment.  Output includes a list of the other segments that a
+//supporter belongs to.  Produces a CSV of supporter_KEY, Email, Groups.
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	//SupporterListeners is the number of listeners for segments info records.
+	SupporterListeners = 5
+	//XrefListeners is the channel volume for the xref listeners.
+	XrefListeners = 500
+	//StartDate is used for the starting of Joined and LastModified ranges.
+	StartDate = "2001-01-01T01:01:01.001Z"
+	//MembersKey is the checkpoint key for the segment member reader.
+	MembersKey = "Members"
+)
+
+// XrefRecord is the container for the information that goes to the output.
+type XrefRecord struct {
+	SupporterID string
+	Email       string
+	Segments    []string
+}
+
+// NewXrefRecord creates an XrefRecord and returns a reference.
+func NewXrefRecord(s string, e string) *XrefRecord {
+	x := XrefRecord{
+		SupporterID: s,
+		Email:       e,
+		Segments:    make([]string, 0),
+	}
+	return &x
+}
+
+// Runtime holds the common data used by the tasks in this app.
+type Runtime struct {
+	E            *goengage.Environment
+	SegmentID    string
+	C1           chan *XrefRecord
+	C2           chan *XrefRecord
+	D            chan bool
+	F            string
+	L            *goengage.UtilLogger
+	MemberOffset int32
+	Check        *goengage.Checkpoint
+	Window       int
+}
+
+// Members accepts a segmentId and writes the segment members to the
+// provided channel.  Finished offsets are saved in the checkpoint.  The
+// channel is closed when reading stops, even for an error.
+func Members(rt Runtime) (err error) {
+	log.Println("Members: begin")
+	defer close(rt.C1)
+
+	count := rt.E.BatchSize()
+	offset := rt.Check.Resume(MembersKey, rt.MemberOffset, int32(cap(rt.C1)+1), count)
+	for count == rt.E.BatchSize() {
+		payload := goengage.SegmentMembershipRequestPayload{
+			SegmentID:   rt.SegmentID,
+			Offset:      offset,
+			Count:       count,
+			JoinedSince: StartDate,
+		}
+
+		rqt := goengage.SegmentMembershipRequest{
+			Header:  goengage.RequestHeader{},
+			Payload: payload,
+		}
+
+		var resp goengage.SegmentMembershipResponse
+
+		n := goengage.NetOp{
+			Host:     rt.E.Host,
+			Method:   goengage.SearchMethod,
+			Endpoint: goengage.SegmentSearchMembers,
+			Token:    rt.E.Token,
+			Request:  &rqt,
+			Response: &resp,
+			Logger:   rt.L,
+		}
+		err = n.Do()
+		if err != nil {
+			return err
+		}
+		if offset%500 == 0 {
+			log.Printf("Members: %6d: %2d of %6d\n",
+				offset,
+				len(resp.Payload.Supporters),
+				resp.Payload.Total)
+		}
+
+		for _, s := range resp.Payload.Supporters {
+			p := goengage.FirstEmail(s)
+			email := ""
+			if p != nil {
+				email = *p
+			}
+			x := NewXrefRecord(s.SupporterID, email)
+			rt.C1 <- x
+		}
+		count = resp.Payload.Count
+		err = rt.Check.Complete(MembersKey, offset, offset+int32(count))
+		if err != nil {
+			return err
+		}
+		offset += int32(count)
+	}
+	log.Println("Members: end")
+	return nil
+}
+
+// Segments accepts an xref record from the channel, populates the Groups field, then
+// pushes the completed record into the write channel. Notifies done with the input
+// channel is empty.  Records after an error are drained so that Members can finish.
+func Segments(rt Runtime, id int) (err error) {
+	log.Printf("Segments %+v: begin\n", id)
+	for {
+		x, ok := <-rt.C1
+		if !ok {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		err = Groups(rt, x)
+		if err != nil {
+			log.Printf("Segments %+v: %v\n", id, err)
+			continue
+		}
+		rt.C2 <- x
+	}
+
+	rt.D <- true
+	return err
+}
+
+// Groups reads the groups for a supporter and adds the ones that aren't
+// the segment of interest to the Xref record.
+func Groups(rt Runtime, x *XrefRecord) (err error) {
+	count := rt.E.BatchSize()
+	offset := int32(0)
+
+	for count == rt.E.BatchSize() {
+		payload := goengage.SupporterGroupsRequestPayload{
+			Identifiers:    []string{x.SupporterID},
+			IdentifierType: goengage.SupporterIDType,
+			ModifiedFrom:   StartDate,
+			Offset:         offset,
+			Count:          count,
+		}
+		rqt := goengage.SupporterGroupsRequest{
+			Header:  goengage.RequestHeader{},
+			Payload: payload,
+		}
+		var resp goengage.SupporterGroupsResponse
+
+		n := goengage.NetOp{
+			Host:     rt.E.Host,
+			Method:   goengage.SearchMethod,
+			Endpoint: goengage.SupporterSearchGroups,
+			Token:    rt.E.Token,
+			Request:  &rqt,
+			Response: &resp,
+			Logger:   rt.L,
+		}
+		err = n.Do()
+		if err != nil {
+			return err
+		}
+		respPayload := resp.Payload
+		results := respPayload.Results
+		for _, s := range results {
+			for _, t := range s.Segments {
+				if t.SegmentID != rt.SegmentID {
+					x.Segments = append(x.Segments, t.Name)
+				}
+			}
+		}
+		count = resp.Payload.Count
+		offset += int32(count)
+	}
+	return nil
+}
+
+// OutputCSV accepts Xref records from a channeland and writes them to
+// a CSV file.  Records after an error are drained so that the segment
+// listeners can finish.
+func OutputCSV(rt Runtime) error {
+	log.Printf("OutputCSV: begin")
+	defer (func() {
+		for range rt.C2 {
+		}
+	})()
+	w, err := goengage.OpenAppendCSV(rt.F, rt.Check.Resuming(), []string{"SupporterId"}, rt.Window)
+	if err != nil {
+		return err
+	}
+	headers := []string{"SupporterId", "Email", "Groups"}
+	err = w.Write(headers)
+	for err == nil {
+		x, ok := <-rt.C2
+		if !ok {
+			break
+		}
+		s := strings.Join(x.Segments, ",")
+		row := []string{
+			x.SupporterID,
+			x.Email,
+			s,
+		}
+		err = w.Write(row)
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	log.Printf("OutputCSV: end")
+	return err
+}
+
+// WaitTerminations waits for "SupporterListeners" supporter readers to
+// complete.  That triggers a close for the CSV writer channel.
+func WaitTerminations(rt Runtime) {
+	log.Printf("WaitTerminations: begin")
+	remaining := SupporterListeners
+	for remaining > 0 {
+		log.Printf("WaitTerminations: waiting for %d listeners\n", remaining)
+		<-rt.D
+		remaining--
+	}
+	close(rt.C2)
+	log.Printf("WaitTerminations: end")
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app       = kingpin.New("one_segment_xref", "Find supporters for a segment. Display supporters and lists of groups they belong to.")
+		login     = app.Flag("login", "YAML file with API token").Required().String()
+		segmentID = app.Flag("segmentId", "primary key for the segment of interest").Default("0d2b6078-6a5c-42c0-b62d-e01208b468cd").String()
+		csvFile   = app.Flag("csv", "CSV filename to store supporter-segment info").Default("supporter_segment.csv").String()
+		offset    = app.Flag("member-offset", "Start here if you lose network connectivity").Default("0").Int32()
+		resume    = app.Flag("resume", "Continue where the last run stopped and append to the CSV").Bool()
+	)
+	app.Parse(args)
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	if csvFile == nil || len(*csvFile) == 0 {
+		return errors.New("--csv is required")
+	}
+	if segmentID == nil || len(*segmentID) != 36 {
+		return errors.New("--segmentId is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+
+	cp, err := goengage.OpenCheckpoint(goengage.CheckpointFile(*csvFile), *resume)
+	if err != nil {
+		return err
+	}
+	//Members that were read but not written are in the segment
+	//listeners and in the CSV channel.
+	cp.Rewind = int32(XrefListeners + SupporterListeners)
+
+	// logger, err := goengage.NewUtilLogger()
+	// if err != nil {
+	// 	panic(err)
+	// }
+
+	rt := Runtime{
+		E:            e,
+		SegmentID:    *segmentID,
+		C1:           make(chan *XrefRecord, XrefListeners),
+		C2:           make(chan *XrefRecord, XrefListeners),
+		D:            make(chan bool, SupporterListeners),
+		F:            *csvFile,
+		MemberOffset: *offset,
+		Check:        cp,
+		// L:         logger,
+	}
+	//A resumed CSV can have the rewound members and the page that was
+	//being read when the last run stopped.
+	rt.Window = cap(rt.C1) + 1 + int(cp.Rewind) + 2*int(e.BatchSize())
+	var fe goengage.FirstError
+	var wg sync.WaitGroup
+
+	//Start the CSV output listener.  Note wg.Add before. Should
+	//reduce race conditions.
+	wg.Add(1)
+	go (func(rt Runtime, wg *sync.WaitGroup) {
+		defer wg.Done()
+		err := OutputCSV(rt)
+		if err != nil {
+			fe.Set(err)
+		}
+	})(rt, &wg)
+	log.Printf("main: CSV writer started\n")
+
+	//Start segment listeners
+	for id := 1; id <= SupporterListeners; id++ {
+		wg.Add(1)
+		go (func(rt Runtime, id int, wg *sync.WaitGroup) {
+			defer wg.Done()
+			err := Segments(rt, id)
+			if err != nil {
+				fe.Set(err)
+			}
+		})(rt, id, &wg)
+	}
+	log.Printf("main: %+v segment listeners started\n", SupporterListeners)
+
+	//Start "done" listener to keep track of segment listeners.
+	wg.Add(1)
+	go (func(rt Runtime, wg *sync.WaitGroup) {
+		defer wg.Done()
+		WaitTerminations(rt)
+	})(rt, &wg)
+	log.Println("main: terminations listener started")
+
+	//Start segment reader.
+	wg.Add(1)
+	go (func(rt Runtime, wg *sync.WaitGroup) {
+		defer wg.Done()
+		err := Members(rt)
+		if err != nil {
+			fe.Set(err)
+		}
+	})(rt, &wg)
+	log.Printf("main: segment reader started\n")
+
+	log.Printf("main: napping...\n")
+	time.Sleep(10 * time.Second)
+	log.Printf("main: waiting...\n")
+	wg.Wait()
+	if fe.Err() != nil {
+		return fe.Err()
+	}
+	err = cp.Finish()
+	if err != nil {
+		return err
+	}
+	log.Printf("main: done")
+	return nil
+}
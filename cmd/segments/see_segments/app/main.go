@@ -0,0 +1,145 @@
+package seesegments
+
+//Application to create a file of segments for a client. Output includes
+//UUID, SegmentName and potentially the .  Can include more --
+//find the output formatter and kludge away!
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Runtime holds the stuff that this app needs.
+type Runtime struct {
+	Env           *goengage.Environment
+	IncludeCounts bool
+	CSVFilename   string
+	Output        output.Options
+	Logger        *goengage.UtilLogger
+}
+
+func NewRuntime(e *goengage.Environment, c bool, f string, opt output.Options, v bool) (*Runtime, error) {
+	rt := Runtime{
+		Env:           e,
+		IncludeCounts: c,
+		CSVFilename:   f,
+		Output:        opt,
+	}
+	if v {
+		logger, err := goengage.NewUtilLogger()
+		if err != nil {
+			return nil, err
+		}
+		rt.Logger = logger
+	}
+	return &rt, nil
+}
+
+// Run finds and displays all segments.
+func Run(rt *Runtime) error {
+	log.Println("Run: begin")
+	headers := []string{
+		"SegmentID",
+		"SegmentName",
+		"MemberCount",
+	}
+	writer, err := rt.Output.Create(rt.Output.Filename(rt.CSVFilename), headers)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	count := rt.Env.BatchSize()
+	offset := int32(0)
+
+	for count == rt.Env.BatchSize() {
+		payload := goengage.SegmentSearchRequestPayload{
+			Count:               count,
+			Offset:              offset,
+			IncludeMemberCounts: rt.IncludeCounts,
+		}
+
+		rqt := goengage.SegmentSearchRequest{
+			Header:  goengage.RequestHeader{},
+			Payload: payload,
+		}
+
+		var resp goengage.SegmentSearchResponse
+
+		n := goengage.NetOp{
+			Host:     rt.Env.Host,
+			Method:   goengage.SearchMethod,
+			Endpoint: goengage.SearchSegment,
+			Token:    rt.Env.Token,
+			Request:  &rqt,
+			Response: &resp,
+			Logger:   rt.Logger,
+		}
+		err := n.Do()
+		if err != nil {
+			return err
+		}
+		if offset%100 == 0 {
+			log.Printf("Run: %6d: %2d of %6d\n",
+				offset,
+				len(resp.Payload.Segments),
+				resp.Payload.Total)
+		}
+
+		for _, s := range resp.Payload.Segments {
+			record := []string{
+				s.SegmentID,
+				s.Name,
+				fmt.Sprintf("%v", s.TotalMembers),
+			}
+			err = writer.Write(record)
+			if err != nil {
+				return err
+			}
+		}
+		count = resp.Payload.Count
+		offset += int32(count)
+	}
+	log.Printf("Run: end")
+	return writer.Close()
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app           = kingpin.New("one_segment_xref", "Creates a CSV of segments for a client")
+		login         = app.Flag("login", "YAML file with API token").Required().String()
+		csvFile       = app.Flag("csv", "CSV filename to create").Default("segments.csv").String()
+		includeCounts = app.Flag("include-counts", "Output will contain the number of members, too").Bool()
+		verbose       = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format        = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip          = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	rt, err := NewRuntime(e, *includeCounts, *csvFile, opt, *verbose)
+	if err != nil {
+		return err
+	}
+	err = Run(rt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// App to search for supporters that have a custom field then
+// to report on the distribution of custom field values.  The
+// app also shows supporters who do not have the custom field.
+// Unlike Classic, a supporter without an assigned custom field
+// value is not equipped with that custom field.
+package customfielddistribution
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	reportSupporter "github.com/salsalabs/goengage/pkg/report"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Runtime area for this app.
+type Runtime struct {
+	E          *goengage.Environment
+	Cache      Cache
+	Keys       []string
+	FieldName  string
+	ReadOffset int32
+	Format     string
+}
+
+// Cache is used to store values and counts.
+type Cache map[string]int32
+
+// NewRuntime populates a new runtime.
+func NewRuntime(env *goengage.Environment, f string) Runtime {
+	c := Cache{
+		Null:        0,
+		NotEquipped: 0,
+	}
+	r := Runtime{
+		E:          env,
+		Cache:      c,
+		Keys:       []string{Null, NotEquipped},
+		FieldName:  f,
+		ReadOffset: int32(0),
+	}
+	return r
+}
+
+// Constants for cache updates.
+const (
+	Null        = "Null"
+	NotEquipped = "NotEquipped"
+)
+
+// Visit implements SupporterTask.Visit and does something with
+// a supporter record
+func (r *Runtime) Visit(s goengage.Supporter) error {
+	for _, f := range s.CustomFieldValues {
+		if f.Name == r.FieldName {
+			if len(f.Value) == 0 {
+				r.Cache[Null] = r.Cache[Null] + 1
+				// log.Printf("Visit: %s: %d\n", Null, r.Cache[Null])
+				return nil
+			}
+			p, ok := r.Cache[f.Value]
+			if ok {
+				p = r.Cache[f.Value]
+			} else {
+				r.Keys = append(r.Keys, f.Value)
+				p = 0
+			}
+			r.Cache[f.Value] = p + 1
+			// log.Printf("Visit: %s: %d\n", f.Value, r.Cache[f.Value])
+			return nil
+		}
+	}
+	r.Cache[NotEquipped] = r.Cache[NotEquipped] + 1
+	// log.Printf("Visit: %s: %d\n", NotEquipped, r.Cache[NotEquipped])
+	return nil
+}
+
+// Finalize implements SupporterTask.Finalize and outputs the
+// distribution results.
+func (r *Runtime) Finalize() error {
+	w, err := output.NewWriter(os.Stdout, r.Format, []string{"Value", "Count"})
+	if err != nil {
+		return err
+	}
+	for _, k := range r.Keys {
+		err = w.Write([]string{k, fmt.Sprintf("%d", r.Cache[k])})
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("%v\n", r.Cache)
+	return w.Close()
+}
+
+// Payload implements SupporterTask.Payload and provides a payload
+// that will retrieve all supporters.
+func (r *Runtime) Payload() goengage.SupporterSearchRequestPayload {
+	payload := goengage.SupporterSearchRequestPayload{
+		IdentifierType: goengage.SupporterIDType,
+		ModifiedFrom:   "2000-01-01T00:00:00.00000Z",
+		ModifiedTo:     "2050-01-01T00:00:00.00000Z",
+		Offset:         0,
+		Count:          0,
+	}
+	return payload
+}
+
+// Offset returns the offset for the first read.
+// Useful for restarts.
+func (r *Runtime) Offset() int32 {
+	return r.ReadOffset
+}
+
+// Adjust offset changes the proposed offset as needed.
+// Useful for chunked ID reads.  Does nothing in this app.
+func (r *Runtime) AdjustOffset(offset int32) int32 {
+	return offset
+}
+
+// Main is the program entry point.  Look for supporters with an email.  Errors are noisy and fatal.
+func Main(args []string) error {
+	var (
+		app       = kingpin.New("custom_field-distribution", "Search for a custom field and report on value distribution")
+		login     = app.Flag("login", "YAML file with API token").Required().String()
+		fieldName = app.Flag("fieldName", "Custom field name to count").Required().String()
+		format    = app.Flag("format", "Output format").Default(output.CSV).Enum(output.Formats...)
+	)
+	app.Parse(args)
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+
+	r := NewRuntime(e, *fieldName)
+	r.Format = *format
+	//Only one visitor because Visit is quick in this app. More than
+	//one cases "concurrent map writes" errors.
+	err = reportSupporter.RunSupporters(r.E, &r, 1)
+	if err != nil {
+		return err
+	}
+	log.Printf("main: done")
+	return nil
+}
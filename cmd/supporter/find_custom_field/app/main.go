@@ -0,0 +1,289 @@
+package findcustomfield
+
+//Application to accept a custom field name and write supporters
+//who have that custom field to a file.
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	//OffsetListeners is the number of listeners for supporters info records.
+	OffsetListeners = 5
+	//StartDate is used for the starting of Joined and LastModified ranges.
+	StartDate = "2001-01-01T01:01:01.001Z"
+)
+
+// CFRecord is the container for the information that goes to the output.
+type CFRecord struct {
+	SupporterID      string
+	Email            string
+	CustomFieldID    string
+	CustomFieldName  string
+	CustomFieldValue string
+}
+
+// NewCFRecord creates an CFRecord and returns a reference.
+func NewCFRecord(s goengage.Supporter, c goengage.CustomFieldValue) *CFRecord {
+	f := goengage.FirstEmail(s)
+	e := "(None)"
+	if f != nil {
+		e = *f
+	}
+	x := CFRecord{
+		SupporterID:      s.SupporterID,
+		Email:            e,
+		CustomFieldID:    c.FieldID,
+		CustomFieldName:  c.Name,
+		CustomFieldValue: c.Value,
+	}
+	return &x
+}
+
+// Runtime holds the common data used by the tasks in this app.
+type Runtime struct {
+	E    *goengage.Environment
+	Name string
+	C0   chan int32
+	C1   chan goengage.Supporter
+	C2   chan *CFRecord
+	D    chan bool
+	F    string
+	O    output.Options
+	L    *goengage.UtilLogger
+}
+
+// Offsets accepts a number of records and writes offsets
+// to the offsets queue in chunks of MaxBatchSize.
+func Offsets(rt Runtime, total int32) {
+	for i := int32(0); i < total; i += rt.E.BatchSize() {
+		rt.C0 <- i
+	}
+	close(rt.C0)
+}
+
+// One accepts an offset and passes along the ones that match the filter requirements.
+func Supporters(rt Runtime, i int) (err error) {
+	log.Printf("Supporters %d: begin\n", i)
+	for {
+		d, ok := <-rt.C0
+		if !ok {
+			break
+		}
+		log.Printf("Supporters %d: %d\n", i, d)
+		payload := goengage.SupporterSearchRequestPayload{
+			ModifiedFrom: StartDate,
+			Offset:       d,
+			Count:        rt.E.BatchSize(),
+		}
+		rqt := goengage.SupporterSearchRequest{
+			Header:  goengage.RequestHeader{},
+			Payload: payload,
+		}
+		var resp goengage.SupporterSearchResults
+		n := goengage.NetOp{
+			Host:     rt.E.Host,
+			Endpoint: goengage.SearchSupporter,
+			Method:   goengage.SearchMethod,
+			Token:    rt.E.Token,
+			Request:  &rqt,
+			Response: &resp,
+		}
+		err = n.Do()
+		if err != nil {
+			return err
+		}
+		for _, s := range resp.Payload.Supporters {
+			found := false
+			for _, c := range s.CustomFieldValues {
+				if !found && c.Name == rt.Name {
+					x := NewCFRecord(s, c)
+					rt.C2 <- x
+				}
+			}
+		}
+	}
+	rt.D <- true
+	log.Printf("Supporters %d: begin\n", i)
+	return nil
+}
+
+// TotalRecords reads the first page of supporters and returns
+// the number of records.
+func TotalRecords(rt Runtime) (int32, error) {
+	payload := goengage.SupporterSearchRequestPayload{
+		ModifiedFrom: StartDate,
+		Offset:       int32(0),
+		Count:        rt.E.BatchSize(),
+	}
+	rqt := goengage.SupporterSearchRequest{
+		Header:  goengage.RequestHeader{},
+		Payload: payload,
+	}
+	var resp goengage.SupporterSearchResults
+	n := goengage.NetOp{
+		Host:     rt.E.Host,
+		Endpoint: goengage.SearchSupporter,
+		Method:   goengage.SearchMethod,
+		Token:    rt.E.Token,
+		Request:  &rqt,
+		Response: &resp,
+	}
+	err := n.Do()
+	if err != nil {
+		return 0, err
+	}
+	return resp.Payload.Total, nil
+}
+
+// Output accepts CF records from a channel and and writes them to
+// the output file.
+func Output(rt Runtime) error {
+	log.Printf("Output: begin")
+	headers := []string{
+		"SupporterID",
+		"Email",
+		"CustomFieldID",
+		"CustomFieldName",
+		"CustomFieldValue",
+	}
+	w, err := rt.O.Create(rt.O.Filename(rt.F), headers)
+	if err != nil {
+		panic(err)
+	}
+	defer w.Close()
+	for {
+		x, ok := <-rt.C2
+		if !ok {
+			break
+		}
+		a := []string{
+			x.SupporterID,
+			x.Email,
+			x.CustomFieldID,
+			x.CustomFieldName,
+			x.CustomFieldValue,
+		}
+		w.Write(a)
+		w.Flush()
+	}
+	log.Printf("Output: end")
+	return w.Close()
+}
+
+// WaitTerminations waits for "OffsetListeners" supporter readers to
+// complete.  That triggers a close for the output channel.
+func WaitTerminations(rt Runtime) {
+	log.Printf("WaitTerminations: begin")
+	remaining := OffsetListeners
+	for remaining > 0 {
+		log.Printf("WaitTerminations: waiting for %d listeners\n", remaining)
+		<-rt.D
+		remaining--
+	}
+	close(rt.C2)
+	log.Printf("WaitTerminations: end")
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app         = kingpin.New("find_custom_field", "Find supporters that have values for the provided cusotm field.")
+		login       = app.Flag("login", "YAML file with API token").Required().String()
+		customField = app.Flag("custom_field", "Search for this custom field").Required().String()
+		format      = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip        = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+
+	// logger, err := goengage.NewUtilLogger()
+	// if err != nil {
+	// 	panic(err)
+	// }
+
+	rt := Runtime{
+		E:    e,
+		Name: *customField,
+		C0:   make(chan int32, 1000),
+		C1:   make(chan goengage.Supporter, 20_000),
+		C2:   make(chan *CFRecord, 100),
+		D:    make(chan bool, OffsetListeners),
+		F:    "find_custom_field.csv",
+		O:    opt,
+		// L:         logger,
+	}
+	var wg sync.WaitGroup
+
+	//Start the output listener.  Note wg.Add before. Should
+	//reduce race conditions.
+	wg.Add(1)
+	go (func(rt Runtime, wg *sync.WaitGroup) {
+		defer wg.Done()
+		err := Output(rt)
+		if err != nil {
+			panic(err)
+		}
+	})(rt, &wg)
+	log.Printf("main: output writer started\n")
+
+	//Start offset listeners
+	for id := 1; id <= OffsetListeners; id++ {
+		wg.Add(1)
+		go (func(rt Runtime, id int, wg *sync.WaitGroup) {
+			defer wg.Done()
+			err := Supporters(rt, id)
+			if err != nil {
+				panic(err)
+			}
+		})(rt, id, &wg)
+	}
+	log.Printf("main: %+v offset listeners started\n", OffsetListeners)
+
+	//Start "done" listener to keep track of offsets listeners.
+	wg.Add(1)
+	go (func(rt Runtime, wg *sync.WaitGroup) {
+		defer wg.Done()
+		WaitTerminations(rt)
+	})(rt, &wg)
+	log.Println("main: terminations listener started")
+
+	total, err := TotalRecords(rt)
+	if err != nil {
+		return err
+	}
+
+	//Start offset seeder
+	log.Printf("main: processing %d offsets\n", total)
+	wg.Add(1)
+	go (func(rt Runtime, wg *sync.WaitGroup) {
+		defer wg.Done()
+		Offsets(rt, total)
+	})(rt, &wg)
+	log.Printf("main: offset seeder started\n")
+
+	log.Printf("main: napping...\n")
+	time.Sleep(10 * time.Second)
+	log.Printf("main: waiting...\n")
+	wg.Wait()
+	log.Printf("main: done")
+	return nil
+}
@@ -0,0 +1,195 @@
+// App to extract phone numbers for a list of supporters. The extracted
+// data is stored in a CSV.  The CSV has one row per supporter. Each row
+// contains SupporterID, Home Phone, Cell Phone, and Work Phone.
+//
+// Unlike Classic, phone numbers in Engage are not fixed fields. They are
+// elements in the "Contacts" part of the supporter record.
+package phonenumbers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	reportSupporter "github.com/salsalabs/goengage/pkg/report"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Runtime area for this app.
+type Runtime struct {
+	E        *goengage.Environment
+	IDFile   string
+	IDs      []string
+	IdOffset int32
+	CSVOut   output.Writer
+	count    int
+}
+
+// RequestedIDs returns the list of supporterIDs from the ID file.
+// Each line of the file is a single Supporter ID.
+func (rt *Runtime) RequestedIds() error {
+	r, err := os.Open(rt.IDFile)
+	if err != nil {
+		return err
+	}
+	var a []string
+	defer r.Close()
+	fs := bufio.NewScanner(r)
+	fs.Split(bufio.ScanLines)
+	for fs.Scan() {
+		id := fs.Text()
+		id = strings.Trim(id, "'\" \t")
+		if len(id) != 36 {
+			err = fmt.Errorf("not a vaid supporterID, file %v, entry '%v'", rt.IDFile, id)
+			return err
+		}
+		a = append(a, id)
+	}
+	rt.IDs = a
+	return nil
+}
+
+// NewRuntime populates a new runtime.
+func NewRuntime(env *goengage.Environment, idFile string, out output.Writer) Runtime {
+	r := Runtime{
+		E:        env,
+		IDFile:   idFile,
+		IdOffset: 0,
+		CSVOut:   out,
+	}
+	return r
+}
+
+// Visit implements SupporterTask.Visit and does something with
+// a supporter record
+func (r *Runtime) Visit(s goengage.Supporter) error {
+	if s.Contacts == nil {
+		return nil
+	}
+	var row []string
+	for i := 0; i < 4; i++ {
+		row = append(row, "")
+	}
+	row[0] = s.SupporterID
+	for _, c := range s.Contacts {
+		switch c.Type {
+		case goengage.ContactHome:
+			row[1] = c.Value
+		case goengage.ContactCell:
+			row[2] = c.Value
+		case goengage.ContactWork:
+			row[3] = c.Value
+		}
+	}
+	err := r.CSVOut.Write(row)
+	if err != nil {
+		return err
+	}
+	log.Println(row)
+	r.count++
+	if r.count%output.FlushRows == 0 {
+		return r.CSVOut.Flush()
+	}
+	return nil
+}
+
+// Finalize implements SupporterTask.Finalize and does nothing
+// in this app.
+func (r *Runtime) Finalize() error {
+	return nil
+}
+
+// Payload implements SupporterTask.Payload and provides a payload
+// that will retrieve all supporters.
+func (r *Runtime) Payload() goengage.SupporterSearchRequestPayload {
+	low := float64(r.IdOffset)
+	remaining := float64(len(r.IDs)) - float64(low)
+	high := low + math.Min(remaining, float64(r.E.BatchSize()))
+	max := len(r.IDs)
+	current := r.IDs[int32(low):int32(high):max]
+	payload := goengage.SupporterSearchRequestPayload{
+		IdentifierType: goengage.SupporterIDType,
+		Identifiers:    current,
+		Offset:         0,
+		Count:          0,
+	}
+	r.IdOffset += r.E.BatchSize()
+	return payload
+}
+
+// Offset returns the offset for the first read.
+// Useful for restarts.
+func (r *Runtime) Offset() int32 {
+	return 0
+}
+
+//AdjustOffset allows us to change the read offset to
+//zero for the payload that we're using.
+
+func (r *Runtime) AdjustOffset(offset int32) int32 {
+	if r.IdOffset > int32(len(r.IDs)) {
+		// Offset that wll hopefully trigger end of file.
+		// Yeah, this needs some work...
+		return 1_000_000_000
+	} else {
+		return 0
+	}
+}
+
+// Main is the program entry point.  Look for supporters with an email.  Errors are noisy and fatal.
+func Main(args []string) error {
+	var (
+		app     = kingpin.New("phone_numbers", "Write a CSV of supporterIDs and phone numbers")
+		login   = app.Flag("login", "YAML file with API token").Required().String()
+		idFile  = app.Flag("input", "Text with list of Engage supporterIDs to look up").Required().String()
+		outFile = app.Flag("output", "CSV filename to store supporter-segment data").Default("phone_numbers.csv").String()
+		format  = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip    = app.Flag("gzip", "Compress the output with gzip").Bool()
+		//debug   = app.Flag("debug", "Write requests and responses to a log file in JSON").Bool()
+	)
+	app.Parse(args)
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	headers := []string{
+		"SupporterID",
+		"HomePhone",
+		"CellPhone",
+		"WorkPhone",
+	}
+	writer, err := opt.Create(opt.Filename(*outFile), headers)
+	if err != nil {
+		return err
+	}
+
+	r := NewRuntime(e, *idFile, writer)
+	err = r.RequestedIds()
+	if err != nil {
+		return err
+	}
+
+	//Only one visitor because Visit is quick in this app. More than
+	//one cases "concurrent map writes" errors.
+	err = reportSupporter.RunSupporters(r.E, &r, 1)
+	cerr := writer.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("main: done")
+	return nil
+}
@@ -0,0 +1,100 @@
+package searchbylastmodified
+
+import (
+	"errors"
+	"fmt"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Main is the program entry point.  Look for supporters in a last_modified range.
+// No values means forever.
+func Main(args []string) error {
+	var (
+		app       = kingpin.New("see-supporter", "A command-line app to to show supporters for an email.")
+		login     = app.Flag("login", "YAML file with API token").Required().String()
+		startDate = app.Flag("start", "Start of the date range").Default("2001-01-01T00:00:00.000Z").String()
+		endDate   = app.Flag("end", "End of the date range").Default("2101-01-01T00:00:00.000Z").String()
+		format    = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip      = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+
+	headers := []string{
+		"SupporterID",
+		"FirstName",
+		"LastName",
+		"LastModified",
+		"Email",
+	}
+	w, err := opt.Create(opt.Filename("last_modified.csv"), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	count := int32(e.BatchSize())
+	offset := int32(0)
+	for count == int32(e.BatchSize()) {
+		payload := goengage.SupporterSearchRequestPayload{
+			IdentifierType: goengage.EmailAddressType,
+			Offset:         offset,
+			Count:          e.BatchSize(),
+			ModifiedFrom:   *startDate,
+			ModifiedTo:     *endDate,
+		}
+		rqt := goengage.SupporterSearchRequest{
+			Header:  goengage.RequestHeader{},
+			Payload: payload,
+		}
+		var resp goengage.SupporterSearchResults
+		n := goengage.NetOp{
+			Host:     e.Host,
+			Method:   goengage.SearchMethod,
+			Endpoint: goengage.SearchSupporter,
+			Token:    e.Token,
+			Request:  &rqt,
+			Response: &resp,
+		}
+		err = n.Do()
+		if err != nil {
+			return err
+		}
+		count = resp.Payload.Count
+		for _, s := range resp.Payload.Supporters {
+			email := goengage.FirstEmail(s)
+			lastModified := fmt.Sprintf("%v", s.LastModified)
+			e := ""
+			if email != nil {
+				e = *email
+			}
+			record := []string{
+				s.SupporterID,
+				s.FirstName,
+				s.LastName,
+				lastModified,
+				e,
+			}
+			err = w.Write(record)
+			if err != nil {
+				return err
+			}
+		}
+		offset += count
+	}
+	return w.Close()
+}
@@ -0,0 +1,152 @@
+package seedistrictsall
+
+import (
+	"errors"
+	"log"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Main is the program entry point.  Look for supporters with an email.  Errors are noisy and fatal.
+func Main(args []string) error {
+	var (
+		app    = kingpin.New("see_districts", "A command-line app to write supporters and state districts to a file.")
+		login  = app.Flag("login", "YAML file with API token").Required().String()
+		format = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip   = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	fn := "supporters_and_districts.csv"
+	headers := []string{"FirstName",
+		"LastName",
+		"Email",
+		"Phone",
+		"AddressLine1",
+		"AddressLine2",
+		"City",
+		"State",
+		"PostalCode",
+		"StateHouse",
+		"StateSenate",
+	}
+	w, err := opt.Create(opt.Filename(fn), headers)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	withDistricts := int32(0)
+	count := e.BatchSize()
+	offset := int32(0)
+	for count == e.BatchSize() {
+		payload := goengage.SupporterSearchRequestPayload{
+			ModifiedFrom: "2006-01-01T00:00:00.000Z",
+			Offset:       offset,
+			Count:        e.BatchSize(),
+		}
+		rqt := goengage.SupporterSearchRequest{
+			Header:  goengage.RequestHeader{},
+			Payload: payload,
+		}
+		var resp goengage.SupporterSearchResults
+
+		n := goengage.NetOp{
+			Host:     e.Host,
+			Method:   goengage.SearchMethod,
+			Endpoint: goengage.SearchSupporter,
+			Token:    e.Token,
+			Request:  &rqt,
+			Response: &resp,
+		}
+		err = n.Do()
+		if err != nil {
+			return err
+		}
+		for _, s := range resp.Payload.Supporters {
+			email := goengage.FirstEmail(s)
+			if s.Result != goengage.Found {
+				// emailTemp := ""
+				// if email != nil {
+				// 	emailTemp = *email
+				// }
+				// log.Printf("%v %v %v %v\n",
+				// 	s.FirstName,
+				// 	s.LastName,
+				// 	emailTemp,
+				// 	s.Result)
+				continue
+			}
+			if len(s.Address.StateHouseDistrict) == 0 && len(s.Address.StateSenateDistrict) == 0 {
+				// log.Printf("%v %v %v %v\n",
+				// 	s.FirstName,
+				// 	s.LastName,
+				// 	emailTemp,
+				// 	"no districts")
+				continue
+			}
+			phone := goengage.FirstPhone(s)
+			var a []string
+			e := ""
+			if email != nil {
+				e = *email
+			}
+			p := ""
+			if phone != nil {
+				p = *phone
+			}
+			for i := 0; i < len(headers); i++ {
+				switch i {
+				case 0:
+					a = append(a, s.FirstName)
+				case 1:
+					a = append(a, s.LastName)
+				case 2:
+					a = append(a, e)
+				case 3:
+					a = append(a, p)
+				case 4:
+					a = append(a, s.Address.AddressLine1)
+				case 5:
+					a = append(a, s.Address.AddressLine2)
+				case 6:
+					a = append(a, s.Address.City)
+				case 7:
+					a = append(a, s.Address.State)
+				case 8:
+					a = append(a, s.Address.PostalCode)
+				case 9:
+					a = append(a, s.Address.StateHouseDistrict)
+				case 10:
+					a = append(a, s.Address.StateSenateDistrict)
+				}
+			}
+			w.Write(a)
+			withDistricts++
+		}
+		count = resp.Payload.Count
+		offset += count
+		log.Printf("Status: %6d of %6d with districts out of  %6d total\n", withDistricts, offset, resp.Payload.Total)
+
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	log.Printf("Done.  Output is in %v\n", opt.Filename(fn))
+	return nil
+}
@@ -0,0 +1,193 @@
+// App to write a CSV of supporters and segments.  Each row is a single
+// supporter-segment relationship.  A row contains
+// * supporterId
+// * Email
+// * segmentId
+// * segmentName
+package segmentsforall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	pipeline "github.com/salsalabs/goengage/pkg/pipeline"
+	report "github.com/salsalabs/goengage/pkg/report"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	//SupporterListenerCount is the number of workers that read
+	//segments for supporters.
+	SupporterListenerCount = 5
+)
+
+// OutRec holds a supporter-segment relationship.
+type OutRec struct {
+	Supporter goengage.Supporter
+	Segment   goengage.Segment
+}
+
+// Runtime area for this app.
+type Runtime struct {
+	E      *goengage.Environment
+	CSVOut output.Writer
+	Check  *goengage.Checkpoint
+	count  int32
+}
+
+// NewRuntime populates a new runtime.
+func NewRuntime(env *goengage.Environment, out output.Writer, cp *goengage.Checkpoint) Runtime {
+	r := Runtime{
+		E:      env,
+		CSVOut: out,
+		Check:  cp,
+	}
+	return r
+}
+
+// Adjust offset changes the proposed offset as needed.
+// Implements SupporterReader.AdjustOffset.
+// Useful for chunked ID reads.  Does nothing in this app.
+func (r *Runtime) AdjustOffset(offset int32) int32 {
+	return offset
+}
+
+// Payload implements SupporterReader.Payload and provides a payload
+// that will retrieve all supporters.
+func (r *Runtime) Payload() goengage.SupporterSearchRequestPayload {
+	payload := goengage.SupporterSearchRequestPayload{
+		IdentifierType: goengage.SupporterIDType,
+		ModifiedFrom:   "2000-01-01T00:00:00.00000Z",
+		ModifiedTo:     "2050-01-01T00:00:00.00000Z",
+		Offset:         0,
+		Count:          0,
+	}
+	return payload
+}
+
+// Offset returns the offset for the first read.
+// Useful for restarts.
+func (r *Runtime) Offset() int32 {
+	return 0
+}
+
+// Checkpoint implements Resumable and provides the checkpoint for
+// restarts.
+func (r *Runtime) Checkpoint() *goengage.Checkpoint {
+	return r.Check
+}
+
+// Segments is a pipeline stage.  It retrieves segments for a supporter
+// and emits a supporter-segment record for each of them.
+func (r *Runtime) Segments(ctx context.Context, x interface{}, emit pipeline.Emit) error {
+	s := x.(goengage.Supporter)
+	segments, err := goengage.SupporterSegments(r.E, s.SupporterID)
+	if err != nil {
+		return err
+	}
+	for _, g := range segments {
+		err = emit(OutRec{s, g})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write is the pipeline sink.  It writes supporter-segment records to
+// the output.
+func (r *Runtime) Write(ctx context.Context, x interface{}) error {
+	s := x.(OutRec)
+	email := ""
+	e := goengage.FirstEmail(s.Supporter)
+	if e != nil {
+		email = *e
+	}
+	row := []string{
+		s.Supporter.SupporterID,
+		email,
+		s.Segment.SegmentID,
+		s.Segment.Name,
+	}
+	err := r.CSVOut.Write(row)
+	if err != nil {
+		return err
+	}
+	r.count++
+	if r.count%output.FlushRows == 0 {
+		log.Printf("Write: %d\n", r.count)
+		return r.CSVOut.Flush()
+	}
+	return nil
+}
+
+// Main is the program entry point. Scan through supporters.  Write supporter-group
+// data to a CSV file.
+func Main(args []string) error {
+	var (
+		app     = kingpin.New("supporter_segments", "Write a CSV of supporters and segments")
+		login   = app.Flag("login", "YAML file with API token").Required().String()
+		outFile = app.Flag("output", "CSV filename to store supporter-segment data").Default("supporter_segments.csv").String()
+		resume  = app.Flag("resume", "Continue where the last run stopped and append to the CSV").Bool()
+		format  = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip    = app.Flag("gzip", "Compress the output with gzip").Bool()
+		//debug   = app.Flag("debug", "Write requests and responses to a log file in JSON").Bool()
+	)
+	app.Parse(args)
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+
+	cp, err := goengage.OpenCheckpoint(goengage.CheckpointFile(*outFile), *resume)
+	if err != nil {
+		return fmt.Errorf("%s on %s", err, *outFile)
+	}
+	//Supporters have any number of segments, so every row in a resumed
+	//file is matched.
+	opt := output.Options{
+		Format: *format,
+		Gzip:   *gzip,
+		Keys:   []string{"SupporterID", "SegmentID"},
+	}
+	headers := []string{
+		"SupporterID",
+		"Email",
+		"SegmentID",
+		"SegmentName",
+	}
+	writer, err := opt.Open(opt.Filename(*outFile), headers, cp.Resuming())
+	if err != nil {
+		return fmt.Errorf("%s on %s", err, *outFile)
+	}
+
+	//Supporters flow from the reader to the segment workers, then
+	//supporter-segment records flow to the CSV writer.
+	r := NewRuntime(e, writer, cp)
+	p := pipeline.NewPipeline("segments_for_all").
+		Source("ReadSupporters", report.SupporterSource(r.E, &r)).
+		Stage("Segments", SupporterListenerCount, r.Segments).
+		Sink("Write", r.Write)
+	cp.Rewind = int32(p.Capacity())
+	err = p.Run(context.Background())
+	cerr := writer.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("%s on %s", err, *outFile)
+	}
+	err = cp.Finish()
+	if err != nil {
+		return fmt.Errorf("%s on %s", err, cp.Filename)
+	}
+	log.Printf("main: done")
+	return nil
+}
@@ -0,0 +1,296 @@
+package segmentsforsome
+
+// An application to accept a list of supporterIDs, find the groups
+// that they belong to, and write an output file. Each row of the
+// file will contain the supporterID, supporter's email and a comma-
+// separated list of groups.
+//
+// Input is provided by a YAML file that contains a "supporterIDs:"
+// field.  The field will be a list of supporterIDs of interest.
+// For convenience's sake, the list of IDs can be added to the
+// login file, then the login file gets supplied twice in the
+// calling arguments.
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	//SettleDuration is the app's settle time in seconds before it
+	//starts waiting for things to terminate.
+	SettleDuration = "5s"
+
+	//ReaderCount is the number of SupporterID readers.
+	ReaderCount = 5
+)
+
+// OutRecord is the data that we want written to the output file.
+type OutRecord struct {
+	SupporterID string
+	Email       string
+	Segments    []goengage.Segment
+}
+
+// Runtime contains the configuration parts that this app needs.
+type Runtime struct {
+	Env         *goengage.Environment
+	ReaderCount int
+	IDChan      chan string
+	OutChan     chan OutRecord
+	DoneChan    chan bool
+	IDFile      string
+	OutFile     string
+	Output      output.Options
+	Logger      *goengage.UtilLogger
+}
+
+// BuildOut accepts a supporter key from a channel and
+// writes an OutRecord to the out channel.  Keys after an error are
+// drained so that the queue can be loaded.
+func (rt *Runtime) BuildOut(id int) (err error) {
+	for {
+		supporterId, ok := <-rt.IDChan
+		if !ok {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		err = rt.Build(id, supporterId)
+	}
+	rt.DoneChan <- true
+	log.Printf("Buildout-%d: end", id)
+	return err
+}
+
+// Build writes an OutRecord for a supporter key to the out channel.
+func (rt *Runtime) Build(id int, supporterId string) error {
+	s, err := goengage.SupporterByID(rt.Env, supporterId)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		//log.Printf("BuildOut-%d: %v does not locate a supporter\n", id, supporterId)
+		return nil
+	}
+	email := ""
+	e := goengage.FirstEmail(*s)
+	if err != nil {
+		email = *e
+	}
+	segments, err := goengage.SupporterSegments(rt.Env, supporterId)
+	if err != nil {
+		return err
+	}
+	if len(segments) > 0 {
+		r := OutRecord{
+			SupporterID: supporterId,
+			Email:       email,
+			Segments:    segments,
+		}
+		rt.OutChan <- r
+		log.Printf("BuildOut-%d: %v %d segments\n", id, supporterId, len(segments))
+	} else {
+		log.Printf("BuildOut-%d: %v does not belong to any segments\n", id, s)
+	}
+	return nil
+}
+
+// RequestedIDs returns the list of supporterIDs from a text file. Each line
+// is an id.
+func (rt *Runtime) RequestedIds() (a []string, err error) {
+	r, err := os.Open(rt.IDFile)
+	if err != nil {
+		return a, err
+	}
+	defer r.Close()
+	fs := bufio.NewScanner(r)
+	fs.Split(bufio.ScanLines)
+	for fs.Scan() {
+		id := fs.Text()
+		id = strings.Trim(id, "'\" \t")
+		if len(id) == 36 {
+			a = append(a, id)
+		} else {
+			//log.Printf("RequestedIds: file %v, '%v' is not a valid id\n", rt.IDFile, id)
+		}
+	}
+	sort.Strings(a)
+	return a, err
+}
+
+// WriteOut accepts an OutRecord from a channel and writes
+// it to the output file.  Records after an error are drained so that
+// the builders can finish.
+func (rt *Runtime) WriteOut() error {
+	defer (func() {
+		for range rt.OutChan {
+		}
+	})()
+	headers := []string{
+		"SupporterID",
+		"Email",
+		"Groups",
+	}
+	writer, err := rt.Output.Create(rt.Output.Filename(rt.OutFile), headers)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+	for {
+		r, ok := <-rt.OutChan
+		if !ok {
+			break
+		}
+		var segments []string
+
+		for _, s := range r.Segments {
+			segments = append(segments, s.Name)
+		}
+		groups := strings.Join(segments, ",")
+		row := []string{
+			r.SupporterID,
+			r.Email,
+			groups,
+		}
+		err = writer.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("WriteOut: end\n")
+	return writer.Close()
+}
+
+// WaitForReaders waits for readers to send to the done channel.
+// Closes the out channel when all readers are done.
+func (rt *Runtime) WaitForReaders() {
+	count := rt.ReaderCount
+	for count > 0 {
+		log.Printf("WaitForReaders: Waiting for %d readers\n", count)
+		_, ok := <-rt.DoneChan
+		if !ok {
+			break
+		}
+		count--
+	}
+	close(rt.OutChan)
+	log.Println("WaitForReaders: done")
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app     = kingpin.New("segments_for_supporters", "Write a file of supporters and segments for a list of supporter IDs")
+		login   = app.Flag("login", "YAML file with API token").Required().String()
+		idFile  = app.Flag("input", "Text with list of Engage supporterIDs to look up").Required().String()
+		outFile = app.Flag("output", "CSV filename to store supporter-segment data").Default("supporters_and_segments.csv").String()
+		debug   = app.Flag("debug", "Write requests and responses to a log file in JSON").Bool()
+		format  = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip    = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	if idFile == nil || len(*idFile) == 0 {
+		idFile = login
+	}
+	if outFile == nil || len(*outFile) == 0 {
+		return errors.New("--output is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+
+	var logger *goengage.UtilLogger
+	if *debug {
+		logger, err = goengage.NewUtilLogger()
+		if err != nil {
+			return err
+		}
+	}
+
+	rtx := Runtime{
+		Env:         e,
+		ReaderCount: ReaderCount,
+		IDChan:      make(chan string, 100),
+		OutChan:     make(chan OutRecord, 100),
+		DoneChan:    make(chan bool),
+		IDFile:      *idFile,
+		OutFile:     *outFile,
+		Output:      opt,
+		Logger:      logger,
+	}
+	rt := &rtx
+
+	requestedIds, err := rt.RequestedIds()
+	if err != nil {
+		return err
+	}
+
+	var fe goengage.FirstError
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go (func(rt *Runtime, wg *sync.WaitGroup) {
+		defer wg.Done()
+		rt.WaitForReaders()
+	})(rt, &wg)
+	log.Println("main: started reader waiter")
+
+	wg.Add(1)
+	go (func(rt *Runtime, wg *sync.WaitGroup) {
+		defer wg.Done()
+		err := rt.WriteOut()
+		if err != nil {
+			fe.Set(err)
+		}
+	})(rt, &wg)
+	log.Println("main: started output writer")
+
+	for i := 1; i <= rt.ReaderCount; i++ {
+		wg.Add(1)
+		go (func(rt *Runtime, wg *sync.WaitGroup, i int) {
+			defer wg.Done()
+			err := rt.BuildOut(i)
+			if err != nil {
+				fe.Set(fmt.Errorf("BuildOut-%d: %w", i, err))
+			}
+		})(rt, &wg, i)
+	}
+	log.Println("main: started output builders")
+
+	// Load the input queue.
+	for _, id := range requestedIds {
+		rt.IDChan <- id
+	}
+	close(rt.IDChan)
+	log.Printf("main: main queue loaded with %d supporterIds\n", len(requestedIds))
+
+	//Settle time.
+	d, _ := time.ParseDuration(SettleDuration)
+	log.Printf("main: waiting %v seconds to let things settle\n", d.Seconds())
+	time.Sleep(d)
+	log.Println("main: running...")
+	wg.Wait()
+	log.Println("main: done")
+	return fe.Err()
+}
@@ -0,0 +1,75 @@
+package updatecustomfield
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Main is the program entry point.  Look for supporters with an email.  Errors are noisy and fatal.
+func Main(args []string) error {
+	var (
+		app        = kingpin.New("update-custom-field", "A command-line app to modify a custom field.")
+		login      = app.Flag("login", "YAML file with API token").Required().String()
+		email      = app.Flag("email", "Supporter's email address").Required().String()
+		fieldName  = app.Flag("fieldName", "Custom field name to modify").Required().String()
+		fieldValue = app.Flag("fieldValue", "Value to assign to `fieldName`").Required().String()
+	)
+	app.Parse(args)
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	if email == nil || len(*email) == 0 {
+		return errors.New("--email is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	s, err := goengage.SupporterByEmail(e, *email)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Println("--------------- Supporter Found ----------------")
+	fmt.Print(string(b))
+	fmt.Println("")
+
+	fmt.Printf("Changing %s to '%s'\n", *fieldName, *fieldValue)
+	found := false
+	for _, c := range s.CustomFieldValues {
+		if c.Name == *fieldName {
+			c.Value = *fieldValue
+			found = true
+		}
+	}
+	if !found {
+		x := fmt.Sprintf("error: '%v' is not a valid custom field name", *fieldName)
+		fmt.Println(x)
+		fmt.Println("Please choose from one of these field names")
+		for _, c := range s.CustomFieldValues {
+			fmt.Printf("* %s\n", c.Name)
+		}
+		return nil
+	}
+	result, err := goengage.SupporterUpsert(e, s, nil)
+	if err != nil {
+		fmt.Printf("Upsert failed with %s\n", err)
+	}
+	b, err = json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		fmt.Printf("JSON marshall error, %s\n", err)
+		fmt.Printf("Supporter si %+v\n", result)
+	} else {
+		fmt.Println("--------------- Supporter Results ----------------")
+		fmt.Print(string(b))
+		fmt.Println("")
+	}
+	return nil
+}
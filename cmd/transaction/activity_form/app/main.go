@@ -0,0 +1,164 @@
+package activityform
+
+//Application to create a file of transactions for a activity_form. You
+//provide credentials and a activity_form_KEY.  This app writes a file of
+//the activity_form's transactions.
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Runtime holds the stuff that this app needs.
+type Runtime struct {
+	Env            *goengage.Environment
+	IncludeCounts  bool
+	CSVFilename    string
+	Output         output.Options
+	Logger         *goengage.UtilLogger
+	ActivityFormID string
+}
+
+func NewRuntime(e *goengage.Environment, f string, opt output.Options, s string, v bool) (*Runtime, error) {
+	rt := Runtime{
+		Env:            e,
+		CSVFilename:    f,
+		Output:         opt,
+		ActivityFormID: s,
+	}
+	if v {
+		logger, err := goengage.NewUtilLogger()
+		if err != nil {
+			return nil, err
+		}
+		rt.Logger = logger
+	}
+	return &rt, nil
+}
+
+// Run finds and displays all transactions.
+func Run(rt *Runtime) error {
+	log.Println("Run: begin")
+	headers := []string{
+		"CreatedDate",
+		"TransactionID",
+		"ActivityName",
+		"ActivityFormID",
+		"Amount",
+		"TemplateID",
+		"TransactionDate",
+		"TransactionType",
+		"DeductibleAmount",
+		"FeesPaid",
+		"Result",
+	}
+	writer, err := rt.Output.Create(rt.Output.Filename(rt.CSVFilename), headers)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	count := rt.Env.BatchSize()
+	offset := int32(0)
+
+	for count == rt.Env.BatchSize() {
+		payload := goengage.TransactionSearchRequestPayload{
+			Identifiers:    []string{rt.ActivityFormID},
+			IdentifierType: goengage.ActivityFormID,
+			Count:          count,
+			Offset:         offset,
+		}
+		fmt.Printf("Request payload: %+v\n", payload)
+		rqt := goengage.TransactionSearchRequest{
+			Header:  goengage.RequestHeader{},
+			Payload: payload,
+		}
+
+		var resp goengage.TransactionSearchResponse
+
+		n := goengage.NetOp{
+			Host:     rt.Env.Host,
+			Method:   goengage.SearchMethod,
+			Endpoint: goengage.SearchTransactionDetails,
+			Token:    rt.Env.Token,
+			Request:  &rqt,
+			Response: &resp,
+			Logger:   rt.Logger,
+		}
+		err := n.Do()
+		if err != nil {
+			return err
+		}
+		if offset%100 == 0 {
+			log.Printf("Run: %6d: %2d of %6d\n",
+				offset,
+				len(resp.Payload.Transactions),
+				resp.Payload.Total)
+		}
+
+		for _, w := range resp.Payload.Transactions {
+			s := w.DonationTransaction
+			record := []string{
+				s.CreatedDate,
+				s.TransactionID,
+				s.ActivityName,
+				s.ActivityFormID,
+				fmt.Sprintf("%v", s.Amount),
+				s.TemplateID,
+				s.TransactionDate,
+				s.TransactionType,
+				fmt.Sprintf("%v", s.DeductibleAmount),
+				fmt.Sprintf("%v", s.FeesPaid),
+				s.Result,
+			}
+			err = writer.Write(record)
+			if err != nil {
+				return err
+			}
+		}
+		count = resp.Payload.Count
+		offset += int32(count)
+	}
+	log.Printf("Run: end")
+	return writer.Close()
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app            = kingpin.New("activity_form_transactions", "Creates a file of transactions for a activity_form")
+		login          = app.Flag("login", "YAML file with API token").Required().String()
+		csvFile        = app.Flag("csv", "CSV filename to create").Default("activity_form_transactions.csv").String()
+		activityFormID = app.Flag("activityFormID", "Find transactions for this activity_form").Required().String()
+		verbose        = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format         = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip           = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	if login == nil || len(*login) == 0 {
+		return errors.New("--login is required")
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	rt, err := NewRuntime(e, *csvFile, opt, *activityFormID, *verbose)
+	if err != nil {
+		return err
+	}
+	err = Run(rt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
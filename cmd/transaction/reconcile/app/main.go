@@ -0,0 +1,388 @@
+package reconcile
+
+//Application to reconcile a payment gateway's settlement file against
+//Engage transactions.  The gateway file is a CSV.  Command-line flags
+//tell the app which columns hold the gateway transaction ID, amount and
+//the like.  Gateway rows are matched to Engage transactions by gateway
+//transaction ID or by authorization code.  The output is a file with one
+//line for each matched, mismatched or missing transaction.
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	report "github.com/salsalabs/goengage/pkg/report"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Reconciliation status.
+const (
+	Matched          = "MATCHED"
+	AmountMismatch   = "AMOUNT_MISMATCH"
+	MissingInEngage  = "MISSING_IN_ENGAGE"
+	MissingInGateway = "MISSING_IN_GATEWAY"
+)
+
+// Tolerance is the largest difference between two amounts that are
+// considered to be equal.
+const Tolerance = 0.005
+
+// Columns names the gateway CSV columns that the app uses.  Empty
+// names are not used.
+type Columns struct {
+	ID            string
+	Authorization string
+	Amount        string
+	Fees          string
+	Date          string
+}
+
+// GatewayRow is a single transaction from the gateway file.
+type GatewayRow struct {
+	Line          int
+	ID            string
+	Authorization string
+	Amount        float64
+	Fees          float64
+	Date          *time.Time
+}
+
+// Outcome is a single line of reconciliation output.
+type Outcome struct {
+	Status  string
+	Gateway *GatewayRow
+	Engage  *goengage.DonationTransaction
+}
+
+// Runtime holds the stuff that this app needs.  Span is shifted to
+// Zulu for Engage.  StartDate and EndDate are the client's calendar
+// dates, in BriefFormat, for filtering the gateway file.
+type Runtime struct {
+	Env        *goengage.Environment
+	Span       report.Span
+	StartDate  string
+	EndDate    string
+	Columns    Columns
+	DateFormat string
+	Location   *time.Location
+	Logger     *goengage.UtilLogger
+}
+
+// Money parses a currency amount.  Dollar signs and commas are ignored.
+func Money(s string) (float64, error) {
+	s = strings.Replace(s, "$", "", -1)
+	s = strings.Replace(s, ",", "", -1)
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// ReadGateway reads the gateway CSV.  Rows dated outside of the start
+// and end dates in the client's timezone are skipped.
+func (rt *Runtime) ReadGateway(fn string) ([]*GatewayRow, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	headers, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int)
+	for i, h := range headers {
+		index[strings.TrimSpace(h)] = i
+	}
+	column := func(name string) (int, error) {
+		if len(name) == 0 {
+			return -1, nil
+		}
+		i, ok := index[name]
+		if !ok {
+			return -1, fmt.Errorf("column '%s' is not in %s", name, fn)
+		}
+		return i, nil
+	}
+	c := rt.Columns
+	var ids [5]int
+	for i, name := range []string{c.ID, c.Authorization, c.Amount, c.Fees, c.Date} {
+		ids[i], err = column(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	value := func(line []string, i int) string {
+		if i < 0 || i >= len(line) {
+			return ""
+		}
+		return strings.TrimSpace(line[i])
+	}
+
+	var a []*GatewayRow
+	for n := 2; ; n++ {
+		line, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := GatewayRow{
+			Line:          n,
+			ID:            value(line, ids[0]),
+			Authorization: value(line, ids[1]),
+		}
+		row.Amount, err = Money(value(line, ids[2]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount, %v", n, err)
+		}
+		row.Fees, err = Money(value(line, ids[3]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid fees, %v", n, err)
+		}
+		if s := value(line, ids[4]); len(s) != 0 {
+			t, err := time.ParseInLocation(rt.DateFormat, s, rt.Location)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid date, %v", n, err)
+			}
+			d := t.In(rt.Location).Format(report.BriefFormat)
+			if d < rt.StartDate || d > rt.EndDate {
+				continue
+			}
+			row.Date = &t
+		}
+		a = append(a, &row)
+	}
+	return a, nil
+}
+
+// ReadEngage reads the Engage transactions in the time span.  Offline
+// transactions never went through the gateway and are skipped.
+func (rt *Runtime) ReadEngage() ([]goengage.DonationTransaction, error) {
+	ts := report.NewTimeSpan(rt.Span.S, rt.Span.E)
+	payload := goengage.TransactionSearchRequestPayload{
+		TransactionFrom: ts.Start,
+		TransactionTo:   ts.End,
+	}
+	a, err := goengage.Transactions(rt.Env, payload, rt.Logger)
+	if err != nil {
+		return nil, err
+	}
+	var b []goengage.DonationTransaction
+	for _, t := range a {
+		if !t.WasOffline {
+			b = append(b, t)
+		}
+	}
+	return b, nil
+}
+
+// Reconcile matches gateway rows to Engage transactions.  Matches are by
+// gateway transaction ID, then by authorization code.  Amounts are
+// compared without signs because gateways and Engage disagree about
+// how to show refunds.
+func Reconcile(gateway []*GatewayRow, engage []goengage.DonationTransaction) []Outcome {
+	byID := make(map[string][]int)
+	byAuth := make(map[string][]int)
+	for i, t := range engage {
+		if len(t.GatewayTransactionID) != 0 {
+			byID[t.GatewayTransactionID] = append(byID[t.GatewayTransactionID], i)
+		}
+		if len(t.GatewayAuthorizationCode) != 0 {
+			byAuth[t.GatewayAuthorizationCode] = append(byAuth[t.GatewayAuthorizationCode], i)
+		}
+	}
+	used := make([]bool, len(engage))
+
+	//find returns the best unused candidate.  Candidates with equal
+	//amounts are preferred.
+	find := func(candidates []int, amount float64) int {
+		best := -1
+		for _, i := range candidates {
+			if used[i] {
+				continue
+			}
+			if math.Abs(math.Abs(float64(engage[i].Amount))-math.Abs(amount)) < Tolerance {
+				return i
+			}
+			if best < 0 {
+				best = i
+			}
+		}
+		return best
+	}
+
+	var a []Outcome
+	for _, g := range gateway {
+		i := -1
+		if len(g.ID) != 0 {
+			i = find(byID[g.ID], g.Amount)
+		}
+		if i < 0 && len(g.Authorization) != 0 {
+			i = find(byAuth[g.Authorization], g.Amount)
+		}
+		if i < 0 {
+			a = append(a, Outcome{Status: MissingInEngage, Gateway: g})
+			continue
+		}
+		used[i] = true
+		status := Matched
+		if math.Abs(math.Abs(float64(engage[i].Amount))-math.Abs(g.Amount)) >= Tolerance {
+			status = AmountMismatch
+		}
+		a = append(a, Outcome{Status: status, Gateway: g, Engage: &engage[i]})
+	}
+	for i := range engage {
+		if !used[i] {
+			a = append(a, Outcome{Status: MissingInGateway, Engage: &engage[i]})
+		}
+	}
+	return a
+}
+
+// WriteOutcomes writes the reconciliation to a file in the output format
+// and returns the count for each status.
+func WriteOutcomes(opt output.Options, fn string, outcomes []Outcome) (map[string]int, error) {
+	counts := make(map[string]int)
+	headers := []string{
+		"Status",
+		"GatewayLine",
+		"GatewayTransactionID",
+		"GatewayAuthorizationCode",
+		"GatewayDate",
+		"GatewayAmount",
+		"GatewayFees",
+		"EngageTransactionID",
+		"EngageTransactionDate",
+		"EngageTransactionType",
+		"EngageAmount",
+		"EngageFees",
+		"SupporterID",
+		"ActivityName",
+	}
+	w, err := opt.Create(opt.Filename(fn), headers)
+	if err != nil {
+		return counts, err
+	}
+	defer w.Close()
+	for _, x := range outcomes {
+		counts[x.Status]++
+		record := make([]string, len(headers))
+		record[0] = x.Status
+		if g := x.Gateway; g != nil {
+			record[1] = fmt.Sprintf("%d", g.Line)
+			record[2] = g.ID
+			record[3] = g.Authorization
+			if g.Date != nil {
+				record[4] = g.Date.Format(report.BriefFormat)
+			}
+			record[5] = fmt.Sprintf("%.2f", g.Amount)
+			record[6] = fmt.Sprintf("%.2f", g.Fees)
+		}
+		if t := x.Engage; t != nil {
+			if len(record[2]) == 0 {
+				record[2] = t.GatewayTransactionID
+				record[3] = t.GatewayAuthorizationCode
+			}
+			record[7] = t.TransactionID
+			record[8] = t.TransactionDate
+			record[9] = t.TransactionType
+			record[10] = fmt.Sprintf("%.2f", t.Amount)
+			record[11] = fmt.Sprintf("%.2f", t.FeesPaid)
+			record[12] = t.SupporterID
+			record[13] = t.ActivityName
+		}
+		err = w.Write(record)
+		if err != nil {
+			return counts, err
+		}
+	}
+	return counts, w.Close()
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	var (
+		app          = kingpin.New("reconcile", "Reconcile a payment gateway settlement CSV against Engage transactions")
+		login        = app.Flag("login", "YAML file with API token").Required().String()
+		gatewayFile  = app.Flag("gateway", "Gateway settlement CSV").Required().String()
+		csvFile      = app.Flag("csv", "CSV filename for the reconciliation").Default("reconcile.csv").String()
+		startDate    = app.Flag("startDate", "Start date, YYYY-MM-DD").Required().String()
+		endDate      = app.Flag("endDate", "End date, YYYY-MM-DD").Required().String()
+		timeZone     = app.Flag("timezone", "Client's timezone, defaults to EST/EDT").Default("America/New_York").String()
+		idColumn     = app.Flag("idColumn", "Gateway column for the gateway transaction ID").Default("Transaction ID").String()
+		authColumn   = app.Flag("authColumn", "Gateway column for the authorization code").Default("Authorization Code").String()
+		amountColumn = app.Flag("amountColumn", "Gateway column for the amount").Default("Amount").String()
+		feesColumn   = app.Flag("feesColumn", "Gateway column for fees, optional").String()
+		dateColumn   = app.Flag("dateColumn", "Gateway column for the settlement date, optional").String()
+		dateFormat   = app.Flag("dateFormat", "Go date format for the gateway date column").Default("2006-01-02").String()
+		verbose      = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format       = app.Flag("format", "Output format, changes the output file's extension").Default(output.CSV).Enum(output.Formats...)
+		gzip         = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		return err
+	}
+	rt := Runtime{
+		Env:       e,
+		Span:      report.ValidateSpan(*startDate, *endDate, location),
+		StartDate: *startDate,
+		EndDate:   *endDate,
+		Columns: Columns{
+			ID:            *idColumn,
+			Authorization: *authColumn,
+			Amount:        *amountColumn,
+			Fees:          *feesColumn,
+			Date:          *dateColumn,
+		},
+		DateFormat: *dateFormat,
+		Location:   location,
+	}
+	if *verbose {
+		rt.Logger, err = goengage.NewUtilLogger()
+		if err != nil {
+			return err
+		}
+	}
+	gateway, err := rt.ReadGateway(*gatewayFile)
+	if err != nil {
+		return err
+	}
+	log.Printf("main: %d gateway transactions\n", len(gateway))
+	engage, err := rt.ReadEngage()
+	if err != nil {
+		return err
+	}
+	log.Printf("main: %d Engage transactions\n", len(engage))
+	counts, err := WriteOutcomes(opt, *csvFile, Reconcile(gateway, engage))
+	if err != nil {
+		return err
+	}
+	for _, s := range []string{Matched, AmountMismatch, MissingInEngage, MissingInGateway} {
+		log.Printf("main: %-20s %6d\n", s, counts[s])
+	}
+	return nil
+}
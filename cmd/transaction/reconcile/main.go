@@ -0,0 +1,16 @@
+package main
+
+//Runs the reconcile app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	reconcile "github.com/salsalabs/goengage/cmd/transaction/reconcile/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("reconcile", reconcile.Main, os.Args[1:])
+}
@@ -0,0 +1,280 @@
+package templates
+
+//Application to export transaction templates and their transactions.
+//Templates are the parents of one-time and recurring donations.  Each
+//template is written to a templates file.  The transactions that belong
+//to the templates are written to a transactions file.
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	report "github.com/salsalabs/goengage/pkg/report"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Runtime holds the stuff that this app needs.
+type Runtime struct {
+	Env              *goengage.Environment
+	CreatedFrom      string
+	CreatedTo        string
+	DonationType     string
+	TemplateFile     string
+	TransactionsFile string
+	Output           output.Options
+	Logger           *goengage.UtilLogger
+}
+
+// NewRuntime returns an initialized Runtime.
+func NewRuntime(e *goengage.Environment, span report.Span, donationType string, templateFile string, transactionsFile string, opt output.Options, v bool) (*Runtime, error) {
+	ts := report.NewTimeSpan(span.S, span.E)
+	rt := Runtime{
+		Env:              e,
+		CreatedFrom:      ts.Start,
+		CreatedTo:        ts.End,
+		DonationType:     donationType,
+		TemplateFile:     templateFile,
+		TransactionsFile: transactionsFile,
+		Output:           opt,
+	}
+	if v {
+		logger, err := goengage.NewUtilLogger()
+		if err != nil {
+			return nil, err
+		}
+		rt.Logger = logger
+	}
+	return &rt, nil
+}
+
+// Templates returns the templates created in the time span that
+// match the donation type.
+func (rt *Runtime) Templates() ([]goengage.TransactionTemplate, error) {
+	payload := goengage.TransactionTemplateSearchRequestPayload{
+		CreatedFrom: rt.CreatedFrom,
+		CreatedTo:   rt.CreatedTo,
+	}
+	a, err := goengage.TransactionTemplates(rt.Env, payload, rt.Logger)
+	if err != nil {
+		return nil, err
+	}
+	if rt.DonationType == "All" {
+		return a, nil
+	}
+	var b []goengage.TransactionTemplate
+	for _, t := range a {
+		if t.DonationType == rt.DonationType {
+			b = append(b, t)
+		}
+	}
+	return b, nil
+}
+
+// Transactions returns the transactions for the provided templates.
+// Templates are sent to Engage in batches.
+func (rt *Runtime) Transactions(templates []goengage.TransactionTemplate) ([]goengage.DonationTransaction, error) {
+	var a []goengage.DonationTransaction
+	var ids []string
+	size := int(rt.Env.BatchSize())
+	for i, t := range templates {
+		ids = append(ids, t.TransactionTemplateID)
+		if len(ids) == size || i == len(templates)-1 {
+			payload := goengage.TransactionSearchRequestPayload{
+				Identifiers:    ids,
+				IdentifierType: goengage.TemplateID,
+			}
+			b, err := goengage.Transactions(rt.Env, payload, rt.Logger)
+			if err != nil {
+				return a, err
+			}
+			log.Printf("Transactions: %5d of %5d templates, %3d transactions\n", i+1, len(templates), len(b))
+			a = append(a, b...)
+			ids = nil
+		}
+	}
+	return a, nil
+}
+
+// WriteTemplates writes templates to a file in the output format.
+func (rt *Runtime) WriteTemplates(templates []goengage.TransactionTemplate) error {
+	headers := []string{
+		"TemplateID",
+		"CreatedDate",
+		"ActivityDate",
+		"PersonID",
+		"ActivityID",
+		"ActivityFormID",
+		"ActivityName",
+		"DonationType",
+		"LastTransactionType",
+		"OneTimeAmount",
+		"RecurringAmount",
+		"RecurringInterval",
+		"RecurringStart",
+		"RecurringEnd",
+		"TotalReceivedAmount",
+		"FeesPaid",
+		"FundName",
+		"CampaignName",
+		"AppealName",
+		"Designation",
+		"DedicationType",
+		"Dedication",
+		"IsFirstDonation",
+		"WasImported",
+		"Result",
+	}
+	writer, err := rt.Output.Create(rt.Output.Filename(rt.TemplateFile), headers)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+	for _, t := range templates {
+		record := []string{
+			t.TransactionTemplateID,
+			t.CreatedDate,
+			t.ActivityDate,
+			t.PersonID,
+			t.ActivityID,
+			t.ActivityFormID,
+			t.ActivityName,
+			t.DonationType,
+			t.LastTransactionType,
+			fmt.Sprintf("%.2f", t.OneTimeAmount),
+			fmt.Sprintf("%.2f", t.RecurringAmount),
+			t.RecurringInterval,
+			t.RecurringStart,
+			t.RecurringEnd,
+			fmt.Sprintf("%.2f", t.TotalReceivedAmount),
+			fmt.Sprintf("%.2f", t.FeesPaid),
+			t.FundName,
+			t.CampaignName,
+			t.AppealName,
+			t.Designation,
+			t.DedicationType,
+			t.Dedication,
+			fmt.Sprintf("%v", t.IsFirstDonation),
+			fmt.Sprintf("%v", t.WasImported),
+			t.Result,
+		}
+		err = writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// WriteTransactions writes transactions to a file in the output format.
+func (rt *Runtime) WriteTransactions(transactions []goengage.DonationTransaction) error {
+	headers := []string{
+		"TemplateID",
+		"TransactionID",
+		"RelatedTransactionID",
+		"TransactionDate",
+		"TransactionType",
+		"ActivityName",
+		"SupporterID",
+		"Amount",
+		"DeductibleAmount",
+		"FeesPaid",
+		"WasOffline",
+		"Result",
+	}
+	writer, err := rt.Output.Create(rt.Output.Filename(rt.TransactionsFile), headers)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+	for _, s := range transactions {
+		record := []string{
+			s.TemplateID,
+			s.TransactionID,
+			s.RelatedTransactionID,
+			s.TransactionDate,
+			s.TransactionType,
+			s.ActivityName,
+			s.SupporterID,
+			fmt.Sprintf("%.2f", s.Amount),
+			fmt.Sprintf("%.2f", s.DeductibleAmount),
+			fmt.Sprintf("%.2f", s.FeesPaid),
+			fmt.Sprintf("%v", s.WasOffline),
+			s.Result,
+		}
+		err = writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// Run finds templates and their transactions, then writes them to files.
+func Run(rt *Runtime) error {
+	log.Println("Run: begin")
+	templates, err := rt.Templates()
+	if err != nil {
+		return err
+	}
+	log.Printf("Run: %d templates\n", len(templates))
+	err = rt.WriteTemplates(templates)
+	if err != nil {
+		return err
+	}
+	transactions, err := rt.Transactions(templates)
+	if err != nil {
+		return err
+	}
+	log.Printf("Run: %d transactions\n", len(transactions))
+	err = rt.WriteTransactions(transactions)
+	if err != nil {
+		return err
+	}
+	log.Println("Run: end")
+	return nil
+}
+
+// Main is the program entry point.
+func Main(args []string) error {
+	donationTypes := []string{"All", goengage.OneTime, goengage.Recurring}
+	var (
+		app              = kingpin.New("templates", "Creates files of transaction templates and their transactions")
+		login            = app.Flag("login", "YAML file with API token").Required().String()
+		startDate        = app.Flag("startDate", "Templates created on or after this date, YYYY-MM-DD").Default("2000-01-01").String()
+		endDate          = app.Flag("endDate", "Templates created on or before this date, YYYY-MM-DD, default is today").Default(time.Now().Format(report.BriefFormat)).String()
+		timeZone         = app.Flag("timezone", "Client's timezone, defaults to EST/EDT").Default("America/New_York").String()
+		donationType     = app.Flag("donationType", "Choose All, ONE_TIME or RECURRING").Default("All").Enum(donationTypes...)
+		templateFile     = app.Flag("templates", "CSV filename for templates").Default("templates.csv").String()
+		transactionsFile = app.Flag("transactions", "CSV filename for template transactions").Default("template_transactions.csv").String()
+		verbose          = app.Flag("verbose", "Log all requests and responses to a file.  Verrrry noisy...").Bool()
+		format           = app.Flag("format", "Output format, changes the output files' extensions").Default(output.CSV).Enum(output.Formats...)
+		gzip             = app.Flag("gzip", "Compress the output with gzip").Bool()
+	)
+	app.Parse(args)
+	opt := output.Options{Format: *format, Gzip: *gzip}
+	err := opt.Validate()
+	if err != nil {
+		return err
+	}
+	e, err := goengage.Credentials(*login)
+	if err != nil {
+		return err
+	}
+	location, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		return err
+	}
+	span := report.ValidateSpan(*startDate, *endDate, location)
+	rt, err := NewRuntime(e, span, *donationType, *templateFile, *transactionsFile, opt, *verbose)
+	if err != nil {
+		return err
+	}
+	err = Run(rt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
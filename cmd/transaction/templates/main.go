@@ -0,0 +1,16 @@
+package main
+
+//Runs the templates app.  The app is in the app package so that the goengage
+//command can run it too.
+
+import (
+	"os"
+
+	templates "github.com/salsalabs/goengage/cmd/transaction/templates/app"
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Program entry point.
+func main() {
+	goengage.Run("templates", templates.Main, os.Args[1:])
+}
@@ -1,6 +1,9 @@
 package goengage
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // ActivityRequest is used to retrieve activities from Engage.
 // Note that ActivityRequest can be used to retrieve activities based
@@ -25,6 +28,55 @@ type ActivityRequestPayload struct {
 	IdentifierType  string   `json:"identifierType,omitempty"`
 }
 
+// ActivityPage is a page of activities from an activity search.
+type ActivityPage interface {
+	//PageCount returns the number of activities in the page.
+	PageCount() int32
+
+	//PageErrors returns the errors that Engage returned for the page.
+	PageErrors() []Error
+}
+
+// ActivityPages reads all of the activities that match the provided
+// payload.  Page returns an empty response for each page.  Visit is called
+// with each page.  Offset and Count in the payload are managed here.
+// Returns the first error from Engage, from a call, or from visit.
+func ActivityPages(e *Environment, payload ActivityRequestPayload, logger *UtilLogger, page func() ActivityPage, visit func(p ActivityPage) error) error {
+	count := e.BatchSize()
+	for count == e.BatchSize() {
+		payload.Count = e.BatchSize()
+		rqt := ActivityRequest{
+			Header:  RequestHeader{},
+			Payload: payload,
+		}
+		resp := page()
+		n := NetOp{
+			Host:     e.Host,
+			Method:   SearchMethod,
+			Endpoint: SearchActivity,
+			Token:    e.Token,
+			Request:  &rqt,
+			Response: resp,
+			Logger:   logger,
+		}
+		err := n.Do()
+		if err != nil {
+			return err
+		}
+		if a := resp.PageErrors(); len(a) != 0 {
+			x := a[0]
+			return fmt.Errorf("ActivityPages: %s %v %v %v", payload.Type, x.Code, x.Message, x.Details)
+		}
+		err = visit(resp)
+		if err != nil {
+			return err
+		}
+		count = resp.PageCount()
+		payload.Offset += count
+	}
+	return nil
+}
+
 // BaseActivity returns activity information from SUBSCRIBE or
 // SUBSCRIPTION_MANAGEMENT requests.  Note that Base is actually
 // contained in the other activity result objects.
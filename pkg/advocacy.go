@@ -55,6 +55,19 @@ type PetitionResponse struct {
 		Count      int32      `json:"count,omitempty"`
 		Activities []Petition `json:"activities,omitempty"`
 	} `json:"payload,omitempty"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// PageCount returns the number of activities in the page.
+// Implements ActivityPage.
+func (r *PetitionResponse) PageCount() int32 {
+	return r.Payload.Count
+}
+
+// PageErrors returns the errors that Engage returned for the page.
+// Implements ActivityPage.
+func (r *PetitionResponse) PageErrors() []Error {
+	return r.Errors
 }
 
 // TargetedLetterResponse is returned when the request is "TARGETED_LETTERS".
@@ -66,4 +79,47 @@ type TargetedLetterResponse struct {
 		Count      int32            `json:"count,omitempty"`
 		Activities []TargetedLetter `json:"activities,omitempty"`
 	} `json:"payload,omitempty"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// PageCount returns the number of activities in the page.
+// Implements ActivityPage.
+func (r *TargetedLetterResponse) PageCount() int32 {
+	return r.Payload.Count
+}
+
+// PageErrors returns the errors that Engage returned for the page.
+// Implements ActivityPage.
+func (r *TargetedLetterResponse) PageErrors() []Error {
+	return r.Errors
+}
+
+// Petitions returns all of the petition activities that match the
+// provided payload.  Type, Offset and Count in the payload are managed
+// here.
+func Petitions(e *Environment, payload ActivityRequestPayload, logger *UtilLogger) ([]Petition, error) {
+	var a []Petition
+	payload.Type = PetitionType
+	err := ActivityPages(e, payload, logger,
+		func() ActivityPage { return &PetitionResponse{} },
+		func(p ActivityPage) error {
+			a = append(a, p.(*PetitionResponse).Payload.Activities...)
+			return nil
+		})
+	return a, err
+}
+
+// TargetedLetters returns all of the targeted letter activities that match
+// the provided payload.  Type, Offset and Count in the payload are managed
+// here.
+func TargetedLetters(e *Environment, payload ActivityRequestPayload, logger *UtilLogger) ([]TargetedLetter, error) {
+	var a []TargetedLetter
+	payload.Type = TargetedLetterType
+	err := ActivityPages(e, payload, logger,
+		func() ActivityPage { return &TargetedLetterResponse{} },
+		func(p ActivityPage) error {
+			a = append(a, p.(*TargetedLetterResponse).Payload.Activities...)
+			return nil
+		})
+	return a, err
 }
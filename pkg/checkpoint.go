@@ -0,0 +1,408 @@
+package goengage
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// CheckpointExtension is added to an output filename to name its
+// checkpoint file.
+const CheckpointExtension = ".checkpoint"
+
+// Checkpoint records how far readers have gone.  Each reader uses a key.
+// Offset readers record the pages that they have finished.  The offset
+// for a key is the low-water mark.  All pages below it are done, even if
+// pages were finished out of order.  Cursor readers record the cursor
+// for the next read.
+//
+// Checkpoints are saved to a JSON file after each change.  The file is
+// written to a temporary file, then renamed, so that it's never half
+// written.  A nil Checkpoint does nothing.  That lets readers use a
+// checkpoint without checking whether there is one.
+//
+// Rewind is the number of records that an app can hold after a reader
+// hands them off.  Readers back up by Rewind records when they resume.
+// Highs records the end of the highest page that an offset reader has
+// finished.  Records between the resumed start and Highs are read again.
+type Checkpoint struct {
+	sync.Mutex
+	Filename string            `json:"-"`
+	Resumed  bool              `json:"-"`
+	Rewind   int32             `json:"-"`
+	Offsets  map[string]int32  `json:"offsets"`
+	Highs    map[string]int32  `json:"highs"`
+	Cursors  map[string]string `json:"cursors"`
+	pages    map[string]map[int32]int32
+}
+
+// CheckpointFile returns the checkpoint filename for an output file.
+func CheckpointFile(fn string) string {
+	return fn + CheckpointExtension
+}
+
+// OpenCheckpoint returns a checkpoint that is saved in a file.  If resume
+// is true, then the checkpoint is read from the file if it exists.  If
+// resume is false, then the checkpoint starts empty.
+func OpenCheckpoint(fn string, resume bool) (*Checkpoint, error) {
+	c := Checkpoint{
+		Filename: fn,
+		Offsets:  make(map[string]int32),
+		Highs:    make(map[string]int32),
+		Cursors:  make(map[string]string),
+		pages:    make(map[string]map[int32]int32),
+	}
+	if !resume {
+		return &c, nil
+	}
+	raw, err := os.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return &c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(raw, &c)
+	if err != nil {
+		return nil, err
+	}
+	if c.Offsets == nil {
+		c.Offsets = make(map[string]int32)
+	}
+	if c.Highs == nil {
+		c.Highs = make(map[string]int32)
+	}
+	if c.Cursors == nil {
+		c.Cursors = make(map[string]string)
+	}
+	c.Resumed = true
+	return &c, nil
+}
+
+// Resuming returns true if the checkpoint was read from a file.
+func (c *Checkpoint) Resuming() bool {
+	return c != nil && c.Resumed
+}
+
+// Offset returns the low-water mark for a key.
+func (c *Checkpoint) Offset(key string) int32 {
+	if c == nil {
+		return 0
+	}
+	c.Lock()
+	defer c.Unlock()
+	return c.Offsets[key]
+}
+
+// Resume returns the offset where a reader should start.  The start backs
+// up to cover records that were read but not yet written when the last
+// run stopped.  Those are the records in the reader's channel and the
+// checkpoint's Rewind.  The start is a multiple of the batch size.
+// Returns the provided offset if it's larger.  A reader that starts past
+// the low-water mark moves the mark to its start.
+func (c *Checkpoint) Resume(key string, offset int32, inFlight int32, batchSize int32) int32 {
+	if c == nil {
+		return offset
+	}
+	c.Lock()
+	defer c.Unlock()
+	low := c.Offsets[key]
+	x := low - inFlight - c.Rewind
+	if batchSize > 0 {
+		x = x - x%batchSize
+	}
+	if x < offset {
+		x = offset
+	}
+	if x > low {
+		c.Offsets[key] = x
+	}
+	return x
+}
+
+// Replay returns the number of records that a reader that resumes at
+// start reads again.  Those are the records from start up to the highest
+// page that was finished when the last run stopped.
+func (c *Checkpoint) Replay(key string, start int32) int32 {
+	if c == nil {
+		return 0
+	}
+	c.Lock()
+	defer c.Unlock()
+	high := c.Highs[key]
+	if low := c.Offsets[key]; low > high {
+		high = low
+	}
+	if high < start {
+		return 0
+	}
+	return high - start
+}
+
+// Complete records that the page from offset up to next is done.  The
+// checkpoint is saved if the low-water mark or the highest page moves.
+// Readers that don't start at zero must call Resume first.
+func (c *Checkpoint) Complete(key string, offset int32, next int32) error {
+	if c == nil {
+		return nil
+	}
+	c.Lock()
+	defer c.Unlock()
+	low := c.Offsets[key]
+	if offset < low {
+		return nil
+	}
+	m, ok := c.pages[key]
+	if !ok {
+		m = make(map[int32]int32)
+		c.pages[key] = m
+	}
+	m[offset] = next
+	moved := false
+	if next > c.Highs[key] {
+		c.Highs[key] = next
+		moved = true
+	}
+	for {
+		n, ok := m[low]
+		if !ok {
+			break
+		}
+		delete(m, low)
+		low = n
+	}
+	if old, ok := c.Offsets[key]; ok && old == low && !moved {
+		return nil
+	}
+	c.Offsets[key] = low
+	return c.save()
+}
+
+// Cursor returns the saved cursor for a key.
+func (c *Checkpoint) Cursor(key string) string {
+	if c == nil {
+		return ""
+	}
+	c.Lock()
+	defer c.Unlock()
+	return c.Cursors[key]
+}
+
+// SetCursor saves the cursor for the next read.
+func (c *Checkpoint) SetCursor(key string, cursor string) error {
+	if c == nil {
+		return nil
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.Cursors[key] = cursor
+	return c.save()
+}
+
+// Finish removes the checkpoint file.  Call it when all of the readers
+// are done.  If a shutdown has started, then the file is kept so that the
+// command can be resumed, and Finish returns ErrStopped.
+func (c *Checkpoint) Finish() error {
+	if c == nil {
+		return nil
+	}
+	err := Stopping.Err()
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	err = os.Remove(c.Filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// save writes the checkpoint to a temporary file, then renames it.  The
+// checkpoint must be locked.
+func (c *Checkpoint) save() error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.Filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.Filename)
+}
+
+// AppendCSV writes records to a CSV file.  A resumed file is appended to.
+// Records that were written near the end of the file are skipped, so rows
+// that are read again after a restart aren't duplicated.  Rows are matched
+// on key columns when they're provided, and on the whole row when they
+// aren't.  Open files are flushed by Stopping if the process has to exit
+// early.
+type AppendCSV struct {
+	sync.Mutex
+	File    *os.File
+	Writer  *csv.Writer
+	Skipped int
+	keys    []int
+	seen    map[string]int
+	flusher int
+}
+
+// OpenAppendCSV opens a CSV file.  If resume is false, then the file is
+// created.  If resume is true, then new records are appended and a partial
+// line at the end of the file is removed.  Records that match the file's
+// header row or one of its last window rows are skipped.  Each row in the
+// file matches one record.  A window of zero matches every row.  Keys are
+// the names of the header columns that identify a row.  The whole row is
+// used if there aren't any keys or if the file doesn't have them.
+func OpenAppendCSV(fn string, resume bool, keys []string, window int) (*AppendCSV, error) {
+	a := AppendCSV{seen: make(map[string]int)}
+	if !resume {
+		f, err := os.Create(fn)
+		if err != nil {
+			return nil, err
+		}
+		a.File = f
+		a.Writer = csv.NewWriter(f)
+		a.flusher = Stopping.Register(a.Flush)
+		return &a, nil
+	}
+	f, err := os.OpenFile(fn, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	n := bytes.LastIndexByte(b, '\n') + 1
+	if n != len(b) {
+		b = b[:n]
+		err = f.Truncate(int64(n))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	r := csv.NewReader(bytes.NewReader(b))
+	r.FieldsPerRecord = -1
+	var header []string
+	var tail [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if header == nil {
+			header = record
+			continue
+		}
+		tail = append(tail, record)
+		if window > 0 && len(tail) >= 2*window {
+			tail = append([][]string(nil), tail[len(tail)-window:]...)
+		}
+	}
+	if window > 0 && len(tail) > window {
+		tail = tail[len(tail)-window:]
+	}
+	a.keys = keyColumns(header, keys)
+	if header != nil {
+		a.seen[a.key(header)]++
+	}
+	for _, record := range tail {
+		a.seen[a.key(record)]++
+	}
+	_, err = f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	a.File = f
+	a.Writer = csv.NewWriter(f)
+	a.flusher = Stopping.Register(a.Flush)
+	return &a, nil
+}
+
+// keyColumns returns the indexes of the keys in the header.  Returns nil
+// if any of the keys are missing.
+func keyColumns(header []string, keys []string) []int {
+	var a []int
+	for _, k := range keys {
+		i := 0
+		for i < len(header) && header[i] != k {
+			i++
+		}
+		if i == len(header) {
+			return nil
+		}
+		a = append(a, i)
+	}
+	return a
+}
+
+// key returns the map key for a record.
+func (a *AppendCSV) key(record []string) string {
+	if len(a.keys) == 0 {
+		return strings.Join(record, "\x1f")
+	}
+	var b []string
+	for _, i := range a.keys {
+		if i < len(record) {
+			b = append(b, record[i])
+		}
+	}
+	return strings.Join(b, "\x1f")
+}
+
+// Write writes a record unless it matches a row that was in the file
+// when it was opened.
+func (a *AppendCSV) Write(record []string) error {
+	a.Lock()
+	defer a.Unlock()
+	k := a.key(record)
+	if a.seen[k] > 0 {
+		a.seen[k]--
+		a.Skipped++
+		return nil
+	}
+	return a.Writer.Write(record)
+}
+
+// Flush writes buffered records to the file.
+func (a *AppendCSV) Flush() error {
+	a.Lock()
+	defer a.Unlock()
+	a.Writer.Flush()
+	return a.Writer.Error()
+}
+
+// Close flushes the records and closes the file.
+func (a *AppendCSV) Close() error {
+	Stopping.Unregister(a.flusher)
+	err := a.Flush()
+	if cerr := a.File.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
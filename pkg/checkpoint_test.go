@@ -0,0 +1,240 @@
+package goengage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// page is a finished page for a checkpoint test.
+type page struct {
+	offset int32
+	next   int32
+}
+
+func TestCheckpointComplete(t *testing.T) {
+	tests := []struct {
+		name  string
+		start int32
+		pages []page
+		low   int32
+		high  int32
+	}{
+		{"none", 0, nil, 0, 0},
+		{"in order", 0, []page{{0, 20}, {20, 40}, {40, 60}}, 60, 60},
+		{"out of order", 0, []page{{20, 40}, {40, 60}, {0, 20}}, 60, 60},
+		{"gap", 0, []page{{0, 20}, {40, 60}}, 20, 60},
+		{"gap filled last", 0, []page{{40, 60}, {0, 20}, {20, 40}}, 60, 60},
+		{"short last page", 0, []page{{0, 20}, {20, 33}}, 33, 33},
+		{"below the mark", 40, []page{{0, 20}, {40, 60}}, 60, 60},
+		{"not at the mark", 40, []page{{60, 80}}, 40, 80},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := OpenCheckpoint(filepath.Join(t.TempDir(), "x.checkpoint"), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Resume("k", tt.start, 0, 20)
+			for _, p := range tt.pages {
+				err = c.Complete("k", p.offset, p.next)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := c.Offset("k"); got != tt.low {
+				t.Errorf("Offset = %d, want %d", got, tt.low)
+			}
+			if got := c.Highs["k"]; got != tt.high {
+				t.Errorf("Highs = %d, want %d", got, tt.high)
+			}
+		})
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	tests := []struct {
+		name     string
+		low      int32
+		high     int32
+		offset   int32
+		inFlight int32
+		rewind   int32
+		batch    int32
+		start    int32
+		replay   int32
+	}{
+		{"empty", 0, 0, 0, 101, 0, 20, 0, 0},
+		{"backs up", 400, 400, 0, 101, 0, 20, 280, 120},
+		{"rewind", 400, 400, 0, 10, 50, 20, 340, 60},
+		{"offset is larger", 400, 400, 380, 101, 0, 20, 380, 20},
+		{"pages above the mark", 400, 480, 0, 10, 0, 20, 380, 100},
+		{"no batch", 400, 400, 0, 15, 0, 0, 385, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := filepath.Join(t.TempDir(), "x.checkpoint")
+			c, err := OpenCheckpoint(fn, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Offsets["k"] = tt.low
+			c.Highs["k"] = tt.high
+			err = c.save()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err = OpenCheckpoint(fn, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !c.Resuming() {
+				t.Fatal("Resuming = false, want true")
+			}
+			c.Rewind = tt.rewind
+			start := c.Resume("k", tt.offset, tt.inFlight, tt.batch)
+			if start != tt.start {
+				t.Errorf("Resume = %d, want %d", start, tt.start)
+			}
+			if got := c.Replay("k", start); got != tt.replay {
+				t.Errorf("Replay = %d, want %d", got, tt.replay)
+			}
+		})
+	}
+}
+
+func TestCheckpointNil(t *testing.T) {
+	var c *Checkpoint
+	if got := c.Resume("k", 40, 10, 20); got != 40 {
+		t.Errorf("Resume = %d, want 40", got)
+	}
+	if err := c.Complete("k", 40, 60); err != nil {
+		t.Errorf("Complete = %v, want nil", err)
+	}
+	if c.Resuming() {
+		t.Error("Resuming = true, want false")
+	}
+}
+
+func TestAppendCSV(t *testing.T) {
+	headers := []string{"ID", "Name"}
+	tests := []struct {
+		name    string
+		file    string
+		keys    []string
+		window  int
+		rows    [][]string
+		skipped int
+		want    string
+	}{
+		{
+			name:    "whole rows",
+			file:    "ID,Name\n1,a\n2,b\n",
+			rows:    [][]string{{"2", "b"}, {"3", "c"}},
+			skipped: 2,
+			want:    "ID,Name\n1,a\n2,b\n3,c\n",
+		},
+		{
+			name:    "partial line",
+			file:    "ID,Name\n1,a\n2,",
+			rows:    [][]string{{"2", "b"}},
+			skipped: 1,
+			want:    "ID,Name\n1,a\n2,b\n",
+		},
+		{
+			name:    "key column",
+			file:    "ID,Name\n1,a\n2,b\n",
+			keys:    []string{"ID"},
+			rows:    [][]string{{"2", "changed"}, {"3", "c"}},
+			skipped: 2,
+			want:    "ID,Name\n1,a\n2,b\n3,c\n",
+		},
+		{
+			name:    "missing key",
+			file:    "ID,Name\n1,a\n",
+			keys:    []string{"Other"},
+			rows:    [][]string{{"1", "changed"}},
+			skipped: 1,
+			want:    "ID,Name\n1,a\n1,changed\n",
+		},
+		{
+			name:    "window",
+			file:    "ID,Name\n1,a\n2,b\n3,c\n",
+			keys:    []string{"ID"},
+			window:  2,
+			rows:    [][]string{{"1", "a"}, {"3", "c"}},
+			skipped: 2,
+			want:    "ID,Name\n1,a\n2,b\n3,c\n1,a\n",
+		},
+		{
+			name:    "each row matches once",
+			file:    "ID,Name\n1,a\n",
+			rows:    [][]string{{"1", "a"}, {"1", "a"}},
+			skipped: 2,
+			want:    "ID,Name\n1,a\n1,a\n",
+		},
+		{
+			name: "new file",
+			rows: [][]string{{"1", "a"}},
+			want: "ID,Name\n1,a\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := filepath.Join(t.TempDir(), "x.csv")
+			if len(tt.file) != 0 {
+				err := os.WriteFile(fn, []byte(tt.file), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			a, err := OpenAppendCSV(fn, true, tt.keys, tt.window)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range append([][]string{headers}, tt.rows...) {
+				err = a.Write(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = a.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Skipped != tt.skipped {
+				t.Errorf("Skipped = %d, want %d", a.Skipped, tt.skipped)
+			}
+			b, err := os.ReadFile(fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); got != tt.want {
+				t.Errorf("file = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyColumns(t *testing.T) {
+	header := []string{"A", "B", "C"}
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"C"}, "2"},
+		{[]string{"C", "A"}, "2,0"},
+		{[]string{"A", "D"}, ""},
+	}
+	for _, tt := range tests {
+		var a []string
+		for _, i := range keyColumns(header, tt.keys) {
+			a = append(a, string(rune('0'+i)))
+		}
+		if got := strings.Join(a, ","); got != tt.want {
+			t.Errorf("keyColumns(%v) = %s, want %s", tt.keys, got, tt.want)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package goengage
 
+import "strconv"
+
 //Search for email blasts and return activity.
 // Search: see https://api.salsalabs.org/help/integration#operation/emailsSearch
 // Activity: see https://help.salsalabs.com/hc/en-us/articles/360019505914-Engage-API-Email-Results
@@ -157,3 +159,100 @@ type SingleBlastRecipientsData struct {
 	Recipients []SingleBlastRecipient `json:"recipients,omitempty"`
 	Total      int32                  `json:"total,omitempty"`
 }
+
+// EmailBlasts returns all of the email blasts that match the provided
+// payload.  Offset and Count in the payload are managed here.
+func EmailBlasts(e *Environment, payload EmailBlastSearchRequestPayload, logger *UtilLogger) ([]EmailActivity, error) {
+	var a []EmailActivity
+	count := e.BatchSize()
+	offset := int32(0)
+	for count == e.BatchSize() {
+		payload.Offset = offset
+		payload.Count = count
+		rqt := EmailBlastSearchRequest{
+			Header:  RequestHeader{},
+			Payload: payload,
+		}
+		var resp EmailBlastSearchResponse
+		n := NetOp{
+			Host:     e.Host,
+			Method:   SearchMethod,
+			Endpoint: EmailBlastSearch,
+			Token:    e.Token,
+			Request:  &rqt,
+			Response: &resp,
+			Logger:   logger,
+		}
+		err := n.Do()
+		if err != nil {
+			return a, err
+		}
+		a = append(a, resp.Payload.EmailActivities...)
+		count = resp.Payload.Count
+		offset += count
+	}
+	return a, nil
+}
+
+// BlastRecipients reads the recipients for a single blast and calls the
+// provided function for each of them.  Recipients are read with a cursor
+// until Engage runs out.  Errors from the function terminate.
+func BlastRecipients(e *Environment, id string, blastType string, logger *UtilLogger, fn func(SingleBlastRecipient) error) error {
+	return ComponentRecipients(e, id, "", blastType, logger, fn)
+}
+
+// ComponentRecipients reads the recipients for a single component of a
+// blast, like a step in a comm series.  An empty content ID reads all of
+// the blast's recipients.  See BlastRecipients.
+func ComponentRecipients(e *Environment, id string, contentID string, blastType string, logger *UtilLogger, fn func(SingleBlastRecipient) error) error {
+	cursor := ""
+	for {
+		payload := IndivualBlastRequestPayload{
+			ID:        id,
+			ContentID: contentID,
+			Type:      blastType,
+			Cursor:    cursor,
+		}
+		rqt := IndivualBlastRequest{
+			Header:  RequestHeader{},
+			Payload: payload,
+		}
+		var resp IndividualBlastResponse
+		n := NetOp{
+			Host:     e.Host,
+			Method:   SearchMethod,
+			Endpoint: IndividualBlastSearch,
+			Token:    e.Token,
+			Request:  &rqt,
+			Response: &resp,
+			Logger:   logger,
+		}
+		err := n.Do()
+		if err != nil {
+			return err
+		}
+		cursor = ""
+		for _, s := range resp.Payload.IndividualEmailActivityData {
+			for _, r := range s.RecipientsData.Recipients {
+				err = fn(r)
+				if err != nil {
+					return err
+				}
+			}
+			cursor = s.Cursor
+		}
+		if len(cursor) == 0 {
+			return nil
+		}
+	}
+}
+
+// AmountValue returns the conversion amount as a number.  Returns zero
+// if the amount is empty or is not a number.
+func (c Conversion) AmountValue() float64 {
+	x, err := strconv.ParseFloat(c.Amount, 64)
+	if err != nil {
+		return 0
+	}
+	return x
+}
@@ -1,9 +1,27 @@
 package goengage
 
-import "time"
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
 
 // Web Developer list-of-blasts endpoint.
-// Dee https://api.salsalabs.org/help/web-dev#operation/getBlastList
+// See https://api.salsalabs.org/help/web-dev#operation/getBlastList
+
+// Sort fields for the list-of-blasts endpoint.
+const (
+	BlastSortName        = "name"
+	BlastSortDescription = "description"
+	BlastSortPublishDate = "publishDate"
+	BlastSortStatus      = "status"
+)
+
+// Sort orders for the Web Developer API.
+const (
+	Ascending  = "ASCENDING"
+	Descending = "DESCENDING"
+)
 
 // BlastListRequest is a convenience struct used to record the
 // search criteria for the list-of-blasts endpoint. Note that the
@@ -28,7 +46,7 @@ type BlastContent struct {
 	WebVersionRedirectDate time.Time `json:"webVersionRedirectDate,omitempty"`
 }
 
-// BlastListResponsePayload carries the tthe blast information that's
+// BlastListResult carries the blast information that's
 // not considered content.
 type BlastListResult struct {
 	ID           string         `json:"id,omitempty"`
@@ -41,7 +59,7 @@ type BlastListResult struct {
 	Content      []BlastContent `json:"content,omitempty"`
 }
 
-// BlastListResponse is the payload from Engage for the list-of-blasts endpoint.
+// BlastListResponsePayload is the payload from Engage for the list-of-blasts endpoint.
 type BlastListResponsePayload struct {
 	Total   int32             `json:"total,omitempty"`
 	Offset  int32             `json:"offset,omitempty"`
@@ -54,6 +72,172 @@ type BlastListResponse struct {
 	ID        string                   `json:"id,omitempty"`
 	Timestamp *time.Time               `json:"timestamp,omitempty"`
 	Header    Header                   `json:"header,omitempty"`
-	Payload   BlastListResponsePayload `json:"payload,,omitempty"`
-	Errors    []Error                  `json:"errors,,omitempty"`
+	Payload   BlastListResponsePayload `json:"payload,omitempty"`
+	Errors    []Error                  `json:"errors,omitempty"`
+}
+
+// SetDates sets the date range for the request.  Zero times are not used.
+func (r *BlastListRequest) SetDates(start time.Time, end time.Time) {
+	r.StartDate = ""
+	r.EndDate = ""
+	if !start.IsZero() {
+		r.StartDate = start.UTC().Format(EngageDateFormat)
+	}
+	if !end.IsZero() {
+		r.EndDate = end.UTC().Format(EngageDateFormat)
+	}
+}
+
+// SetSort sets the sort field and order for the request.
+func (r *BlastListRequest) SetSort(field string, order string) {
+	r.SortField = field
+	r.SortOrder = order
+}
+
+// Values returns the request as URL query values.  Empty fields are
+// not included.
+func (r BlastListRequest) Values() url.Values {
+	v := url.Values{}
+	set := func(k string, s string) {
+		if len(s) != 0 {
+			v.Set(k, s)
+		}
+	}
+	set("startDate", r.StartDate)
+	set("endDate", r.EndDate)
+	set("criteria", r.Criteria)
+	set("sortField", r.SortField)
+	set("sortOrder", r.SortOrder)
+	if r.Count > 0 {
+		v.Set("count", fmt.Sprintf("%d", r.Count))
+	}
+	if r.Offset > 0 {
+		v.Set("offset", fmt.Sprintf("%d", r.Offset))
+	}
+	return v
+}
+
+// Endpoint returns the list-of-blasts endpoint with the request
+// encoded as queries.
+func (r BlastListRequest) Endpoint() string {
+	q := r.Values().Encode()
+	if len(q) == 0 {
+		return EmailBlastList
+	}
+	return EmailBlastList + "?" + q
+}
+
+// BlastList returns a single batch of blasts for the request.
+func BlastList(e *Environment, r BlastListRequest, logger *UtilLogger) (*BlastListResponse, error) {
+	var resp BlastListResponse
+	n := NetOp{
+		Host:     e.Host,
+		Method:   EnquireMethod,
+		Endpoint: r.Endpoint(),
+		Token:    e.Token,
+		Response: &resp,
+		Logger:   logger,
+	}
+	err := n.Do()
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) != 0 {
+		x := resp.Errors[0]
+		return nil, fmt.Errorf("blast list error %d, %s, %s", x.Code, x.Message, x.Details)
+	}
+	return &resp, nil
+}
+
+// BlastListIterator reads blasts from the list-of-blasts endpoint one
+// batch at a time.  Typical use:
+//
+//	it := NewBlastListIterator(e, r, nil)
+//	for it.Next() {
+//		b := it.Result()
+//		...
+//	}
+//	if it.Err() != nil {
+//		...
+//	}
+type BlastListIterator struct {
+	Env     *Environment
+	Request BlastListRequest
+	Logger  *UtilLogger
+	batch   []BlastListResult
+	index   int
+	done    bool
+	err     error
+}
+
+// NewBlastListIterator returns an iterator for the request.  Reading
+// starts at the request's offset.  A request count of zero uses the
+// environment's maximum batch size.
+func NewBlastListIterator(e *Environment, r BlastListRequest, logger *UtilLogger) *BlastListIterator {
+	if r.Count <= 0 {
+		r.Count = e.BatchSize()
+	}
+	return &BlastListIterator{
+		Env:     e,
+		Request: r,
+		Logger:  logger,
+		index:   -1,
+	}
+}
+
+// Next advances to the next blast.  Returns false when there are no more
+// blasts or when an error occurs.  Use Err to see which.
+func (it *BlastListIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	it.index++
+	if it.index < len(it.batch) {
+		return true
+	}
+	if it.done {
+		return false
+	}
+	resp, err := BlastList(it.Env, it.Request, it.Logger)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.batch = resp.Payload.Results
+	it.index = 0
+	count := int32(len(it.batch))
+	it.Request.Offset += count
+	it.done = count < it.Request.Count
+	return count > 0
+}
+
+// Result returns the current blast.
+func (it *BlastListIterator) Result() BlastListResult {
+	return it.batch[it.index]
+}
+
+// Offset returns the offset of the current blast.  Useful for restarts.
+func (it *BlastListIterator) Offset() int32 {
+	return it.Request.Offset - int32(len(it.batch)) + int32(it.index)
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (it *BlastListIterator) Err() error {
+	return it.err
+}
+
+// EachBlastContent calls the provided function for each content item of
+// each blast that matches the request.  Errors from the function terminate.
+func EachBlastContent(e *Environment, r BlastListRequest, logger *UtilLogger, fn func(BlastListResult, BlastContent) error) error {
+	it := NewBlastListIterator(e, r, logger)
+	for it.Next() {
+		b := it.Result()
+		for _, c := range b.Content {
+			err := fn(b, c)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return it.Err()
 }
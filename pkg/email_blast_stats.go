@@ -0,0 +1,164 @@
+package goengage
+
+import (
+	"sort"
+	"time"
+)
+
+//Accumulates recipient statistics for email blasts.  Statistics can be
+//for a whole blast or for part of a blast, like a split or a comm series
+//step.
+
+// OpenBucket is a range of times between sending an email and the first
+// time that a recipient opened it.
+type OpenBucket struct {
+	Name  string
+	Limit time.Duration
+}
+
+// OpenBuckets are the ranges used for time-to-first-open distributions.
+// The last bucket catches everything else.
+var OpenBuckets = []OpenBucket{
+	{"Under1Hour", time.Hour},
+	{"1To6Hours", 6 * time.Hour},
+	{"6To24Hours", 24 * time.Hour},
+	{"1To3Days", 72 * time.Hour},
+	{"3To7Days", 168 * time.Hour},
+	{"Over7Days", 0},
+}
+
+// BlastStats contains the statistics for a blast or part of a blast.
+type BlastStats struct {
+	BlastID      string         `json:"blastId,omitempty"`
+	BlastName    string         `json:"blastName,omitempty"`
+	SplitName    string         `json:"splitName,omitempty"`
+	Sent         int            `json:"sent"`
+	Delivered    int            `json:"delivered"`
+	Opened       int            `json:"opened"`
+	Clicked      int            `json:"clicked"`
+	Converted    int            `json:"converted"`
+	Unsubscribed int            `json:"unsubscribed"`
+	Bounced      int            `json:"bounced"`
+	Bounces      map[string]int `json:"bounces,omitempty"`
+	Conversions  int            `json:"conversions"`
+	Revenue      float64        `json:"revenue"`
+	FirstOpens   map[string]int `json:"firstOpens,omitempty"`
+	delays       []time.Duration
+}
+
+// NewBlastStats returns an initialized BlastStats.
+func NewBlastStats(id string, name string, split string) *BlastStats {
+	return &BlastStats{
+		BlastID:    id,
+		BlastName:  name,
+		SplitName:  split,
+		Bounces:    make(map[string]int),
+		FirstOpens: make(map[string]int),
+	}
+}
+
+// Add accumulates the statistics for a single recipient.
+func (b *BlastStats) Add(r SingleBlastRecipient) {
+	b.Sent++
+	if len(r.BounceCategory) != 0 {
+		b.Bounced++
+		b.Bounces[r.BounceCategory]++
+	} else {
+		b.Delivered++
+	}
+	if r.Opened {
+		b.Opened++
+	}
+	if r.Clicked {
+		b.Clicked++
+	}
+	if r.Converted {
+		b.Converted++
+	}
+	if r.Unsubscribed {
+		b.Unsubscribed++
+	}
+	for _, c := range r.ConversionData {
+		b.Conversions++
+		b.Revenue += c.AmountValue()
+	}
+	sent, err := Date(r.TimeSent)
+	if err != nil || sent == nil {
+		return
+	}
+	opened, err := Date(r.FirstOpenDate)
+	if err != nil || opened == nil {
+		return
+	}
+	d := opened.Sub(*sent)
+	if d < 0 {
+		d = 0
+	}
+	b.delays = append(b.delays, d)
+	for _, x := range OpenBuckets {
+		if x.Limit == 0 || d < x.Limit {
+			b.FirstOpens[x.Name]++
+			break
+		}
+	}
+}
+
+// Rate returns n as a fraction of the delivered count.
+func (b *BlastStats) Rate(n int) float64 {
+	if b.Delivered == 0 {
+		return 0
+	}
+	return float64(n) / float64(b.Delivered)
+}
+
+// OpenRate is the fraction of delivered emails that were opened.
+func (b *BlastStats) OpenRate() float64 {
+	return b.Rate(b.Opened)
+}
+
+// ClickRate is the fraction of delivered emails that were clicked.
+func (b *BlastStats) ClickRate() float64 {
+	return b.Rate(b.Clicked)
+}
+
+// ConversionRate is the fraction of delivered emails that converted.
+func (b *BlastStats) ConversionRate() float64 {
+	return b.Rate(b.Converted)
+}
+
+// UnsubscribeRate is the fraction of delivered emails that unsubscribed.
+func (b *BlastStats) UnsubscribeRate() float64 {
+	return b.Rate(b.Unsubscribed)
+}
+
+// BounceRate is the fraction of sent emails that bounced.
+func (b *BlastStats) BounceRate() float64 {
+	if b.Sent == 0 {
+		return 0
+	}
+	return float64(b.Bounced) / float64(b.Sent)
+}
+
+// ClickToOpenRate is the fraction of opened emails that were clicked.
+func (b *BlastStats) ClickToOpenRate() float64 {
+	if b.Opened == 0 {
+		return 0
+	}
+	return float64(b.Clicked) / float64(b.Opened)
+}
+
+// MedianTimeToOpen returns the median time between sending and the
+// first open.  Returns zero if nothing was opened.
+func (b *BlastStats) MedianTimeToOpen() time.Duration {
+	n := len(b.delays)
+	if n == 0 {
+		return 0
+	}
+	a := make([]time.Duration, n)
+	copy(a, b.delays)
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+	if n%2 == 1 {
+		return a[n/2]
+	}
+	return (a[n/2-1] + a[n/2]) / 2
+}
@@ -0,0 +1,199 @@
+package goengage
+
+//The engagement package builds email engagement profiles for supporters.
+//A profile counts the sends, opens, clicks and conversions for a supporter
+//across blasts.  Each profile gets a recency/frequency score from 0 to 100.
+//
+//Recency is 1.0 for an open at the as-of date.  It halves every half-life
+//after that.  Recency is zero for supporters that never opened.
+//
+//Frequency is (opens + clicks + conversions) / (3 * sends), so a
+//supporter that opens, clicks and converts on every send has a frequency
+//of 1.0.
+//
+//Score is 100 * (weight * recency + (1 - weight) * frequency), rounded.
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Profile is the engagement history for a single supporter.
+type Profile struct {
+	SupporterID string     `json:"supporterId"`
+	Email       string     `json:"email,omitempty"`
+	Sends       int        `json:"sends"`
+	Opens       int        `json:"opens"`
+	Clicks      int        `json:"clicks"`
+	Conversions int        `json:"conversions"`
+	LastSend    *time.Time `json:"lastSend,omitempty"`
+	LastOpen    *time.Time `json:"lastOpen,omitempty"`
+	Recency     float64    `json:"recency"`
+	Frequency   float64    `json:"frequency"`
+	Score       int        `json:"score"`
+}
+
+// Profiles accumulates and scores engagement profiles.
+type Profiles struct {
+	AsOf          time.Time
+	HalfLife      time.Duration
+	RecencyWeight float64
+	m             map[string]*Profile
+}
+
+// NewProfiles returns an initialized Profiles.  Scores are computed as of
+// the provided time.
+func NewProfiles(asOf time.Time, halfLife time.Duration, recencyWeight float64) *Profiles {
+	return &Profiles{
+		AsOf:          asOf,
+		HalfLife:      halfLife,
+		RecencyWeight: recencyWeight,
+		m:             make(map[string]*Profile),
+	}
+}
+
+// Add accumulates a single blast recipient.  Bounced sends are not counted.
+func (p *Profiles) Add(r goengage.SingleBlastRecipient) {
+	if len(r.SupporterID) == 0 || len(r.BounceCategory) != 0 {
+		return
+	}
+	x, ok := p.m[r.SupporterID]
+	if !ok {
+		x = &Profile{
+			SupporterID: r.SupporterID,
+			Email:       r.SupporterEmail,
+		}
+		p.m[r.SupporterID] = x
+	}
+	x.Sends++
+	if r.Opened {
+		x.Opens++
+	}
+	if r.Clicked {
+		x.Clicks++
+	}
+	if r.Converted {
+		x.Conversions++
+	}
+	x.LastSend = latest(x.LastSend, r.TimeSent)
+	x.LastOpen = latest(x.LastOpen, r.FirstOpenDate)
+}
+
+// latest returns the later of a time and a parsed Engage date.  Dates
+// that don't parse are ignored.
+func latest(t *time.Time, s string) *time.Time {
+	u, err := goengage.Date(s)
+	if err != nil || u == nil {
+		return t
+	}
+	if t == nil || u.After(*t) {
+		return u
+	}
+	return t
+}
+
+// Score computes the recency, frequency and score for a profile.
+func (p *Profiles) Score(x *Profile) {
+	x.Recency = 0
+	if x.LastOpen != nil && p.HalfLife > 0 {
+		age := p.AsOf.Sub(*x.LastOpen)
+		if age < 0 {
+			age = 0
+		}
+		x.Recency = math.Pow(0.5, float64(age)/float64(p.HalfLife))
+	}
+	x.Frequency = 0
+	if x.Sends > 0 {
+		x.Frequency = float64(x.Opens+x.Clicks+x.Conversions) / float64(3*x.Sends)
+	}
+	s := p.RecencyWeight*x.Recency + (1-p.RecencyWeight)*x.Frequency
+	x.Score = int(math.Round(100 * s))
+}
+
+// All scores and returns the profiles, sorted by score from lowest to
+// highest, then by email.
+func (p *Profiles) All() []*Profile {
+	var a []*Profile
+	for _, x := range p.m {
+		p.Score(x)
+		a = append(a, x)
+	}
+	sort.Slice(a, func(i, j int) bool {
+		if a[i].Score != a[j].Score {
+			return a[i].Score < a[j].Score
+		}
+		return a[i].Email < a[j].Email
+	})
+	return a
+}
+
+// Collect reads the recipients of every email blast published in the
+// window and adds them to the profiles.  Dates are Engage-formatted.
+func (p *Profiles) Collect(e *goengage.Environment, publishedFrom string, publishedTo string, logger *goengage.UtilLogger) error {
+	payload := goengage.EmailBlastSearchRequestPayload{
+		PublishedFrom: publishedFrom,
+		PublishedTo:   publishedTo,
+		Type:          goengage.EmailType,
+	}
+	blasts, err := goengage.EmailBlasts(e, payload, logger)
+	if err != nil {
+		return err
+	}
+	for i, b := range blasts {
+		err := goengage.BlastRecipients(e, b.ID, goengage.EmailType, logger, func(r goengage.SingleBlastRecipient) error {
+			p.Add(r)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Collect: %4d of %4d blasts, %7d supporters, %s\n", i+1, len(blasts), len(p.m), b.Name)
+	}
+	return nil
+}
+
+// WriteScores stores the profiles' scores in a supporter custom field.
+// Each upsert stores a batch of scores.
+func WriteScores(e *goengage.Environment, a []*Profile, fieldID string, fieldName string, logger *goengage.UtilLogger) error {
+	size := int(e.BatchSize())
+	err := goengage.APICalls.Plan(goengage.Plan{
+		Name:      "scores",
+		Endpoint:  goengage.UpsertSupporter,
+		Total:     int32(len(a)),
+		BatchSize: e.BatchSize(),
+	})
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(a); i += size {
+		j := i + size
+		if j > len(a) {
+			j = len(a)
+		}
+		var b []goengage.Supporter
+		for _, x := range a[i:j] {
+			s := goengage.Supporter{
+				SupporterID: x.SupporterID,
+				CustomFieldValues: []goengage.CustomFieldValue{
+					{
+						FieldID: fieldID,
+						Name:    fieldName,
+						Value:   fmt.Sprintf("%d", x.Score),
+					},
+				},
+			}
+			b = append(b, s)
+		}
+		_, err := goengage.SupportersUpsert(e, b, logger)
+		if err != nil {
+			return err
+		}
+		log.Printf("WriteScores: %7d of %7d scores stored\n", j, len(a))
+	}
+	return nil
+}
@@ -2,61 +2,119 @@ package goengage
 
 import (
 	"errors"
+	"log"
 	"net/http"
-	"os"
-
-	yaml "gopkg.in/yaml.v2"
+	"sync"
+	"time"
 )
 
-// Environment is the Engage environment.
+// DefaultBatchSize is the batch size used when metrics can't be read.
+const DefaultBatchSize = int32(20)
+
+// Environment is the Engage environment.  Metrics are read the first time
+// that they are needed and cached.  MetricsInterval is how long the cached
+// metrics are used.  Zero means that they are used until UpdateMetrics is
+// called.  An offline environment never reads metrics.  Its batch size is
+// the MaxBatchSize in Metrics.
 type Environment struct {
-	Host    string
-	Token   string
-	Metrics Metrics
+	Host            string
+	Token           string
+	Metrics         Metrics
+	Profile         Profile
+	Offline         bool
+	MetricsInterval time.Duration
+	cache           *metricsCache
+}
+
+// metricsCache protects the metrics when an environment is shared by
+// goroutines.  It's a pointer so that environments can be copied.
+type metricsCache struct {
+	sync.Mutex
+	read time.Time
 }
 
 // NewEnvironment creates a new Environment and initializes the metrics.
-// Panics if updating the metrics returns an error.
+// Panics if updating the metrics returns an error.  Use OpenEnvironment
+// to see errors instead.
 func NewEnvironment(h string, t string) Environment {
+	e, err := OpenEnvironment(h, t)
+	if err == nil {
+		err = e.UpdateMetrics()
+	}
+	if err != nil {
+		panic(err)
+	}
+	return *e
+}
+
+// OpenEnvironment creates a new Environment.  Metrics are not read until
+// they are needed.  Returns an error if the host or the token is missing.
+func OpenEnvironment(h string, t string) (*Environment, error) {
+	if len(h) == 0 {
+		return nil, errors.New("an API host is *required*")
+	}
+	if len(t) == 0 {
+		return nil, errors.New("an API token is *required*")
+	}
 	e := Environment{
 		Host:  h,
 		Token: t,
+		cache: &metricsCache{},
+	}
+	return &e, nil
+}
+
+// OfflineEnvironment creates an Environment that never reads metrics.
+// Use it to replay requests or with a fake server.  Returns an error if
+// the host or the token is missing, or if the batch size isn't positive.
+func OfflineEnvironment(h string, t string, maxBatchSize int32) (*Environment, error) {
+	if maxBatchSize < 1 {
+		return nil, errors.New("offline batch size must be at least 1")
 	}
-	err := (&e).UpdateMetrics()
+	e, err := OpenEnvironment(h, t)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return e
+	e.Offline = true
+	e.Metrics.MaxBatchSize = maxBatchSize
+	return e, nil
 }
 
 // Credentials reads a YAML file containing an Engage API host
 // and an Engage API token.  These are then stored into an
-// environment object.
+// environment object.  Files with named profiles use the profile
+// in GOENGAGE_PROFILE or the file's default profile.  See
+// ProfileCredentials.
 func Credentials(fn string) (*Environment, error) {
-	if len(fn) == 0 {
-		return nil, errors.New(" configuration file is *required*")
-	}
-	var c struct {
-		Token string `json:"token"`
-		Host  string `json:"host"`
-	}
-	raw, err := os.ReadFile(fn)
-	if err != nil {
-		return nil, err
+	return ProfileCredentials(fn, "")
+}
+
+// lock returns the environment's metrics cache, locked.  Environments
+// that weren't created by a constructor get a cache here.
+func (e *Environment) lock() *metricsCache {
+	if e.cache == nil {
+		e.cache = &metricsCache{}
 	}
-	err = yaml.Unmarshal(raw, &c)
-	if err != nil {
-		return nil, err
+	e.cache.Lock()
+	return e.cache
+}
+
+// stale returns true if the metrics need to be read.
+func (e *Environment) stale() bool {
+	if e.Offline {
+		return false
 	}
-	if len(c.Host) == 0 {
-		c.Host = APIHost
+	if e.cache.read.IsZero() {
+		return true
 	}
-	e := NewEnvironment(c.Host, c.Token)
-	return &e, nil
+	return e.MetricsInterval > 0 && time.Since(e.cache.read) > e.MetricsInterval
 }
 
-// UpdateMetrics reads metrics and returns them.
-func (e *Environment) UpdateMetrics() error {
+// update reads the metrics.  The cache must be locked.
+func (e *Environment) update() error {
+	if e.Offline {
+		return nil
+	}
 	var resp MetricsResponse
 	n := NetOp{
 		Host:     e.Host,
@@ -71,5 +129,43 @@ func (e *Environment) UpdateMetrics() error {
 		return err
 	}
 	e.Metrics = resp.Payload
+	e.cache.read = time.Now()
 	return nil
 }
+
+// UpdateMetrics reads metrics and returns them.  Does nothing for an
+// offline environment.
+func (e *Environment) UpdateMetrics() error {
+	c := e.lock()
+	defer c.Unlock()
+	return e.update()
+}
+
+// CurrentMetrics returns the cached metrics.  Metrics are read first if
+// they haven't been read or if they are older than MetricsInterval.
+func (e *Environment) CurrentMetrics() (Metrics, error) {
+	c := e.lock()
+	defer c.Unlock()
+	if e.stale() {
+		err := e.update()
+		if err != nil {
+			return e.Metrics, err
+		}
+	}
+	return e.Metrics, nil
+}
+
+// BatchSize returns the maximum number of records that Engage returns for
+// a read.  If the metrics can't be read, then BatchSize logs the error and
+// returns DefaultBatchSize.  The next API call will return the error.
+func (e *Environment) BatchSize() int32 {
+	m, err := e.CurrentMetrics()
+	if err == nil && m.MaxBatchSize < 1 {
+		err = errors.New("metrics do not have a batch size")
+	}
+	if err != nil {
+		log.Printf("BatchSize: using %d, %v\n", DefaultBatchSize, err)
+		return DefaultBatchSize
+	}
+	return m.MaxBatchSize
+}
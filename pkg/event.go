@@ -92,4 +92,32 @@ type TicketedEventResponse struct {
 		Count      int32           `json:"count,omitempty"`
 		Activities []TicketedEvent `json:"activities,omitempty"`
 	} `json:"payload,omitempty"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// PageCount returns the number of activities in the page.
+// Implements ActivityPage.
+func (r *TicketedEventResponse) PageCount() int32 {
+	return r.Payload.Count
+}
+
+// PageErrors returns the errors that Engage returned for the page.
+// Implements ActivityPage.
+func (r *TicketedEventResponse) PageErrors() []Error {
+	return r.Errors
+}
+
+// TicketedEvents returns all of the ticketed event activities that match
+// the provided payload.  Type, Offset and Count in the payload are managed
+// here.
+func TicketedEvents(e *Environment, payload ActivityRequestPayload, logger *UtilLogger) ([]TicketedEvent, error) {
+	var a []TicketedEvent
+	payload.Type = TicketedEventType
+	err := ActivityPages(e, payload, logger,
+		func() ActivityPage { return &TicketedEventResponse{} },
+		func(p ActivityPage) error {
+			a = append(a, p.(*TicketedEventResponse).Payload.Activities...)
+			return nil
+		})
+	return a, err
 }
@@ -52,12 +52,24 @@ type NetOp struct {
 // the end of the maximum number of passes through the loop without
 // relief, then Do return an error containing the HTTP status that
 // caused the condition.
+//
+// Do counts every call in APICalls.  Do returns an error that wraps
+// ErrCallBudget if the call would go over the budget in APICalls.  Do
+// returns ErrStopped once Stopping has started a shutdown.
 func (n *NetOp) Do() (err error) {
 	d, _ := time.ParseDuration(FirstDuration)
 	ok := false
 	s := http.StatusOK
 
 	for i := 1; !ok && i <= MaxWaitIterations; i++ {
+		err := Stopping.Err()
+		if err != nil {
+			return err
+		}
+		err = APICalls.Begin(n.Endpoint, i)
+		if err != nil {
+			return err
+		}
 		resp, err := n.internal()
 		if err != nil {
 			return err
@@ -100,13 +112,16 @@ func BotchedError(n *NetOp, resp *http.Response, s string) bool {
 }
 
 // Delay displays the current HTTP status, takes a nap, and returns
-// the next nap interval.
+// the next nap interval.  The nap ends early if a shutdown starts.
 func Delay(n *NetOp, statusCode int, pass int, duration time.Duration) time.Duration {
 	m := fmt.Sprintf("Delay: HTTP error %v on %v. Sleeping %v seconds, pass %d of %d.",
 		statusCode, n.Endpoint, duration.Seconds(), pass, MaxWaitIterations)
 	log.Println(m)
 	n.Println(m)
-	time.Sleep(duration)
+	select {
+	case <-time.After(duration):
+	case <-Stopping.Done():
+	}
 	duration = duration * Multiplier
 	return duration
 }
@@ -2,6 +2,7 @@ package goengage
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"time"
 )
@@ -109,6 +110,46 @@ type ResultDonation struct {
 	Donation
 }
 
+// DonationResultResponse contains the results of an offline donation
+// upsert.  Each donation carries its own errors and activity ID.
+type DonationResultResponse struct {
+	ID        string     `json:"id,omitempty"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	Header    Header     `json:"header,omitempty"`
+	Payload   struct {
+		Donations []ResultDonation `json:"donations,omitempty"`
+		Count     int32            `json:"count,omitempty"`
+	} `json:"payload,omitempty"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// OfflineDonations sends a batch of offline donations to Engage.  The
+// batch should not be larger than MaxBatchSize.  Results are returned
+// in the same order as the donations.
+func OfflineDonations(e *Environment, a []Donation, logger *UtilLogger) ([]ResultDonation, error) {
+	var rqt DonationUpsertRequest
+	rqt.Payload.Donations = a
+	var resp DonationResultResponse
+	n := NetOp{
+		Host:     e.Host,
+		Method:   OfflineUpsertMethod,
+		Endpoint: OfflineUpsert,
+		Token:    e.Token,
+		Request:  &rqt,
+		Response: &resp,
+		Logger:   logger,
+	}
+	err := n.Do()
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) != 0 {
+		x := resp.Errors[0]
+		return nil, fmt.Errorf("OfflineDonations: %v %v %v", x.Code, x.Message, x.Details)
+	}
+	return resp.Payload.Donations, nil
+}
+
 // ToString converts Donation record to a JSON string.
 func (r Donation) ToString() string {
 	b, err := json.MarshalIndent(r, "", "  ")
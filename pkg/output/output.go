@@ -0,0 +1,582 @@
+package goengage
+
+//The output package writes report rows in a choice of formats.  Reports
+//provide a list of headers and a list of strings for each row, just like
+//they do for encoding/csv.  The format decides how the rows are written.
+//
+//	csv       comma-separated values with a header row
+//	tsv       tab-separated values with a header row.  Tabs, newlines and
+//	          backslashes in values are escaped as \t, \n, \r and \\.
+//	jsonl     JSON Lines, one object per row.  Keys are the headers.
+//	json      a pretty-printed JSON array of objects.
+//	markdown  a Markdown table.
+//	fixed     fixed-width text columns.  Rows are spooled to a temporary
+//	          file until Close so that the columns can be sized.
+//	xlsx      an Excel workbook.  See xlsx.go.
+//
+//Output other than xlsx can be compressed with gzip.  json, fixed and xlsx
+//output can't be read until it's closed, so an early exit closes it.
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// Output formats.
+const (
+	//CSV is comma-separated values.
+	CSV = "csv"
+	//TSV is tab-separated values.
+	TSV = "tsv"
+	//JSONLines is one JSON object per line.
+	JSONLines = "jsonl"
+	//JSON is a pretty-printed JSON array.
+	JSON = "json"
+	//Markdown is a Markdown table.
+	Markdown = "markdown"
+	//Fixed is fixed-width text.
+	Fixed = "fixed"
+
+	//GzipExtension is added to the names of compressed files.
+	GzipExtension = ".gz"
+
+	//FlushRows is how often long reports flush their writers.  Flushing
+	//gzipped output after every row undoes most of the compression.
+	FlushRows = 1000
+)
+
+// Formats lists the output formats.  Useful for kingpin's Enum.
+var Formats = []string{CSV, TSV, JSONLines, JSON, Markdown, Fixed, XLSX}
+
+// Extensions are the filename extensions for the formats.
+var Extensions = map[string]string{
+	CSV:       ".csv",
+	TSV:       ".tsv",
+	JSONLines: ".jsonl",
+	JSON:      ".json",
+	Markdown:  ".md",
+	Fixed:     ".txt",
+	XLSX:      ".xlsx",
+}
+
+// Writer writes report rows.  The headers are written when the writer is
+// created.
+type Writer interface {
+	//Write writes a row.
+	Write(row []string) error
+
+	//Flush writes buffered rows.
+	Flush() error
+
+	//Close flushes the rows and finishes the output.  Writers that
+	//write files close them.
+	Close() error
+}
+
+// Sheets is a Writer that can add sheets, like an XLSX workbook.  Rows
+// written to the Writer go to the first sheet.
+type Sheets interface {
+	Writer
+
+	//AddSheet adds a sheet and writes its headers.
+	AddSheet(name string, headers []string) (Writer, error)
+}
+
+// Options select the output format and compression.  The zero value
+// writes uncompressed CSV.  Keys and Window choose how a resumed file
+// skips rows that are written again.  See goengage.OpenAppendCSV.
+type Options struct {
+	Format string
+	Gzip   bool
+	Keys   []string
+	Window int
+}
+
+// Validate returns an error if the format isn't one of Formats, or if
+// an XLSX workbook is gzipped.  Workbooks are already compressed.
+func (o Options) Validate() error {
+	if len(o.Format) == 0 {
+		return nil
+	}
+	if _, ok := Extensions[o.Format]; !ok {
+		return fmt.Errorf("format %s is not one of %s", o.Format, strings.Join(Formats, ", "))
+	}
+	if o.Format == XLSX && o.Gzip {
+		return fmt.Errorf("format %s is already compressed, gzip is not needed", XLSX)
+	}
+	return nil
+}
+
+// format returns the format with the default applied.
+func (o Options) format() string {
+	if len(o.Format) == 0 {
+		return CSV
+	}
+	return o.Format
+}
+
+// closes returns true if output in the format can't be read until the
+// writer is closed.
+func (o Options) closes() bool {
+	switch o.format() {
+	case JSON, Fixed, XLSX:
+		return true
+	}
+	return false
+}
+
+// Filename changes a filename's extension to match the format, and adds
+// GzipExtension for compressed output.  "supporters.csv" becomes
+// "supporters.jsonl.gz" for gzipped JSON Lines.
+func (o Options) Filename(fn string) string {
+	ext := filepath.Ext(fn)
+	for _, x := range Extensions {
+		if ext == x {
+			fn = strings.TrimSuffix(fn, ext)
+			break
+		}
+	}
+	fn = fn + Extensions[o.format()]
+	if o.Gzip {
+		fn = fn + GzipExtension
+	}
+	return fn
+}
+
+// Open creates a file and writes the headers.  If resume is true, then an
+// existing file is appended to and rows that match the last Window rows in
+// the file are skipped.  Only uncompressed CSV can be resumed.
+func (o Options) Open(fn string, headers []string, resume bool) (Writer, error) {
+	if !resume {
+		return o.Create(fn, headers)
+	}
+	if o.format() != CSV || o.Gzip {
+		return nil, fmt.Errorf("%s: only uncompressed %s output can be resumed", fn, CSV)
+	}
+	w, err := goengage.OpenAppendCSV(fn, true, o.Keys, o.Window)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Write(headers)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Create creates a file and writes the headers.  The file is flushed by
+// goengage.Stopping if the process has to exit early.  Formats that can't
+// be read until they're finished are closed instead.
+func (o Options) Create(fn string, headers []string) (Writer, error) {
+	err := o.Validate()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(fn)
+	if err != nil {
+		return nil, err
+	}
+	x := fileWriter{file: f}
+	if o.format() == XLSX {
+		book, err := newXLSXWriter(f, sheetFor(fn), headers)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		x.rows = book
+		x.flusher = goengage.Stopping.Register(x.Close)
+		return &workbookFile{&x, book}, nil
+	}
+	var w io.Writer = f
+	if o.Gzip {
+		x.gzip = gzip.NewWriter(f)
+		w = x.gzip
+	}
+	x.rows, err = NewWriter(w, o.format(), headers)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if o.closes() {
+		x.flusher = goengage.Stopping.Register(x.Close)
+	} else {
+		x.flusher = goengage.Stopping.Register(x.Flush)
+	}
+	return &x, nil
+}
+
+// NewWriter returns a writer for a format and writes the headers.  Closing
+// the writer doesn't close w.
+func NewWriter(w io.Writer, format string, headers []string) (Writer, error) {
+	var x Writer
+	switch format {
+	case "", CSV:
+		x = &csvWriter{csv.NewWriter(w)}
+	case TSV:
+		x = &tsvWriter{w: w}
+	case JSONLines:
+		x = &jsonWriter{w: w, headers: headers}
+	case JSON:
+		x = &jsonWriter{w: w, headers: headers, pretty: true}
+	case Markdown:
+		x = &markdownWriter{w: w, headers: headers}
+	case Fixed:
+		f, err := os.CreateTemp("", "goengage-fixed-*.txt")
+		if err != nil {
+			return nil, err
+		}
+		x = &fixedWriter{w: w, spool: f, buf: bufio.NewWriter(f)}
+	case XLSX:
+		return newXLSXWriter(w, DefaultSheet, headers)
+	default:
+		return nil, Options{Format: format}.Validate()
+	}
+	if h, ok := x.(interface{ header([]string) error }); ok {
+		return x, h.header(headers)
+	}
+	return x, x.Write(headers)
+}
+
+// fileWriter is a Writer for a file, optionally compressed.  It locks the
+// rows so that they can be flushed by another goroutine.
+type fileWriter struct {
+	sync.Mutex
+	file    *os.File
+	gzip    *gzip.Writer
+	rows    Writer
+	flusher int
+	closed  bool
+}
+
+// Write writes a row.
+func (x *fileWriter) Write(row []string) error {
+	x.Lock()
+	defer x.Unlock()
+	return x.rows.Write(row)
+}
+
+// Flush writes buffered rows to the file.
+func (x *fileWriter) Flush() error {
+	x.Lock()
+	defer x.Unlock()
+	err := x.rows.Flush()
+	if err == nil && x.gzip != nil {
+		err = x.gzip.Flush()
+	}
+	return err
+}
+
+// Close finishes the rows and closes the file.  Closing twice does
+// nothing.
+func (x *fileWriter) Close() error {
+	goengage.Stopping.Unregister(x.flusher)
+	x.Lock()
+	defer x.Unlock()
+	if x.closed {
+		return nil
+	}
+	x.closed = true
+	err := x.rows.Close()
+	if x.gzip != nil {
+		if gerr := x.gzip.Close(); err == nil {
+			err = gerr
+		}
+	}
+	if ferr := x.file.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// workbookFile is a Writer for an XLSX file.  Implements Sheets.
+type workbookFile struct {
+	*fileWriter
+	book *xlsxWriter
+}
+
+// AddSheet adds a sheet to the workbook.
+func (x *workbookFile) AddSheet(name string, headers []string) (Writer, error) {
+	return x.book.AddSheet(name, headers)
+}
+
+// csvWriter writes comma-separated values.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// Write writes a row.
+func (x *csvWriter) Write(row []string) error {
+	return x.w.Write(row)
+}
+
+// Flush writes buffered rows.
+func (x *csvWriter) Flush() error {
+	x.w.Flush()
+	return x.w.Error()
+}
+
+// Close flushes the rows.
+func (x *csvWriter) Close() error {
+	return x.Flush()
+}
+
+// tsvEscaper escapes the characters that can't appear in a TSV value.
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+// tsvWriter writes tab-separated values.
+type tsvWriter struct {
+	w io.Writer
+}
+
+// Write writes a row.
+func (x *tsvWriter) Write(row []string) error {
+	a := make([]string, len(row))
+	for i, s := range row {
+		a[i] = tsvEscaper.Replace(s)
+	}
+	_, err := io.WriteString(x.w, strings.Join(a, "\t")+"\n")
+	return err
+}
+
+// Flush does nothing.  Rows are written as they arrive.
+func (x *tsvWriter) Flush() error {
+	return nil
+}
+
+// Close does nothing.
+func (x *tsvWriter) Close() error {
+	return nil
+}
+
+// jsonWriter writes JSON objects.  Keys are the headers, in order.  Rows
+// with more values than headers use "ColumnN" for the extra keys.  Pretty
+// output is an indented array.  Otherwise, each object is on its own line.
+type jsonWriter struct {
+	w       io.Writer
+	headers []string
+	pretty  bool
+	rows    int
+}
+
+// header starts the array for pretty output.
+func (x *jsonWriter) header(headers []string) error {
+	if !x.pretty {
+		return nil
+	}
+	_, err := io.WriteString(x.w, "[")
+	return err
+}
+
+// object returns a row as a JSON object.
+func (x *jsonWriter) object(row []string, indent string) (string, error) {
+	var a []string
+	colon := ":"
+	if len(indent) != 0 {
+		colon = ": "
+	}
+	for i, s := range row {
+		k := fmt.Sprintf("Column%d", i+1)
+		if i < len(x.headers) {
+			k = x.headers[i]
+		}
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return "", err
+		}
+		vb, err := json.Marshal(s)
+		if err != nil {
+			return "", err
+		}
+		a = append(a, string(kb)+colon+string(vb))
+	}
+	if len(indent) == 0 {
+		return "{" + strings.Join(a, ",") + "}", nil
+	}
+	inner := indent + "  "
+	if len(a) == 0 {
+		return "{}", nil
+	}
+	return "{\n" + inner + strings.Join(a, ",\n"+inner) + "\n" + indent + "}", nil
+}
+
+// Write writes a row as an object.
+func (x *jsonWriter) Write(row []string) error {
+	if !x.pretty {
+		s, err := x.object(row, "")
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(x.w, s+"\n")
+		return err
+	}
+	s, err := x.object(row, "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if x.rows == 0 {
+		sep = "\n  "
+	}
+	x.rows++
+	_, err = io.WriteString(x.w, sep+s)
+	return err
+}
+
+// Flush does nothing.  Rows are written as they arrive.
+func (x *jsonWriter) Flush() error {
+	return nil
+}
+
+// Close ends the array for pretty output.
+func (x *jsonWriter) Close() error {
+	if !x.pretty {
+		return nil
+	}
+	s := "\n]\n"
+	if x.rows == 0 {
+		s = "]\n"
+	}
+	_, err := io.WriteString(x.w, s)
+	return err
+}
+
+// markdownEscaper escapes the characters that break a Markdown table.
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+// markdownWriter writes a Markdown table.
+type markdownWriter struct {
+	w       io.Writer
+	headers []string
+}
+
+// header writes the header row and the separator row.
+func (x *markdownWriter) header(headers []string) error {
+	err := x.Write(headers)
+	if err != nil {
+		return err
+	}
+	a := make([]string, len(headers))
+	for i := range a {
+		a[i] = "---"
+	}
+	_, err = io.WriteString(x.w, "| "+strings.Join(a, " | ")+" |\n")
+	return err
+}
+
+// Write writes a row.
+func (x *markdownWriter) Write(row []string) error {
+	a := make([]string, len(row))
+	for i, s := range row {
+		a[i] = markdownEscaper.Replace(s)
+	}
+	_, err := io.WriteString(x.w, "| "+strings.Join(a, " | ")+" |\n")
+	return err
+}
+
+// Flush does nothing.  Rows are written as they arrive.
+func (x *markdownWriter) Flush() error {
+	return nil
+}
+
+// Close does nothing.
+func (x *markdownWriter) Close() error {
+	return nil
+}
+
+// fixedWriter writes fixed-width columns.  Each column is as wide as its
+// widest value.  Rows are spooled to a temporary file until Close, so
+// large reports don't have to fit in memory.
+type fixedWriter struct {
+	w      io.Writer
+	spool  *os.File
+	buf    *bufio.Writer
+	widths []int
+}
+
+// Write spools a row.  Newlines and tabs become spaces, so the spooled
+// cells are separated by tabs.
+func (x *fixedWriter) Write(row []string) error {
+	a := make([]string, len(row))
+	for i, s := range row {
+		a[i] = strings.Join(strings.Fields(s), " ")
+		n := len([]rune(a[i]))
+		if i == len(x.widths) {
+			x.widths = append(x.widths, 0)
+		}
+		if n > x.widths[i] {
+			x.widths[i] = n
+		}
+	}
+	_, err := x.buf.WriteString(strings.Join(a, "\t") + "\n")
+	return err
+}
+
+// Flush writes spooled rows to the temporary file.  Rows are written to
+// the output by Close.
+func (x *fixedWriter) Flush() error {
+	return x.buf.Flush()
+}
+
+// Close writes the rows and removes the temporary file.  The header row
+// is underlined with dashes.
+func (x *fixedWriter) Close() error {
+	defer os.Remove(x.spool.Name())
+	defer x.spool.Close()
+	err := x.buf.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = x.spool.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	line := func(row []string) string {
+		a := make([]string, len(row))
+		for i, s := range row {
+			a[i] = s
+			if i < len(x.widths) {
+				a[i] += strings.Repeat(" ", x.widths[i]-len([]rune(s)))
+			}
+		}
+		return strings.TrimRight(strings.Join(a, "  "), " ") + "\n"
+	}
+	r := bufio.NewReader(x.spool)
+	for i := 0; ; i++ {
+		s, err := r.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		row := strings.Split(strings.TrimSuffix(s, "\n"), "\t")
+		_, err = io.WriteString(x.w, line(row))
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			a := make([]string, len(row))
+			for j := range row {
+				if j < len(x.widths) {
+					a[j] = strings.Repeat("-", x.widths[j])
+				}
+			}
+			_, err = io.WriteString(x.w, line(a))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
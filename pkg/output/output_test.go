@@ -0,0 +1,215 @@
+package goengage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewWriter(t *testing.T) {
+	headers := []string{"ID", "Note"}
+	rows := [][]string{
+		{"1", "tab\there"},
+		{"22", "line\nbreak | pipe \"quoted\" back\\slash"},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{CSV, "ID,Note\n1,tab\there\n22,\"line\nbreak | pipe \"\"quoted\"\" back\\slash\"\n"},
+		{TSV, "ID\tNote\n1\ttab\\there\n22\tline\\nbreak | pipe \"quoted\" back\\\\slash\n"},
+		{JSONLines, `{"ID":"1","Note":"tab\there"}` + "\n" +
+			`{"ID":"22","Note":"line\nbreak | pipe \"quoted\" back\\slash"}` + "\n"},
+		{JSON, "[\n  {\n    \"ID\": \"1\",\n    \"Note\": \"tab\\there\"\n  },\n" +
+			"  {\n    \"ID\": \"22\",\n    \"Note\": \"line\\nbreak | pipe \\\"quoted\\\" back\\\\slash\"\n  }\n]\n"},
+		{Markdown, "| ID | Note |\n| --- | --- |\n| 1 | tab\there |\n" +
+			"| 22 | line<br>break \\| pipe \"quoted\" back\\slash |\n"},
+		{Fixed, "ID  Note\n--  -------------------------------------\n" +
+			"1   tab here\n22  line break | pipe \"quoted\" back\\slash\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b bytes.Buffer
+			w, err := NewWriter(&b, tt.format, headers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range rows {
+				err = w.Write(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = w.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWriterEmpty(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{JSON, "[]\n"},
+		{JSONLines, ""},
+		{Fixed, "A\n-\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b bytes.Buffer
+			w, err := NewWriter(&b, tt.format, []string{"A"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = w.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONExtraColumns(t *testing.T) {
+	var b bytes.Buffer
+	w, err := NewWriter(&b, JSONLines, []string{"A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Write([]string{"1", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"A":"1","Column2":"2"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFilename(t *testing.T) {
+	tests := []struct {
+		opt  Options
+		fn   string
+		want string
+	}{
+		{Options{}, "supporters.csv", "supporters.csv"},
+		{Options{Format: JSONLines, Gzip: true}, "supporters.csv", "supporters.jsonl.gz"},
+		{Options{Format: XLSX}, "out/supporters.txt", "out/supporters.xlsx"},
+		{Options{Format: Markdown}, "supporters", "supporters.md"},
+		{Options{Format: TSV}, "supporters.v2.csv", "supporters.v2.tsv"},
+		{Options{Format: Fixed}, "supporters.dat", "supporters.dat.txt"},
+	}
+	for _, tt := range tests {
+		if got := tt.opt.Filename(tt.fn); got != tt.want {
+			t.Errorf("%+v.Filename(%s) = %s, want %s", tt.opt, tt.fn, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		opt Options
+		ok  bool
+	}{
+		{Options{}, true},
+		{Options{Format: CSV, Gzip: true}, true},
+		{Options{Format: XLSX}, true},
+		{Options{Format: XLSX, Gzip: true}, false},
+		{Options{Format: "yaml"}, false},
+	}
+	for _, tt := range tests {
+		err := tt.opt.Validate()
+		if (err == nil) != tt.ok {
+			t.Errorf("%+v.Validate() = %v, want ok %v", tt.opt, err, tt.ok)
+		}
+	}
+}
+
+func TestCreateGzip(t *testing.T) {
+	opt := Options{Format: CSV, Gzip: true}
+	fn := opt.Filename(filepath.Join(t.TempDir(), "x.csv"))
+	w, err := opt.Create(fn, []string{"A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Write([]string{"1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != "A\n1\n" {
+		t.Errorf("got %q, want %q", got, "A\n1\n")
+	}
+}
+
+func TestOpenResume(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Options
+		ok   bool
+	}{
+		{"csv", Options{}, true},
+		{"gzip", Options{Gzip: true}, false},
+		{"json", Options{Format: JSON}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := filepath.Join(t.TempDir(), "x.csv")
+			err := os.WriteFile(fn, []byte("A\n1\n"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := tt.opt.Open(fn, []string{"A"}, true)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Open = %v, want ok %v", err, tt.ok)
+			}
+			if err != nil {
+				return
+			}
+			for _, r := range []string{"1", "2"} {
+				err = w.Write([]string{r})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = w.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); got != "A\n1\n2\n" {
+				t.Errorf("got %q, want %q", got, "A\n1\n2\n")
+			}
+		})
+	}
+}
@@ -0,0 +1,593 @@
+package goengage
+
+//XLSX workbooks are zip files of Office Open XML parts.  Each sheet's rows
+//are spooled to a temporary file while the report runs.  Close writes the
+//workbook, the styles and the sheets to the zip file.
+//
+//Cells are typed using the column's header.  See ColumnType.  Text columns
+//keep leading zeros in ZIP codes and IDs.  Dates and currency get number
+//formats so that Excel can sort and sum them.  The header row is bold and
+//frozen.
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	//XLSX is an Excel workbook.
+	XLSX = "xlsx"
+
+	//DefaultSheet is the name of the first sheet when a workbook is
+	//written to a stream.
+	DefaultSheet = "Sheet1"
+
+	//MaxRows is the most rows that a sheet can have.
+	MaxRows = 1048576
+
+	//MaxCellText is the longest text that a cell can hold.
+	MaxCellText = 32767
+
+	//MaxSheetName is the longest name that a sheet can have.
+	MaxSheetName = 31
+
+	//MinColumnWidth and MaxColumnWidth limit column widths, in characters.
+	MinColumnWidth = 8
+	MaxColumnWidth = 60
+)
+
+// CellType decides how the values in a column are written.
+type CellType int
+
+// Cell types.
+const (
+	//General values are numbers if they look like numbers, else text.
+	General CellType = iota
+	//Text values are always text.
+	Text
+	//Date values are dates, or dates and times.  Values that aren't
+	//dates are text.
+	Date
+	//Currency values are amounts with two decimal places.  Values that
+	//aren't amounts are text.
+	Currency
+)
+
+// Styles in styles.xml.  The order matches cellXfs.
+const (
+	styleGeneral = iota
+	styleHeader
+	styleText
+	styleDate
+	styleDateTime
+	styleCurrency
+)
+
+// Namespaces and content types for the workbook's parts.
+const (
+	xlsxMain          = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	xlsxRelationships = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	xlsxPackageRels   = "http://schemas.openxmlformats.org/package/2006/relationships"
+	xlsxContentTypes  = "http://schemas.openxmlformats.org/package/2006/content-types"
+	xlsxDocument      = xlsxRelationships + "/officeDocument"
+	xlsxWorksheet     = xlsxRelationships + "/worksheet"
+	xlsxStyles        = xlsxRelationships + "/styles"
+	xlsxTypePrefix    = "application/vnd.openxmlformats-officedocument.spreadsheetml."
+)
+
+// xlsxStylesXML is the styles part.  Custom number formats start at 164.
+const xlsxStylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="` + xlsxMain + `">
+<numFmts count="3"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd hh:mm:ss"/><numFmt numFmtId="166" formatCode="$#,##0.00"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="6"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="49" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1" applyNumberFormat="1"/><xf numFmtId="49" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="166" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>`
+
+// ErrClosed is returned when a closed workbook is written to.
+var ErrClosed = errors.New("workbook is closed")
+
+// numberPattern matches the numbers that Excel can hold without losing
+// digits.  Leading zeros mean text, like a ZIP code.
+var numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]{0,14})(\.[0-9]{1,15})?$`)
+
+// sheetNameCleaner removes the characters that can't be in a sheet name.
+var sheetNameCleaner = strings.NewReplacer("[", "", "]", "", ":", "", "*", "", "?", "", "/", "", "\\", "")
+
+// excelEpoch is day zero for Excel dates.
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// dateLayouts are the layouts that Date columns accept.  Layouts without
+// a time are written as dates.
+var dateLayouts = []struct {
+	Layout string
+	Time   bool
+}{
+	{"2006-01-02", false},
+	{time.RFC3339Nano, true},
+	{"2006-01-02 15:04:05", true},
+	{"2006-01-02T15:04:05", true},
+	{"01/02/2006", false},
+}
+
+// ColumnType returns the cell type for a header.  IDs, ZIP and postal
+// codes, phone numbers and other codes are text.  Headers with "Date" are
+// dates.  Headers with "Amount", "Revenue", "Price" or "Fee" are currency.
+// Everything else is General.
+func ColumnType(header string) CellType {
+	h := strings.ToLower(header)
+	has := func(a ...string) bool {
+		for _, s := range a {
+			if strings.Contains(h, s) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case strings.HasSuffix(header, "ID") || strings.HasSuffix(header, "Id") || h == "id":
+		return Text
+	case has("zip", "postal", "phone", "code"):
+		return Text
+	case has("date"):
+		return Date
+	case has("amount", "revenue", "price", "fee"):
+		return Currency
+	}
+	return General
+}
+
+// Workbook writes an XLSX workbook with one or more sheets.  It's safe to
+// use from more than one goroutine.
+type Workbook struct {
+	sync.Mutex
+	//Types overrides ColumnType for headers in the map.  Set it before
+	//adding sheets.
+	Types  map[string]CellType
+	w      io.Writer
+	sheets []*Sheet
+	closed bool
+}
+
+// Sheet is a sheet in a workbook.  Implements Writer.  Rows go to a
+// temporary file until the workbook is closed.
+type Sheet struct {
+	Name   string
+	book   *Workbook
+	types  []CellType
+	widths []int
+	file   *os.File
+	buf    *bufio.Writer
+	rows   int
+}
+
+// NewWorkbook returns an empty workbook that is written to w when it's
+// closed.  Closing the workbook doesn't close w.
+func NewWorkbook(w io.Writer) *Workbook {
+	return &Workbook{w: w}
+}
+
+// SheetName returns a name that Excel accepts.  Characters that aren't
+// allowed are removed and the name is shortened to MaxSheetName.
+func SheetName(name string) string {
+	name = strings.TrimSpace(sheetNameCleaner.Replace(name))
+	name = strings.Trim(name, "'")
+	if utf8.RuneCountInString(name) > MaxSheetName {
+		name = string([]rune(name)[:MaxSheetName])
+	}
+	if len(name) == 0 {
+		name = DefaultSheet
+	}
+	return name
+}
+
+// AddSheet adds a sheet and writes its header row.  The name is cleaned
+// with SheetName and must be unique in the workbook.
+func (b *Workbook) AddSheet(name string, headers []string) (*Sheet, error) {
+	b.Lock()
+	defer b.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	name = SheetName(name)
+	for _, s := range b.sheets {
+		if strings.EqualFold(s.Name, name) {
+			return nil, fmt.Errorf("workbook already has a sheet named %s", name)
+		}
+	}
+	f, err := os.CreateTemp("", "goengage-sheet-*.xml")
+	if err != nil {
+		return nil, err
+	}
+	s := &Sheet{
+		Name: name,
+		book: b,
+		file: f,
+		buf:  bufio.NewWriter(f),
+	}
+	for _, h := range headers {
+		t, ok := b.Types[h]
+		if !ok {
+			t = ColumnType(h)
+		}
+		s.types = append(s.types, t)
+	}
+	b.sheets = append(b.sheets, s)
+	err = s.row(headers, true)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write writes a row.
+func (s *Sheet) Write(row []string) error {
+	s.book.Lock()
+	defer s.book.Unlock()
+	if s.book.closed {
+		return ErrClosed
+	}
+	if s.rows >= MaxRows {
+		return fmt.Errorf("sheet %s has more than %d rows", s.Name, MaxRows)
+	}
+	return s.row(row, false)
+}
+
+// Flush writes buffered rows to the temporary file.  The sheet isn't in
+// the workbook until the workbook is closed.
+func (s *Sheet) Flush() error {
+	s.book.Lock()
+	defer s.book.Unlock()
+	if s.book.closed {
+		return nil
+	}
+	return s.buf.Flush()
+}
+
+// Close does nothing.  Sheets are written when the workbook is closed.
+func (s *Sheet) Close() error {
+	return nil
+}
+
+// row writes a row of cells.  The workbook must be locked.
+func (s *Sheet) row(row []string, header bool) error {
+	s.rows++
+	fmt.Fprintf(s.buf, `<row r="%d">`, s.rows)
+	for i, v := range row {
+		if utf8.RuneCountInString(v) > MaxCellText {
+			v = string([]rune(v)[:MaxCellText])
+		}
+		t := General
+		if i < len(s.types) {
+			t = s.types[i]
+		}
+		ref := CellName(i, s.rows)
+		style, number := styleHeader, ""
+		if !header {
+			style, number = cell(t, v)
+		}
+		if len(number) != 0 {
+			fmt.Fprintf(s.buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, number)
+		} else if len(v) != 0 {
+			fmt.Fprintf(s.buf, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+			xml.EscapeText(s.buf, []byte(v))
+			s.buf.WriteString(`</t></is></c>`)
+		}
+		s.width(i, v)
+	}
+	_, err := s.buf.WriteString("</row>\n")
+	return err
+}
+
+// width widens a column to fit a value.
+func (s *Sheet) width(i int, v string) {
+	for len(s.widths) <= i {
+		s.widths = append(s.widths, MinColumnWidth)
+	}
+	n := utf8.RuneCountInString(v) + 2
+	if n > MaxColumnWidth {
+		n = MaxColumnWidth
+	}
+	if n > s.widths[i] {
+		s.widths[i] = n
+	}
+}
+
+// cell returns the style for a value and, for numbers and dates, the
+// value to store.  An empty number means that the value is text.
+func cell(t CellType, v string) (int, string) {
+	switch t {
+	case Text:
+		return styleText, ""
+	case Date:
+		for _, d := range dateLayouts {
+			x, err := time.Parse(d.Layout, v)
+			if err != nil {
+				continue
+			}
+			serial, ok := Serial(x)
+			if !ok {
+				break
+			}
+			if d.Time {
+				return styleDateTime, serial
+			}
+			return styleDate, serial
+		}
+	case Currency:
+		a := strings.NewReplacer("$", "", ",", "").Replace(v)
+		if numberPattern.MatchString(a) {
+			return styleCurrency, a
+		}
+	default:
+		if numberPattern.MatchString(v) {
+			return styleGeneral, v
+		}
+	}
+	return styleGeneral, ""
+}
+
+// Serial returns the Excel date for a time, using the time's own clock.
+// Returns false for times that Excel can't show.
+func Serial(t time.Time) (string, bool) {
+	if t.Year() < 1900 || t.Year() > 9999 {
+		return "", false
+	}
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	seconds := float64(wall.Unix()-excelEpoch.Unix()) + float64(wall.Nanosecond())/1e9
+	return strconv.FormatFloat(seconds/86400, 'f', -1, 64), true
+}
+
+// CellName returns a cell's name, like "A1".  Columns start at 0 and rows
+// start at 1.
+func CellName(col, row int) string {
+	s := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		s = string(rune('A'+(col-1)%26)) + s
+	}
+	return fmt.Sprintf("%s%d", s, row)
+}
+
+// Close writes the workbook.  Temporary files are removed.  Closing twice
+// does nothing.
+func (b *Workbook) Close() error {
+	b.Lock()
+	defer b.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	defer (func() {
+		for _, s := range b.sheets {
+			s.file.Close()
+			os.Remove(s.file.Name())
+		}
+	})()
+	z := zip.NewWriter(b.w)
+	err := b.write(z)
+	if cerr := z.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// xlsxRelationship is a relationship in a rels part.
+type xlsxRelationship struct {
+	ID     string `xml:"Id,attr"`
+	Type   string `xml:"Type,attr"`
+	Target string `xml:"Target,attr"`
+}
+
+// xlsxRels is a rels part.
+type xlsxRels struct {
+	XMLName       xml.Name           `xml:"Relationships"`
+	Xmlns         string             `xml:"xmlns,attr"`
+	Relationships []xlsxRelationship `xml:"Relationship"`
+}
+
+// xlsxOverride is a content type for a part.
+type xlsxOverride struct {
+	PartName    string `xml:"PartName,attr"`
+	ContentType string `xml:"ContentType,attr"`
+}
+
+// xlsxDefault is a content type for an extension.
+type xlsxDefault struct {
+	Extension   string `xml:"Extension,attr"`
+	ContentType string `xml:"ContentType,attr"`
+}
+
+// xlsxTypes is the [Content_Types].xml part.
+type xlsxTypes struct {
+	XMLName   xml.Name       `xml:"Types"`
+	Xmlns     string         `xml:"xmlns,attr"`
+	Defaults  []xlsxDefault  `xml:"Default"`
+	Overrides []xlsxOverride `xml:"Override"`
+}
+
+// xlsxSheetRef is a sheet in the workbook part.
+type xlsxSheetRef struct {
+	Name    string `xml:"name,attr"`
+	SheetID int    `xml:"sheetId,attr"`
+	RID     string `xml:"r:id,attr"`
+}
+
+// xlsxWorkbook is the workbook part.
+type xlsxWorkbook struct {
+	XMLName xml.Name       `xml:"workbook"`
+	Xmlns   string         `xml:"xmlns,attr"`
+	XmlnsR  string         `xml:"xmlns:r,attr"`
+	Views   []struct{}     `xml:"bookViews>workbookView"`
+	Sheets  []xlsxSheetRef `xml:"sheets>sheet"`
+}
+
+// write writes the parts to the zip file.
+func (b *Workbook) write(z *zip.Writer) error {
+	types := xlsxTypes{
+		Xmlns: xlsxContentTypes,
+		Defaults: []xlsxDefault{
+			{"rels", "application/vnd.openxmlformats-package.relationships+xml"},
+			{"xml", "application/xml"},
+		},
+		Overrides: []xlsxOverride{
+			{"/xl/workbook.xml", xlsxTypePrefix + "sheet.main+xml"},
+			{"/xl/styles.xml", xlsxTypePrefix + "styles+xml"},
+		},
+	}
+	rels := xlsxRels{
+		Xmlns:         xlsxPackageRels,
+		Relationships: []xlsxRelationship{{"rId1", xlsxDocument, "xl/workbook.xml"}},
+	}
+	book := xlsxWorkbook{
+		Xmlns:  xlsxMain,
+		XmlnsR: xlsxRelationships,
+		Views:  []struct{}{{}},
+	}
+	bookRels := xlsxRels{Xmlns: xlsxPackageRels}
+	for i, s := range b.sheets {
+		id := fmt.Sprintf("rId%d", i+1)
+		target := fmt.Sprintf("worksheets/sheet%d.xml", i+1)
+		types.Overrides = append(types.Overrides, xlsxOverride{"/xl/" + target, xlsxTypePrefix + "worksheet+xml"})
+		book.Sheets = append(book.Sheets, xlsxSheetRef{s.Name, i + 1, id})
+		bookRels.Relationships = append(bookRels.Relationships, xlsxRelationship{id, xlsxWorksheet, target})
+	}
+	id := fmt.Sprintf("rId%d", len(b.sheets)+1)
+	bookRels.Relationships = append(bookRels.Relationships, xlsxRelationship{id, xlsxStyles, "styles.xml"})
+
+	parts := []struct {
+		Name string
+		V    interface{}
+	}{
+		{"[Content_Types].xml", types},
+		{"_rels/.rels", rels},
+		{"xl/workbook.xml", book},
+		{"xl/_rels/workbook.xml.rels", bookRels},
+	}
+	for _, p := range parts {
+		err := xmlPart(z, p.Name, p.V)
+		if err != nil {
+			return err
+		}
+	}
+	w, err := z.Create("xl/styles.xml")
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, xlsxStylesXML)
+	if err != nil {
+		return err
+	}
+	for i, s := range b.sheets {
+		err = s.write(z, fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), i == 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xmlPart writes a part using encoding/xml.
+func xmlPart(z *zip.Writer, name string, v interface{}) error {
+	w, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// write writes the sheet to the zip file.  The header row is frozen and
+// the columns are sized to fit their values.
+func (s *Sheet) write(z *zip.Writer, name string, selected bool) error {
+	err := s.buf.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	w, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	tab := ""
+	if selected {
+		tab = ` tabSelected="1"`
+	}
+	fmt.Fprintf(w, "%s<worksheet xmlns=\"%s\" xmlns:r=\"%s\">\n", xml.Header, xlsxMain, xlsxRelationships)
+	fmt.Fprintf(w, `<sheetViews><sheetView workbookViewId="0"%s>`, tab)
+	fmt.Fprint(w, `<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`)
+	fmt.Fprint(w, `<selection pane="bottomLeft" activeCell="A2" sqref="A2"/></sheetView></sheetViews>`+"\n")
+	if len(s.widths) != 0 {
+		fmt.Fprint(w, "<cols>")
+		for i, n := range s.widths {
+			fmt.Fprintf(w, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, n)
+		}
+		fmt.Fprint(w, "</cols>\n")
+	}
+	fmt.Fprint(w, "<sheetData>\n")
+	_, err = io.Copy(w, s.file)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "</sheetData>\n</worksheet>\n")
+	return err
+}
+
+// xlsxWriter is a Writer for a workbook.  Rows go to the first sheet.
+// Implements Sheets.
+type xlsxWriter struct {
+	*Sheet
+	book *Workbook
+}
+
+// AddSheet adds a sheet to the workbook.
+func (x *xlsxWriter) AddSheet(name string, headers []string) (Writer, error) {
+	return x.book.AddSheet(name, headers)
+}
+
+// Close writes the workbook.
+func (x *xlsxWriter) Close() error {
+	return x.book.Close()
+}
+
+// newXLSXWriter returns a Writer for a new workbook.  The first sheet
+// has the headers.
+func newXLSXWriter(w io.Writer, name string, headers []string) (*xlsxWriter, error) {
+	b := NewWorkbook(w)
+	s, err := b.AddSheet(name, headers)
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	return &xlsxWriter{s, b}, nil
+}
+
+// sheetFor returns a sheet name for a filename, without the directory or
+// extensions.
+func sheetFor(fn string) string {
+	fn = filepath.Base(fn)
+	for len(filepath.Ext(fn)) != 0 {
+		fn = strings.TrimSuffix(fn, filepath.Ext(fn))
+	}
+	return SheetName(fn)
+}
@@ -0,0 +1,192 @@
+package goengage
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCellName(t *testing.T) {
+	tests := []struct {
+		col  int
+		row  int
+		want string
+	}{
+		{0, 1, "A1"},
+		{25, 2, "Z2"},
+		{26, 3, "AA3"},
+		{51, 4, "AZ4"},
+		{52, 5, "BA5"},
+		{701, 6, "ZZ6"},
+		{702, 7, "AAA7"},
+		{16383, 1048576, "XFD1048576"},
+	}
+	for _, tt := range tests {
+		if got := CellName(tt.col, tt.row); got != tt.want {
+			t.Errorf("CellName(%d, %d) = %s, want %s", tt.col, tt.row, got, tt.want)
+		}
+	}
+}
+
+func TestColumnType(t *testing.T) {
+	tests := []struct {
+		header string
+		want   CellType
+	}{
+		{"SupporterID", Text},
+		{"supporterId", Text},
+		{"ID", Text},
+		{"Zip", Text},
+		{"PostalCode", Text},
+		{"Phone", Text},
+		{"TrackingCode", Text},
+		{"TransactionDate", Date},
+		{"Amount", Currency},
+		{"Revenue", Currency},
+		{"TicketPrice", Currency},
+		{"Fees", Currency},
+		{"Gifts", General},
+		{"Name", General},
+	}
+	for _, tt := range tests {
+		if got := ColumnType(tt.header); got != tt.want {
+			t.Errorf("ColumnType(%s) = %d, want %d", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCell(t *testing.T) {
+	tests := []struct {
+		name   string
+		t      CellType
+		v      string
+		style  int
+		number string
+	}{
+		{"general number", General, "42", styleGeneral, "42"},
+		{"general negative", General, "-3.25", styleGeneral, "-3.25"},
+		{"general leading zero", General, "02134", styleGeneral, ""},
+		{"general too long", General, "1234567890123456", styleGeneral, ""},
+		{"general text", General, "abc", styleGeneral, ""},
+		{"text number", Text, "42", styleText, ""},
+		{"date", Date, "2024-01-02", styleDate, "45293"},
+		{"date and time", Date, "2024-01-02T12:00:00Z", styleDateTime, "45293.5"},
+		{"date slashes", Date, "01/02/2024", styleDate, "45293"},
+		{"not a date", Date, "soon", styleGeneral, ""},
+		{"currency", Currency, "$1,234.50", styleCurrency, "1234.50"},
+		{"currency text", Currency, "free", styleGeneral, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style, number := cell(tt.t, tt.v)
+			if style != tt.style || number != tt.number {
+				t.Errorf("cell(%d, %q) = %d, %q, want %d, %q", tt.t, tt.v, style, number, tt.style, tt.number)
+			}
+		})
+	}
+}
+
+func TestSerial(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want string
+		ok   bool
+	}{
+		{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "45293", true},
+		{time.Date(2024, 1, 2, 6, 0, 0, 0, time.FixedZone("x", -5*3600)), "45293.25", true},
+		{time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC), "", false},
+		{time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC), "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Serial(tt.t)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Serial(%v) = %s, %v, want %s, %v", tt.t, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Donations", "Donations"},
+		{"a/b:c*d?e[f]g\\h", "abcdefgh"},
+		{"'quoted'", "quoted"},
+		{"", DefaultSheet},
+		{"[]", DefaultSheet},
+		{strings.Repeat("x", 40), strings.Repeat("x", MaxSheetName)},
+	}
+	for _, tt := range tests {
+		if got := SheetName(tt.name); got != tt.want {
+			t.Errorf("SheetName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWorkbook(t *testing.T) {
+	var b bytes.Buffer
+	book := NewWorkbook(&b)
+	book.Types = map[string]CellType{"Count": Text}
+	s, err := book.AddSheet("Gifts", []string{"SupporterID", "Amount", "Count", "Note"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = book.AddSheet("gifts", []string{"A"})
+	if err == nil {
+		t.Error("AddSheet allowed a duplicate name")
+	}
+	err = s.Write([]string{"007", "$5.00", "3", "a < b & c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = book.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Write([]string{"1"}); err != ErrClosed {
+		t.Errorf("Write after Close = %v, want %v", err, ErrClosed)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := make(map[string]string)
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = string(x)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("workbook has no %s", name)
+		}
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Gifts"`) {
+		t.Errorf("workbook.xml doesn't name the sheet:\n%s", parts["xl/workbook.xml"])
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	cells := []string{
+		`<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">SupporterID</t></is></c>`,
+		`<c r="A2" s="2" t="inlineStr"><is><t xml:space="preserve">007</t></is></c>`,
+		`<c r="B2" s="5"><v>5.00</v></c>`,
+		`<c r="C2" s="2" t="inlineStr"><is><t xml:space="preserve">3</t></is></c>`,
+		`<c r="D2" s="0" t="inlineStr"><is><t xml:space="preserve">a &lt; b &amp; c</t></is></c>`,
+	}
+	for _, c := range cells {
+		if !strings.Contains(sheet, c) {
+			t.Errorf("sheet1.xml doesn't have %s", c)
+		}
+	}
+}
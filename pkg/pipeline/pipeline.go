@@ -0,0 +1,219 @@
+package goengage
+
+//The pipeline package moves records from a source, through stages, to a sink.
+//Each stage has a fixed number of workers.  Stages are connected by buffered
+//channels.  A full channel makes the stage before it wait, so a slow sink
+//slows down the source instead of piling up records.
+//
+//Errors stop the pipeline.  An error from the source stops the source.  The
+//records that it already sent still go through the stages and the sink, so
+//that a stopped reader doesn't lose output.  An error from a stage or the sink
+//cancels the pipeline.  Run returns the first error.
+//
+//A shutdown doesn't cancel the pipeline.  The source stops when Engage calls
+//return ErrStopped.  A stage or sink that returns ErrStopped loses that record,
+//but the records behind it still drain to the sink.
+//
+//Records are interface{} values.  Stages convert them to the types that they
+//expect.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+// DefaultBuffer is the size of the channels between stages.
+const DefaultBuffer = 100
+
+// Emit sends a record to the next stage.  Returns an error if the pipeline
+// has been cancelled.
+type Emit func(x interface{}) error
+
+// Source sends records to the pipeline.  It returns when it runs out of
+// records or when emit returns an error.
+type Source func(ctx context.Context, emit Emit) error
+
+// Stage accepts a record and emits zero or more records to the next stage.
+type Stage func(ctx context.Context, x interface{}, emit Emit) error
+
+// Sink accepts records at the end of the pipeline.
+type Sink func(ctx context.Context, x interface{}) error
+
+// step is a stage and its workers.
+type step struct {
+	Name    string
+	Workers int
+	Stage   Stage
+}
+
+// Pipeline is a source, zero or more stages and an optional sink.
+type Pipeline struct {
+	Name       string
+	Buffer     int
+	source     Source
+	sourceName string
+	steps      []step
+	sink       Sink
+	sinkName   string
+}
+
+// NewPipeline returns an empty pipeline that uses DefaultBuffer.
+func NewPipeline(name string) *Pipeline {
+	return &Pipeline{
+		Name:   name,
+		Buffer: DefaultBuffer,
+	}
+}
+
+// Source sets the pipeline's source.
+func (p *Pipeline) Source(name string, f Source) *Pipeline {
+	p.sourceName = name
+	p.source = f
+	return p
+}
+
+// Stage adds a stage with a number of workers.  Fewer than one worker
+// means one.
+func (p *Pipeline) Stage(name string, workers int, f Stage) *Pipeline {
+	if workers < 1 {
+		workers = 1
+	}
+	p.steps = append(p.steps, step{name, workers, f})
+	return p
+}
+
+// Sink sets the pipeline's sink.  The sink has a single worker, so it
+// doesn't need to lock what it writes to.
+func (p *Pipeline) Sink(name string, f Sink) *Pipeline {
+	p.sinkName = name
+	p.sink = f
+	return p
+}
+
+// Capacity returns the number of records that the pipeline can hold after
+// the source emits them.  Use it to decide how far a restarted source
+// needs to back up.
+func (p *Pipeline) Capacity() int {
+	n := 0
+	for _, s := range p.steps {
+		n += p.Buffer + s.Workers
+	}
+	if p.sink != nil {
+		n += p.Buffer + 1
+	}
+	return n
+}
+
+// send writes a record to a channel.  Returns the context's error if the
+// pipeline is cancelled first.
+func send(ctx context.Context, c chan interface{}, x interface{}) error {
+	select {
+	case c <- x:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run starts the source, the stages and the sink, then waits for them to
+// finish.  Returns the first error.  Cancelling ctx cancels the pipeline.
+func (p *Pipeline) Run(ctx context.Context) error {
+	if p.source == nil {
+		return fmt.Errorf("%s: pipeline has no source", p.Name)
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	var fe goengage.FirstError
+	var wg sync.WaitGroup
+
+	//fail records an error.  Errors caused by cancelling are not the
+	//first error.  A shutdown doesn't cancel.
+	fail := func(name string, err error, stop bool) {
+		if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+			return
+		}
+		log.Printf("%s: %s error %v\n", p.Name, name, err)
+		fe.Set(err)
+		if stop && !errors.Is(err, goengage.ErrStopped) {
+			cancel()
+		}
+	}
+
+	//Start the source.  It closes its channel when it's done.
+	in := make(chan interface{}, p.Buffer)
+	wg.Add(1)
+	go (func(out chan interface{}) {
+		defer wg.Done()
+		defer close(out)
+		log.Printf("%s: %s begin\n", p.Name, p.sourceName)
+		err := p.source(ctx, func(x interface{}) error {
+			return send(ctx, out, x)
+		})
+		if err != nil {
+			fail(p.sourceName, err, false)
+		}
+		log.Printf("%s: %s end\n", p.Name, p.sourceName)
+	})(in)
+
+	//Start the stages.  Each stage closes its channel when all of its
+	//workers are done.
+	for _, s := range p.steps {
+		out := make(chan interface{}, p.Buffer)
+		var sg sync.WaitGroup
+		for i := 0; i < s.Workers; i++ {
+			sg.Add(1)
+			go (func(s step, in chan interface{}, out chan interface{}) {
+				defer sg.Done()
+				emit := func(x interface{}) error {
+					return send(ctx, out, x)
+				}
+				for x := range in {
+					if ctx.Err() != nil {
+						continue
+					}
+					err := s.Stage(ctx, x, emit)
+					if err != nil {
+						fail(s.Name, err, true)
+					}
+				}
+			})(s, in, out)
+		}
+		wg.Add(1)
+		go (func(s step, out chan interface{}) {
+			defer wg.Done()
+			log.Printf("%s: %s begin, %d workers\n", p.Name, s.Name, s.Workers)
+			sg.Wait()
+			close(out)
+			log.Printf("%s: %s end\n", p.Name, s.Name)
+		})(s, out)
+		in = out
+	}
+
+	//Start the sink.  Without a sink, the last channel is drained.
+	wg.Add(1)
+	go (func(in chan interface{}) {
+		defer wg.Done()
+		for x := range in {
+			if p.sink == nil || ctx.Err() != nil {
+				continue
+			}
+			err := p.sink(ctx, x)
+			if err != nil {
+				fail(p.sinkName, err, true)
+			}
+		}
+	})(in)
+
+	wg.Wait()
+	err := fe.Err()
+	if err == nil {
+		err = parent.Err()
+	}
+	return err
+}
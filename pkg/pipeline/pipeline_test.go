@@ -0,0 +1,172 @@
+package goengage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+)
+
+var (
+	errSource = errors.New("source failed")
+	errStage  = errors.New("stage failed")
+	errSink   = errors.New("sink failed")
+)
+
+// count returns a source that emits the numbers from 1 to n, then
+// returns err.
+func count(n int, err error) Source {
+	return func(ctx context.Context, emit Emit) error {
+		for i := 1; i <= n; i++ {
+			e := emit(i)
+			if e != nil {
+				return e
+			}
+		}
+		return err
+	}
+}
+
+// forever returns a source that emits until it's cancelled.
+func forever() Source {
+	return func(ctx context.Context, emit Emit) error {
+		for i := 1; ; i++ {
+			err := emit(i)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// double is a stage that emits each record times two.  It fails on fail.
+func double(fail int) Stage {
+	return func(ctx context.Context, x interface{}, emit Emit) error {
+		if x.(int) == fail {
+			return errStage
+		}
+		return emit(x.(int) * 2)
+	}
+}
+
+func TestPipelineRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		source Source
+		stage  Stage
+		fail   int
+		cancel bool
+		want   error
+		sum    int
+	}{
+		{"all records", count(100, nil), double(-1), -1, false, nil, 10100},
+		{"source error keeps sent records", count(10, errSource), double(-1), -1, false, errSource, 110},
+		{"stage error", forever(), double(50), -1, false, errStage, -1},
+		{"sink error", forever(), double(-1), 100, false, errSink, -1},
+		{"cancelled", forever(), double(-1), -1, true, context.Canceled, -1},
+		{"no stages", count(10, nil), nil, -1, false, nil, 55},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var m sync.Mutex
+			sum := 0
+			p := NewPipeline(tt.name).Source("source", tt.source)
+			if tt.stage != nil {
+				p = p.Stage("double", 3, tt.stage)
+			}
+			p = p.Sink("sink", func(ctx context.Context, x interface{}) error {
+				m.Lock()
+				defer m.Unlock()
+				if x.(int) == tt.fail {
+					return errSink
+				}
+				sum += x.(int)
+				if tt.cancel && sum > 1000 {
+					cancel()
+				}
+				return nil
+			})
+			done := make(chan error)
+			go (func() {
+				done <- p.Run(ctx)
+			})()
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("Run did not return")
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Run = %v, want %v", err, tt.want)
+			}
+			if tt.sum >= 0 && sum != tt.sum {
+				t.Errorf("sum = %d, want %d", sum, tt.sum)
+			}
+		})
+	}
+}
+
+func TestPipelineShutdown(t *testing.T) {
+	var sum int
+	p := NewPipeline("shutdown").
+		Source("source", count(100, goengage.ErrStopped)).
+		Stage("stopped", 3, func(ctx context.Context, x interface{}, emit Emit) error {
+			if x.(int) == 50 {
+				return goengage.ErrStopped
+			}
+			return emit(x)
+		}).
+		Sink("sink", func(ctx context.Context, x interface{}) error {
+			sum += x.(int)
+			return nil
+		})
+	err := p.Run(context.Background())
+	if !errors.Is(err, goengage.ErrStopped) {
+		t.Errorf("Run = %v, want %v", err, goengage.ErrStopped)
+	}
+	if sum != 5050-50 {
+		t.Errorf("sum = %d, want %d", sum, 5050-50)
+	}
+}
+
+func TestPipelineNoSource(t *testing.T) {
+	err := NewPipeline("empty").Run(context.Background())
+	if err == nil {
+		t.Error("Run = nil, want an error")
+	}
+}
+
+func TestPipelineCapacity(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers []int
+		sink    bool
+		want    int
+	}{
+		{"source only", nil, false, 0},
+		{"one stage", []int{5}, false, 105},
+		{"stages and sink", []int{1, 5}, true, 101 + 105 + 101},
+		{"zero workers", []int{0}, false, 101},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPipeline(tt.name).Source("source", count(0, nil))
+			for _, w := range tt.workers {
+				p = p.Stage("stage", w, double(-1))
+			}
+			if tt.sink {
+				p = p.Sink("sink", func(ctx context.Context, x interface{}) error {
+					return nil
+				})
+			}
+			if got := p.Capacity(); got != tt.want {
+				t.Errorf("Capacity = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package goengage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Environment variables that select and override credentials.
+const (
+	//ConfigEnv names the credentials file when one isn't provided.
+	ConfigEnv = "GOENGAGE_CONFIG"
+	//ProfileEnv names the profile when one isn't provided.
+	ProfileEnv = "GOENGAGE_PROFILE"
+	//TokenEnv overrides the profile's API token.
+	TokenEnv = "GOENGAGE_TOKEN"
+	//HostEnv overrides the profile's API host.
+	HostEnv = "GOENGAGE_HOST"
+	//TimezoneEnv overrides the profile's timezone.
+	TimezoneEnv = "GOENGAGE_TIMEZONE"
+	//OutputDirEnv overrides the profile's output directory.
+	OutputDirEnv = "GOENGAGE_OUTPUT_DIR"
+	//MaxBatchSizeEnv overrides the profile's offline batch size.
+	MaxBatchSizeEnv = "GOENGAGE_MAX_BATCH_SIZE"
+)
+
+// Profile holds the credentials and defaults for a single org.  Host
+// defaults to APIHost, or to UATHost if UAT is true.  A profile with a
+// MaxBatchSize is offline.  It uses the batch size instead of reading
+// metrics from Engage.
+type Profile struct {
+	Name         string `yaml:"-"`
+	Token        string `yaml:"token"`
+	Host         string `yaml:"host"`
+	UAT          bool   `yaml:"uat"`
+	Timezone     string `yaml:"timezone"`
+	OutputDir    string `yaml:"outputDir"`
+	MaxBatchSize int32  `yaml:"maxBatchSize"`
+}
+
+// Config is the contents of a credentials file.  A file can have named
+// profiles, one per org.  A file can also be the original single-org
+// format, with just a token and a host.
+//
+//	default: apda
+//	profiles:
+//	  apda:
+//	    token: your-token-here
+//	    timezone: America/Chicago
+//	    outputDir: /data/apda
+//	  sandbox:
+//	    token: your-other-token-here
+//	    uat: true
+type Config struct {
+	Default  string              `yaml:"default"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+	Profile  `yaml:",inline"`
+}
+
+// ReadConfig reads a credentials file.
+func ReadConfig(fn string) (*Config, error) {
+	raw, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	err = yaml.Unmarshal(raw, &c)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Names returns the profile names in alphabetical order.
+func (c *Config) Names() []string {
+	var a []string
+	for k := range c.Profiles {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+	return a
+}
+
+// Select returns a copy of a profile.  An empty name uses the profile in
+// GOENGAGE_PROFILE, then the default profile, then the only profile.  A
+// file in the original format has just one unnamed profile.
+func (c *Config) Select(name string) (*Profile, error) {
+	if len(name) == 0 {
+		name = os.Getenv(ProfileEnv)
+	}
+	if len(c.Profiles) == 0 {
+		if len(name) != 0 {
+			return nil, fmt.Errorf("profile %s not found, file has no profiles", name)
+		}
+		p := c.Profile
+		return &p, nil
+	}
+	if len(name) == 0 {
+		name = c.Default
+	}
+	if len(name) == 0 {
+		if len(c.Profiles) != 1 {
+			return nil, fmt.Errorf("a profile is *required*, choose from %v", c.Names())
+		}
+		name = c.Names()[0]
+	}
+	x, ok := c.Profiles[name]
+	if !ok || x == nil {
+		return nil, fmt.Errorf("profile %s not found, choose from %v", name, c.Names())
+	}
+	p := *x
+	p.Name = name
+	return &p, nil
+}
+
+// Override replaces profile values with the values of the GOENGAGE_*
+// environment variables that are set.  Returns an error if the batch
+// size isn't a number.
+func (p *Profile) Override() error {
+	for _, x := range []struct {
+		Name  string
+		Value *string
+	}{
+		{TokenEnv, &p.Token},
+		{HostEnv, &p.Host},
+		{TimezoneEnv, &p.Timezone},
+		{OutputDirEnv, &p.OutputDir},
+	} {
+		s := os.Getenv(x.Name)
+		if len(s) != 0 {
+			*x.Value = s
+		}
+	}
+	s := os.Getenv(MaxBatchSizeEnv)
+	if len(s) != 0 {
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%s: %v", MaxBatchSizeEnv, err)
+		}
+		p.MaxBatchSize = int32(n)
+	}
+	return nil
+}
+
+// Hostname returns the API host for the profile.
+func (p *Profile) Hostname() string {
+	if len(p.Host) != 0 {
+		return p.Host
+	}
+	if p.UAT {
+		return UATHost
+	}
+	return APIHost
+}
+
+// LoadProfile reads a profile from a credentials file and applies the
+// environment variable overrides.  An empty filename uses the file in
+// GOENGAGE_CONFIG.  If there's no file, then GOENGAGE_TOKEN must be set.
+func LoadProfile(fn string, name string) (*Profile, error) {
+	if len(fn) == 0 {
+		fn = os.Getenv(ConfigEnv)
+	}
+	p := &Profile{Name: name}
+	if len(fn) != 0 {
+		c, err := ReadConfig(fn)
+		if err != nil {
+			return nil, err
+		}
+		p, err = c.Select(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fn, err)
+		}
+	}
+	err := p.Override()
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Token) == 0 {
+		if len(fn) == 0 {
+			return nil, errors.New(" configuration file is *required*")
+		}
+		return nil, fmt.Errorf("%s: no API token", fn)
+	}
+	return p, nil
+}
+
+// ProfileCredentials reads a profile from a credentials file and uses it
+// to create an environment object.  Metrics are not read until they are
+// needed.
+func ProfileCredentials(fn string, name string) (*Environment, error) {
+	p, err := LoadProfile(fn, name)
+	if err != nil {
+		return nil, err
+	}
+	var e *Environment
+	if p.MaxBatchSize != 0 {
+		e, err = OfflineEnvironment(p.Hostname(), p.Token, p.MaxBatchSize)
+	} else {
+		e, err = OpenEnvironment(p.Hostname(), p.Token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.Profile = *p
+	return e, nil
+}
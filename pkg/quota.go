@@ -0,0 +1,269 @@
+package goengage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCallBudget is returned by NetOp.Do when a command would use more API
+// calls than its budget allows.
+var ErrCallBudget = errors.New("API call budget exceeded")
+
+// RateWindow is the period for Engage's rate limit.
+const RateWindow = time.Minute
+
+// Usage holds the API calls for a single endpoint.  Retries are calls
+// that were repeated because of HTTP 429 or 504 errors.  Estimate is the
+// number of calls that plans expected the endpoint to use.
+type Usage struct {
+	Endpoint string
+	Calls    int
+	Retries  int
+	Estimate int
+}
+
+// Plan is the calls that a scan needs.  The scan reads Total records from
+// Endpoint, BatchSize at a time, starting at Offset.  Each record needs
+// Lookups more calls to LookupEndpoint, like reading the supporter for
+// each activity.  Make a plan before the scan starts reading pages.
+type Plan struct {
+	Name           string
+	Endpoint       string
+	Total          int32
+	BatchSize      int32
+	Offset         int32
+	Lookups        int
+	LookupEndpoint string
+}
+
+// Records returns the number of records that the scan reads.
+func (p Plan) Records() int {
+	if p.Total <= p.Offset {
+		return 0
+	}
+	return int(p.Total - p.Offset)
+}
+
+// Pages returns the number of pages that the scan reads.
+func (p Plan) Pages() int {
+	if p.BatchSize < 1 {
+		return 0
+	}
+	n := int32(p.Records())
+	return int((n + p.BatchSize - 1) / p.BatchSize)
+}
+
+// Calls returns the number of calls that the scan needs.
+func (p Plan) Calls() int {
+	return p.Pages() + p.Records()*p.Lookups
+}
+
+// Ledger counts the API calls made by NetOp.Do.  A ledger with a budget
+// refuses calls that would go over the budget.  Scans make a Plan from
+// the search total before they read their pages.  A scan that would go
+// over budget is refused before it starts.
+//
+// The ledger also paces calls.  Throttle sets the minimum time between
+// calls.  SetQuota provides the calls that Engage has left in its rate
+// window.  When they're used up, calls wait for the next window instead
+// of failing with HTTP 429.  Waits end when Stopping starts a shutdown.
+type Ledger struct {
+	sync.Mutex
+	MaxCalls  int
+	RateLimit int32
+	Interval  time.Duration
+	usage     map[string]*Usage
+	last      time.Time
+	left      int32
+	window    time.Time
+}
+
+// APICalls is the ledger used by NetOp.Do.
+var APICalls = NewLedger()
+
+// NewLedger returns an empty ledger with no budget.
+func NewLedger() *Ledger {
+	return &Ledger{
+		left:  -1,
+		usage: make(map[string]*Usage),
+	}
+}
+
+// SetBudget sets the maximum number of calls.  Zero means no limit.
+func (l *Ledger) SetBudget(maxCalls int) {
+	l.Lock()
+	defer l.Unlock()
+	l.MaxCalls = maxCalls
+}
+
+// SetQuota starts a rate window from the metrics.  CurrentRateLimit is
+// the number of calls left in the window.  RateLimit is the number of
+// calls allowed in each window.
+func (l *Ledger) SetQuota(m Metrics) {
+	l.Lock()
+	defer l.Unlock()
+	l.RateLimit = m.RateLimit
+	l.left = m.CurrentRateLimit
+	l.window = time.Now()
+}
+
+// Throttle sets the minimum time between calls.  Zero turns pacing off.
+func (l *Ledger) Throttle(d time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	l.Interval = d
+}
+
+// entry returns the usage for an endpoint.  Query strings are ignored.
+// The ledger must be locked.
+func (l *Ledger) entry(endpoint string) *Usage {
+	if u, err := url.Parse(endpoint); err == nil {
+		endpoint = u.Path
+	}
+	u, ok := l.usage[endpoint]
+	if !ok {
+		u = &Usage{Endpoint: endpoint}
+		l.usage[endpoint] = u
+	}
+	return u
+}
+
+// calls returns the calls made for all endpoints.  The ledger must be
+// locked.
+func (l *Ledger) calls() int {
+	made := 0
+	for _, u := range l.usage {
+		made += u.Calls
+	}
+	return made
+}
+
+// reserve returns how long a call has to wait for the throttle and the
+// rate window, and takes its place.  The ledger must be locked.
+func (l *Ledger) reserve() time.Duration {
+	now := time.Now()
+	at := now
+	if l.Interval > 0 {
+		if next := l.last.Add(l.Interval); next.After(at) {
+			at = next
+		}
+		l.last = at
+	}
+	if l.RateLimit > 0 && l.left >= 0 {
+		end := l.window.Add(RateWindow)
+		if !at.Before(end) {
+			l.window = at
+			l.left = l.RateLimit
+		}
+		if l.left == 0 {
+			at = end
+			l.window = end
+			l.left = l.RateLimit
+		}
+		l.left--
+	}
+	return at.Sub(now)
+}
+
+// Begin records a call to an endpoint.  Pass is the attempt number from
+// NetOp.Do.  Returns ErrCallBudget if the budget is used up.  Waits if
+// calls are being paced.  Returns ErrStopped if a shutdown starts while
+// waiting.
+func (l *Ledger) Begin(endpoint string, pass int) error {
+	l.Lock()
+	made := l.calls()
+	if l.MaxCalls > 0 && made >= l.MaxCalls {
+		l.Unlock()
+		return fmt.Errorf("%w, %d calls made, budget is %d", ErrCallBudget, made, l.MaxCalls)
+	}
+	wait := l.reserve()
+	u := l.entry(endpoint)
+	u.Calls++
+	if pass > 1 {
+		u.Retries++
+	}
+	l.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-Stopping.Done():
+		l.Lock()
+		defer l.Unlock()
+		u.Calls--
+		if pass > 1 {
+			u.Retries--
+		}
+		return ErrStopped
+	}
+}
+
+// Plan adds a scan's calls to the estimates.  Returns ErrCallBudget if
+// the calls made and the scan's calls would go over the budget.  Scans
+// that need more calls than Engage has left in the rate window are paced
+// to the rate limit.
+func (l *Ledger) Plan(p Plan) error {
+	l.Lock()
+	defer l.Unlock()
+	need := p.Calls()
+	made := l.calls()
+	log.Printf("Plan: %s, %d records, %d pages and %d lookups, about %d calls\n",
+		p.Name, p.Records(), p.Pages(), p.Records()*p.Lookups, need)
+	if l.MaxCalls > 0 && made+need > l.MaxCalls {
+		return fmt.Errorf("%w, %s needs about %d calls for %d records, %d calls made, budget is %d",
+			ErrCallBudget, p.Name, need, p.Records(), made, l.MaxCalls)
+	}
+	l.entry(p.Endpoint).Estimate += p.Pages()
+	if p.Lookups > 0 {
+		l.entry(p.LookupEndpoint).Estimate += p.Records() * p.Lookups
+	}
+	if l.RateLimit > 0 && l.left >= 0 && need > int(l.left) {
+		log.Printf("Plan: %s needs more than the %d calls left in this minute, pacing calls to %d a minute\n",
+			p.Name, l.left, l.RateLimit)
+	}
+	return nil
+}
+
+// Usage returns the usage for each endpoint, sorted by endpoint.
+func (l *Ledger) Usage() []Usage {
+	l.Lock()
+	defer l.Unlock()
+	var a []Usage
+	for _, u := range l.usage {
+		a = append(a, *u)
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].Endpoint < a[j].Endpoint })
+	return a
+}
+
+// Report writes the calls for each endpoint and the totals.
+func (l *Ledger) Report(w io.Writer) {
+	a := l.Usage()
+	if len(a) == 0 {
+		return
+	}
+	f := "%-55s %8v %8v %9v\n"
+	fmt.Fprintf(w, f, "Endpoint", "Calls", "Retries", "Estimate")
+	calls, retries, estimate := 0, 0, 0
+	for _, u := range a {
+		fmt.Fprintf(w, f, u.Endpoint, u.Calls, u.Retries, u.Estimate)
+		calls += u.Calls
+		retries += u.Retries
+		estimate += u.Estimate
+	}
+	fmt.Fprintf(w, f, "Total", calls, retries, estimate)
+	if l.MaxCalls > 0 {
+		fmt.Fprintf(w, "Budget %d, %d left\n", l.MaxCalls, l.MaxCalls-calls)
+	}
+}
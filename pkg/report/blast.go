@@ -9,44 +9,63 @@ package goengage
 //terminating and puts a true onto DoneChannel.
 
 import (
+	"context"
 	"log"
 
 	goengage "github.com/salsalabs/goengage/pkg"
+	pipeline "github.com/salsalabs/goengage/pkg/pipeline"
 )
 
-// EmailBlastGuide is the interface to use when scanning all email blasts
-// and doing something.
-type EmailBlastGuide interface {
+// EmailBlastReader provides the payload and offset for reading email
+// blasts.
+type EmailBlastReader interface {
+	//Payload is the request payload defining which supporters to retrieve.
+	Payload() goengage.EmailBlastSearchRequestPayload
 
+	//Offset() returns the offset to start reading.  Useful for
+	//restarting after a service interruption.
+	Offset() int32
+}
+
+// EmailBlastVisitor does something with each email blast.
+type EmailBlastVisitor interface {
 	//Visit does something with the blast. Errors terminate.
 	Visit(s goengage.EmailActivity) error
 
 	//Finalize is called after all blasts have been processed.
 	Finalize() error
+}
 
-	//Payload is the request payload defining which supporters to retrieve.
-	Payload() goengage.EmailBlastSearchRequestPayload
+// EmailBlastTask reads email blasts and visits them.  Use it with
+// RunEmailBlasts.
+type EmailBlastTask interface {
+	EmailBlastReader
+	EmailBlastVisitor
+}
+
+// EmailBlastGuide is the interface to use when scanning all email blasts
+// and doing something with ReadEmailBlasts and ProcessEmailBlasts.
+type EmailBlastGuide interface {
+	EmailBlastTask
 
 	//Channel is the listener channel to use.
 	Channel() chan goengage.EmailActivity
 
 	//DoneChannel() receives a true when the listener is done.
 	DoneChannel() chan bool
-
-	//Offset() returns the offset to start reading.  Useful for
-	//restarting after a service interruption.
-
-	Offset() int32
 }
 
-// ReadEmailBlasts reads all blasts and pushes them onto a channel.
-// Probably a good idea to start this as a go routine after the Listener
-// is started...
-func ReadEmailBlasts(e *goengage.Environment, g EmailBlastGuide) error {
-	log.Println("ReadEmailBlasts: start")
-	count := int32(e.Metrics.MaxBatchSize)
-	offset := int32(g.Offset())
-	for count == int32(e.Metrics.MaxBatchSize) {
+// ReadEmailBlastsKey is the checkpoint key for ReadEmailBlasts.
+const ReadEmailBlastsKey = "ReadEmailBlasts"
+
+// EmailBlastPages reads all blasts and calls push for each of them.
+// Resumable readers start at the checkpoint.  InFlight is the number of
+// blasts that can be held after push returns.
+func EmailBlastPages(e *goengage.Environment, g EmailBlastReader, inFlight int32, push func(s goengage.EmailActivity) error) error {
+	cp := CheckpointFor(g)
+	count := int32(e.BatchSize())
+	offset := cp.Resume(ReadEmailBlastsKey, g.Offset(), inFlight, count)
+	for count == int32(e.BatchSize()) {
 		payload := g.Payload()
 		payload.Offset = offset
 		payload.Count = count
@@ -70,29 +89,98 @@ func ReadEmailBlasts(e *goengage.Environment, g EmailBlastGuide) error {
 		count = resp.Payload.Count
 		log.Printf("ReadEmailBlasts: offset %5d, read %2d\n", offset, count)
 		for _, s := range resp.Payload.EmailActivities {
-			g.Channel() <- s
+			err = push(s)
+			if err != nil {
+				return err
+			}
+		}
+		next := offset + int32(len(resp.Payload.EmailActivities))
+		err = cp.Complete(ReadEmailBlastsKey, offset, next)
+		if err != nil {
+			return err
 		}
-		offset += int32(len(resp.Payload.EmailActivities))
+		offset = next
+	}
+	return nil
+}
+
+// ReadEmailBlasts reads all blasts and pushes them onto a channel.
+// Probably a good idea to start this as a go routine after the Listener
+// is started...  Resumable guides start at the checkpoint.  The channel
+// is closed when reading stops, even for an error, so that listeners
+// can finish.
+func ReadEmailBlasts(e *goengage.Environment, g EmailBlastGuide) error {
+	log.Println("ReadEmailBlasts: start")
+	defer close(g.Channel())
+	err := EmailBlastPages(e, g, int32(cap(g.Channel())+1), func(s goengage.EmailActivity) error {
+		g.Channel() <- s
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 	log.Println("ReadEmailBlasts: done")
-	close(g.Channel())
 	return nil
 }
 
 // ProcessEmailBlasts reads blasts from an interface-provided channel, then
 // calls Visit in the interface.  At end of data, the app calls Finalize() then
-// sends true to the DoneChannel.
+// sends true to the DoneChannel.  Returns the first error from Visit or
+// Finalize.  Blasts after a Visit error are drained so that the reader can
+// finish.
 func ProcessEmailBlasts(e *goengage.Environment, g EmailBlastGuide) error {
 	log.Println("ProcessEmailBlasts: start")
+	var err error
 	for {
 		s, ok := <-g.Channel()
 		if !ok {
 			break
 		}
-		g.Visit(s)
+		if err != nil {
+			continue
+		}
+		err = g.Visit(s)
+		if err != nil {
+			log.Printf("ProcessEmailBlasts: %v\n", err)
+		}
+	}
+	ferr := g.Finalize()
+	if err == nil {
+		err = ferr
 	}
-	g.Finalize()
 	g.DoneChannel() <- true
 	log.Println("ProcessEmailBlasts: end")
-	return nil
+	return err
+}
+
+// EmailBlastSource returns a pipeline source that emits each blast.
+// Resumable readers start at the checkpoint.  Set the checkpoint's Rewind
+// to the pipeline's Capacity.
+func EmailBlastSource(e *goengage.Environment, g EmailBlastReader) pipeline.Source {
+	return func(ctx context.Context, emit pipeline.Emit) error {
+		return EmailBlastPages(e, g, 0, func(s goengage.EmailActivity) error {
+			return emit(s)
+		})
+	}
+}
+
+// RunEmailBlasts reads all blasts and calls Visit with a number of
+// workers.  Finalize is called once after all blasts have been visited.
+// Returns the first error.  For resumable tasks, the pipeline's capacity
+// is added to the checkpoint's Rewind.
+func RunEmailBlasts(e *goengage.Environment, g EmailBlastTask, workers int) error {
+	p := pipeline.NewPipeline("RunEmailBlasts").
+		Source("ReadEmailBlasts", EmailBlastSource(e, g)).
+		Stage("Visit", workers, func(ctx context.Context, x interface{}, emit pipeline.Emit) error {
+			return g.Visit(x.(goengage.EmailActivity))
+		})
+	if cp := CheckpointFor(g); cp != nil {
+		cp.Rewind += int32(p.Capacity())
+	}
+	err := p.Run(context.Background())
+	ferr := g.Finalize()
+	if err == nil {
+		err = ferr
+	}
+	return err
 }
@@ -1,18 +1,17 @@
 package goengage
 
 import (
-	"encoding/csv"
 	"fmt"
 	"log"
-	"os"
 	"sync"
 
 	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
 )
 
 // MaxRecords returns the maximum number of activity records
 // of a particular type.
-func MaxRecords(e *goengage.Environment, guide Guide, ts TimeSpan) (int32, error) {
+func MaxRecords(e *goengage.Environment, guide Source, ts TimeSpan) (int32, error) {
 	resp, err := ReadBatch(e, guide, int32(0), ts)
 	if err != nil {
 		return int32(0), err
@@ -20,64 +19,139 @@ func MaxRecords(e *goengage.Environment, guide Guide, ts TimeSpan) (int32, error
 	return resp.Payload.Total, err
 }
 
+// ActivityPlan returns the plan for reading a Source's activities from
+// an offset.  Guides that look up supporters need a call for each
+// activity.  Filters aren't known ahead of time, so the plan counts a
+// lookup for every activity.
+func ActivityPlan(e *goengage.Environment, guide Source, total int32, offset int32) goengage.Plan {
+	p := goengage.Plan{
+		Name:      fmt.Sprintf("%s activities", guide.TypeActivity()),
+		Endpoint:  goengage.SearchActivity,
+		Total:     total,
+		BatchSize: e.BatchSize(),
+		Offset:    offset,
+	}
+	lookup := true
+	if x, ok := guide.(Lookup); ok {
+		lookup = x.LookupSupporters()
+	}
+	if lookup {
+		p.Lookups = 1
+		p.LookupEndpoint = goengage.SearchSupporter
+	}
+	return p
+}
+
+// ActivityKey returns the checkpoint key for reading activities for a
+// Source in a time span.
+func ActivityKey(guide Source, ts TimeSpan) string {
+	return fmt.Sprintf("ReadActivities %s %s %s", guide.TypeActivity(), ts.Start, ts.End)
+}
+
 // ReadActivities retrieves activity records from Engage, filters them,
 // then writes them to the Guide channel. The offset channel tells
 // us where to start reading.  When no items are available from the
-// offset channel, we'll write a true to the done channel.
+// offset channel, we'll write a true to the done channel.  Resumable
+// guides record each finished offset in the checkpoint.  Returns the
+// first error.  Offsets after an error are read from the offset channel
+// and ignored so that the offset writer can finish.
 func ReadActivities(e *goengage.Environment,
-	guide Guide,
+	guide Source,
 	i int,
 	oc chan int32,
 	gc chan goengage.Fundraise,
 	dc chan bool,
-	ts TimeSpan) {
+	ts TimeSpan) (err error) {
 
 	n := fmt.Sprintf("ReadActivities-%d", i)
 	log.Printf("%s: begin", n)
+	cp := CheckpointFor(guide)
+	key := ActivityKey(guide, ts)
+	lookup := true
+	if x, ok := guide.(Lookup); ok {
+		lookup = x.LookupSupporters()
+	}
+	done := false
 	for {
 		offset, ok := <-oc
 		if !ok {
 			break
 		}
-		resp, err := ReadBatch(e, guide, offset, ts)
+		if done || err != nil {
+			continue
+		}
+		var count int32
+		count, err = ReadPage(e, guide, n, offset, ts, lookup, gc)
 		if err != nil {
-			// panic(err)
 			log.Printf("%s: offset %6d error %s\n", n, offset, err)
-			break
+			continue
 		}
-		if resp.Payload.Count == 0 {
-			break
+		if count == 0 {
+			done = true
+			continue
 		}
-		pass := int32(0)
-		total := resp.Payload.Total
-		for _, r := range resp.Payload.Activities {
-			if guide.Filter(r) {
+		err = cp.Complete(key, offset, offset+e.BatchSize())
+		if err != nil {
+			log.Printf("%s: offset %6d error %s\n", n, offset, err)
+		}
+	}
+	dc <- true
+	log.Printf("%s: end", n)
+	return err
+}
+
+// ReadPage reads the activities at an offset, filters them, then writes
+// them to the Guide channel.  If lookup is true, then the supporter is
+// read for each activity that passes the filter.  Returns the number of
+// activities read.
+func ReadPage(e *goengage.Environment,
+	guide Source,
+	n string,
+	offset int32,
+	ts TimeSpan,
+	lookup bool,
+	gc chan goengage.Fundraise) (int32, error) {
+
+	resp, err := ReadBatch(e, guide, offset, ts)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Payload.Count == 0 {
+		return 0, nil
+	}
+	pass := int32(0)
+	total := resp.Payload.Total
+	for _, r := range resp.Payload.Activities {
+		if guide.Filter(r) {
+			if lookup {
 				s, err := goengage.SupporterByID(e, r.SupporterID)
 				if err != nil {
-					panic(err)
+					return 0, err
+				}
+				//Deleted supporters leave their donations behind.
+				if s != nil {
+					r.Supporter = *s
 				}
-				r.Supporter = *s
-				gc <- r
-				pass++
 			}
+			gc <- r
+			pass++
 		}
-		log.Printf("%s: offset %6d of %6d, %3d adds\n", n, offset, total, pass)
 	}
-	dc <- true
-	log.Printf("%s: end", n)
+	log.Printf("%s: offset %6d of %6d, %3d adds\n", n, offset, total, pass)
+	return resp.Payload.Count, nil
 }
 
 // ReadBatch is a utility function to read activity records. Returns the
 // response object and an error code.
 func ReadBatch(e *goengage.Environment,
-	guide Guide,
+	guide Source,
 	offset int32,
 	ts TimeSpan) (resp *goengage.FundraiseResponse, err error) {
 
 	payload := goengage.ActivityRequestPayload{
 		Type:         guide.TypeActivity(),
 		Offset:       offset,
-		Count:        e.Metrics.MaxBatchSize,
+		Count:        e.BatchSize(),
 		ModifiedFrom: ts.Start,
 		ModifiedTo:   ts.End,
 	}
@@ -99,10 +173,11 @@ func ReadBatch(e *goengage.Environment,
 
 // ReportFundraising on a Guide by reading all records, filtering, then
 // writing survivors to a CSV file.
-func ReportFundraising(e *goengage.Environment, guide Guide, ts TimeSpan) (err error) {
+func ReportFundraising(e *goengage.Environment, guide Guide, ts TimeSpan) error {
 	gc := make(chan goengage.Fundraise, 100)
 	dc := make(chan bool)
 	oc := make(chan int32, 100)
+	var fe goengage.FirstError
 	var wg sync.WaitGroup
 
 	//Start the reader waiter.  It waits until all readers are done,
@@ -113,14 +188,21 @@ func ReportFundraising(e *goengage.Environment, guide Guide, ts TimeSpan) (err e
 		WaitForReaders(guide, gc, done)
 	})(guide, gc, dc, &wg)
 
+	//Resumed guides start at the checkpoint.  Records from there to the
+	//highest page that was finished are read again, and so are the pages
+	//that the readers were working on.
+	cp := CheckpointFor(guide)
+	start := cp.Resume(ActivityKey(guide, ts), guide.Offset(), int32(cap(gc)+1), e.BatchSize())
+	window := cp.Replay(ActivityKey(guide, ts), start) + int32(guide.Readers())*e.BatchSize()
+
 	//Start the CSV writer. It receives fundraise records from readers and
 	//writes them to a CSV.
 	wg.Add(1)
 	go (func(guide Guide, gc chan goengage.Fundraise, wg *sync.WaitGroup) {
 		defer wg.Done()
-		err := Store(guide, gc)
+		err := Store(guide, gc, int(window))
 		if err != nil {
-			panic(err)
+			fe.Set(err)
 		}
 	})(guide, gc, &wg)
 
@@ -137,18 +219,33 @@ func ReportFundraising(e *goengage.Environment, guide Guide, ts TimeSpan) (err e
 			ts TimeSpan,
 			wg *sync.WaitGroup) {
 			defer wg.Done()
-			ReadActivities(e, guide, i, oc, gc, dc, ts)
+			err := ReadActivities(e, guide, i, oc, gc, dc, ts)
+			if err != nil {
+				fe.Set(err)
+			}
 		})(e, guide, i, oc, gc, dc, ts, &wg)
 	}
 
-	// Push offsets onto the offset channel.
+	// Push offsets onto the offset channel.  Offsets stop when a shutdown
+	// starts.
 	maxRecords, err := MaxRecords(e, guide, ts)
+	if err != nil {
+		fe.Set(err)
+	}
 	log.Printf("ReportFundraising: reporting on start time %s\n", ts.Start)
 	log.Printf("ReportFundraising:              end   time %s\n", ts.End)
 	log.Printf("ReportFundraising: %d donations\n", maxRecords)
-	maxRecords = maxRecords + int32(e.Metrics.MaxBatchSize-1)
-	for offset := int32(guide.Offset()); offset <= maxRecords; offset += e.Metrics.MaxBatchSize {
-		oc <- offset
+	if err == nil {
+		err = goengage.APICalls.Plan(ActivityPlan(e, guide, maxRecords, start))
+		if err != nil {
+			fe.Set(err)
+		}
+	}
+	if err == nil {
+		maxRecords = maxRecords + int32(e.BatchSize()-1)
+		for offset := start; offset <= maxRecords && !goengage.Stopping.Stopped(); offset += e.BatchSize() {
+			oc <- offset
+		}
 	}
 	close(oc)
 
@@ -156,13 +253,77 @@ func ReportFundraising(e *goengage.Environment, guide Guide, ts TimeSpan) (err e
 	log.Printf("ReportFundraising: waiting for terminations")
 	wg.Wait()
 	log.Printf("ReportFundraising: done")
-	return err
+	return fe.Err()
+}
+
+// CollectFundraising reads all records for a Source, filters them, then
+// returns the survivors.  Use this instead of ReportFundraising when the
+// app needs to see all of the donations before it can write anything.
+func CollectFundraising(e *goengage.Environment, guide Source, ts TimeSpan) ([]goengage.Fundraise, error) {
+	gc := make(chan goengage.Fundraise, 100)
+	dc := make(chan bool)
+	oc := make(chan int32, 100)
+	var a []goengage.Fundraise
+	var fe goengage.FirstError
+	var wg sync.WaitGroup
+
+	//Start the reader waiter.  It closes the fundraise channel when
+	//all readers are done.
+	wg.Add(1)
+	go (func(guide Source, gc chan goengage.Fundraise, done chan bool, wg *sync.WaitGroup) {
+		defer wg.Done()
+		WaitForReaders(guide, gc, done)
+	})(guide, gc, dc, &wg)
+
+	//Start the collector.  It accumulates the fundraise records that
+	//the readers send.
+	wg.Add(1)
+	go (func(gc chan goengage.Fundraise, wg *sync.WaitGroup) {
+		defer wg.Done()
+		for r := range gc {
+			a = append(a, r)
+		}
+	})(gc, &wg)
+
+	//Start the readers.
+	for i := 0; i < guide.Readers(); i++ {
+		wg.Add(1)
+		go (func(i int, wg *sync.WaitGroup) {
+			defer wg.Done()
+			err := ReadActivities(e, guide, i, oc, gc, dc, ts)
+			if err != nil {
+				fe.Set(err)
+			}
+		})(i, &wg)
+	}
+
+	// Push offsets onto the offset channel.  Offsets stop when a
+	// shutdown starts.
+	maxRecords, err := MaxRecords(e, guide, ts)
+	if err == nil {
+		log.Printf("CollectFundraising: %d donations\n", maxRecords)
+		err = goengage.APICalls.Plan(ActivityPlan(e, guide, maxRecords, guide.Offset()))
+	}
+	if err == nil {
+		maxRecords = maxRecords + int32(e.BatchSize()-1)
+		for offset := int32(guide.Offset()); offset <= maxRecords && !goengage.Stopping.Stopped(); offset += e.BatchSize() {
+			oc <- offset
+		}
+	} else {
+		fe.Set(err)
+	}
+	close(oc)
+
+	log.Printf("CollectFundraising: waiting for terminations")
+	wg.Wait()
+	log.Printf("CollectFundraising: done, collected %d donations", len(a))
+	return a, fe.Err()
 }
 
 // WaitForReaders waits for readers to send to a done channel.
 // The number of readers is specified in the provided Guide.
 // Closes the inbound Funrdraise channel when all readers are done.
-func WaitForReaders(guide Guide, gc chan goengage.Fundraise, done chan bool) {
+func WaitForReaders(guide Source, gc chan goengage.Fundraise, done chan bool) {
 	count := guide.Readers()
 	for count > 0 {
 		log.Printf("WaitForReaders: Waiting for %d readers\n", count)
@@ -177,27 +338,65 @@ func WaitForReaders(guide Guide, gc chan goengage.Fundraise, done chan bool) {
 }
 
 // Store waits for fundraise records to appear on the queue, then
-// Writes them to a CSV file.
-func Store(guide Guide, gc chan goengage.Fundraise) error {
+// writes them to the guide's output.  Resumed guides append to the CSV file
+// and skip rows that match the file's last window rows.  Keyed guides match
+// rows on their key columns.  Returns the first error.  Records after an
+// error, or after the file can't be opened, are drained so that the
+// readers can finish.
+func Store(guide Guide, gc chan goengage.Fundraise, window int) error {
 	log.Println("Store: begin")
-	f, err := os.Create(guide.Filename())
+	opt := OutputFor(guide)
+	if x, ok := guide.(Keyed); ok {
+		opt.Keys = x.Keys()
+	}
+	opt.Window = window
+	fn := opt.Filename(guide.Filename())
+	w, err := opt.Open(fn, guide.Headers(), CheckpointFor(guide).Resuming())
 	if err != nil {
+		for range gc {
+		}
 		return err
 	}
-	w := csv.NewWriter(f)
-	w.Write(guide.Headers())
 
+	//Resumable output has to be on disk when its offsets are checkpointed.
+	//Other output is flushed now and then so that gzip can compress it.
+	every := output.FlushRows
+	if CheckpointFor(guide) != nil {
+		every = 1
+	}
+	rows := 0
 	for {
 		r, ok := <-gc
 		log.Printf("Store: %v\n", r)
 		if !ok {
 			break
 		}
+		if err != nil {
+			continue
+		}
 		log.Printf("Store: %v\n", guide.Line(r))
-		w.Write(guide.Line(r))
-		w.Flush()
+		err = w.Write(guide.Line(r))
+		if err != nil {
+			log.Printf("Store: %v, draining the queue\n", err)
+			continue
+		}
+		rows++
+		if rows%every == 0 {
+			err = w.Flush()
+		}
+		if x, ok := guide.(Summarizer); ok {
+			x.Summarize(r)
+		}
+	}
+	if x, ok := guide.(Summarizer); ok && err == nil {
+		err = WriteSummary(x, w)
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if x, ok := w.(*goengage.AppendCSV); ok {
+		log.Printf("Store: skipped %d rows that were already in the file\n", x.Skipped)
 	}
-	f.Close()
-	log.Println("Store: done")
+	log.Printf("Store: done, wrote %s\n", fn)
 	return err
 }
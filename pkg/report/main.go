@@ -6,6 +6,7 @@ import (
 	"time"
 
 	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
 )
 
 const (
@@ -28,35 +29,120 @@ const (
 	BackupDuration = "-1ms"
 )
 
-// Guide provides the basic tools to read and filter records then
-// write them to a CSV file.
-type Guide interface {
+// Source provides the basic tools to read and filter records.
+type Source interface {
 	//TypeActivity returns the kind of activity being read.
 	TypeActivity() string
 
 	//Filter returns true if the record should be used.
 	Filter(goengage.Fundraise) bool
 
-	//Headers returns column headers for a CSV file.
+	//Readers returns the number of readers to start.
+	Readers() int
+
+	//Offset() returns the offset to start reading.  Useful for
+	//restarting after a service interruption.
+	Offset() int32
+}
+
+// Lookup is an optional interface for a Source.  ReadActivities retrieves
+// the supporter record for each donation that passes the filter.  Sources
+// that do not need supporter records can implement Lookup and return false
+// to save an API call per donation.
+type Lookup interface {
+	LookupSupporters() bool
+}
+
+// Resumable is an optional interface for guides.  Readers record their
+// progress in the checkpoint.  A resumed checkpoint makes readers start
+// where the last run stopped.
+type Resumable interface {
+	Checkpoint() *goengage.Checkpoint
+}
+
+// CheckpointFor returns the guide's checkpoint.  Returns nil if the guide
+// isn't Resumable.
+func CheckpointFor(g interface{}) *goengage.Checkpoint {
+	if x, ok := g.(Resumable); ok {
+		return x.Checkpoint()
+	}
+	return nil
+}
+
+// Keyed is an optional interface for guides.  Keys returns the headers of
+// the columns that identify a row.  A resumed output file uses them to
+// skip rows that were already written.
+type Keyed interface {
+	Keys() []string
+}
+
+// Output is an optional interface for guides.  It selects the format
+// for the guide's Headers and Lines.  Guides that aren't Output write CSV.
+type Output interface {
+	Output() output.Options
+}
+
+// OutputFor returns the guide's output options.  Returns CSV if the guide
+// isn't Output.
+func OutputFor(g interface{}) output.Options {
+	if x, ok := g.(Output); ok {
+		return x.Output()
+	}
+	return output.Options{}
+}
+
+// Summarizer is an optional interface for guides.  Store passes each
+// record that it writes to Summarize.  If the output has sheets, like an
+// XLSX workbook, then the summary is written to its own sheet.
+type Summarizer interface {
+	//Summarize accepts a record that was written.
+	Summarize(goengage.Fundraise)
+
+	//Summary returns the summary sheet's name, headers and rows.
+	Summary() (name string, headers []string, rows [][]string)
+}
+
+// WriteSummary writes a guide's summary to a new sheet.  Does nothing if
+// the output doesn't have sheets.
+func WriteSummary(g Summarizer, w output.Writer) error {
+	b, ok := w.(output.Sheets)
+	if !ok {
+		log.Println("WriteSummary: the summary needs xlsx output, skipped")
+		return nil
+	}
+	name, headers, rows := g.Summary()
+	s, err := b.AddSheet(name, headers)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		err = s.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	return s.Flush()
+}
+
+// Guide provides the basic tools to read and filter records then
+// write them to a file.  The file is CSV unless the guide is Output.
+type Guide interface {
+	Source
+
+	//Headers returns column headers for the output.
 	Headers() []string
 
-	//Line returns a list of strings to go in to the CSV file for each
+	//Line returns a list of strings to go in to the output for each
 	//fundraising record.
 	Line(goengage.Fundraise) []string
 
-	//Readers returns the number of readers to start.
-	Readers() int
-
-	//Filename returns the CSV filename.
+	//Filename returns the CSV filename.  Output guides use it with the
+	//extension for their format.
 	Filename() string
 
 	//Location returns the location used to adjust transactions.
 	//Transactions are Zulu.  Timezone is used to covert them to local.
 	Location() *time.Location
-
-	//Offset() returns the offset to start reading.  Useful for
-	//restarting after a service interruption.
-	Offset() int32
 }
 
 // Span is a pair of Time objects for the start and end of a time span.
@@ -8,49 +8,70 @@ package goengage
 //terminating and puts a true onto DoneChannel.
 
 import (
+	"context"
 	"log"
 
 	goengage "github.com/salsalabs/goengage/pkg"
+	pipeline "github.com/salsalabs/goengage/pkg/pipeline"
 )
 
-// SupporterGuide is the interface to use when scanning all supporters
-// and doing something.
-type SupporterGuide interface {
+// SupporterReader provides the payload and offsets for reading supporters.
+type SupporterReader interface {
+	//Payload is the request payload defining which supporters to retrieve.
+	Payload() goengage.SupporterSearchRequestPayload
+
+	//Offset() returns the offset to start reading.  Useful for
+	//restarting after a service interruption.
+	Offset() int32
 
+	//AdjustOffset() allows you to change the offset just before the
+	//read. This is most helpful when the payload contains a list of
+	//ID's that need to be read in chunks. This method allows you to
+	//set the offset to zero, thereby avoiding confusion.
+	AdjustOffset(offset int32) int32
+}
+
+// SupporterVisitor does something with each supporter.
+type SupporterVisitor interface {
 	//Visit does something with the supporter. Errors terminate.
 	Visit(s goengage.Supporter) error
 
 	//Finalize is called after all supporters have been processed.
 	Finalize() error
+}
 
-	//Payload is the request payload defining which supporters to retrieve.
-	Payload() goengage.SupporterSearchRequestPayload
+// SupporterTask reads supporters and visits them.  Use it with
+// RunSupporters.
+type SupporterTask interface {
+	SupporterReader
+	SupporterVisitor
+}
+
+// SupporterGuide is the interface to use when scanning all supporters
+// and doing something with ReadSupporters and ProcessSupporters.
+type SupporterGuide interface {
+	SupporterTask
 
 	//Channel is the listener channel to use.
 	Channel() chan goengage.Supporter
 
 	//DoneChannel() receives a true when the listener is done.
 	DoneChannel() chan bool
-
-	//Offset() returns the offset to start reading.  Useful for
-	//restarting after a service interruption.
-	Offset() int32
-
-	//AdjustOffset() allows you to change the offset just before the
-	//read. This is most helpful when the payload contains a list of
-	//ID's that need to be read in chunks. This method allows you to
-	//set the offset to zero, thereby avoiding confusion.
-	AdjustOffset(offset int32) int32
 }
 
-// ReadSupporters reads all supporters and pushes them onto a channel.
-// Probably a good idea to start this as a go routine after the Listener
-// is started...
-func ReadSupporters(e *goengage.Environment, g SupporterGuide) error {
-	log.Println("ReadSupporters: start")
-	count := int32(e.Metrics.MaxBatchSize)
-	offset := int32(g.Offset())
-	for count == int32(e.Metrics.MaxBatchSize) {
+// ReadSupportersKey is the checkpoint key for ReadSupporters.
+const ReadSupportersKey = "ReadSupporters"
+
+// SupporterPages reads all supporters and calls push for each of them.
+// Resumable readers start at the checkpoint.  InFlight is the number of
+// supporters that can be held after push returns.  The first page's total
+// plans the calls for the rest of the pages.
+func SupporterPages(e *goengage.Environment, g SupporterReader, inFlight int32, push func(s goengage.Supporter) error) error {
+	cp := CheckpointFor(g)
+	count := int32(e.BatchSize())
+	offset := cp.Resume(ReadSupportersKey, g.Offset(), inFlight, count)
+	first := true
+	for count == int32(e.BatchSize()) {
 		payload := g.Payload()
 		payload.Offset = g.AdjustOffset(offset)
 		payload.Count = count
@@ -72,31 +93,112 @@ func ReadSupporters(e *goengage.Environment, g SupporterGuide) error {
 			return err
 		}
 		count = resp.Payload.Count
+		if first {
+			first = false
+			err = goengage.APICalls.Plan(goengage.Plan{
+				Name:      "supporters",
+				Endpoint:  goengage.SearchSupporter,
+				Total:     resp.Payload.Total,
+				BatchSize: e.BatchSize(),
+				Offset:    offset + count,
+			})
+			if err != nil {
+				return err
+			}
+		}
 		log.Printf("ReadSupporters: offset %d\n", offset)
 		for _, s := range resp.Payload.Supporters {
-			g.Channel() <- s
+			err = push(s)
+			if err != nil {
+				return err
+			}
+		}
+		err = cp.Complete(ReadSupportersKey, offset, offset+count)
+		if err != nil {
+			return err
 		}
 		offset += count
 	}
+	return nil
+}
+
+// ReadSupporters reads all supporters and pushes them onto a channel.
+// Probably a good idea to start this as a go routine after the Listener
+// is started...  Resumable guides start at the checkpoint.  The channel
+// is closed when reading stops, even for an error, so that listeners
+// can finish.
+func ReadSupporters(e *goengage.Environment, g SupporterGuide) error {
+	log.Println("ReadSupporters: start")
+	defer close(g.Channel())
+	err := SupporterPages(e, g, int32(cap(g.Channel())+1), func(s goengage.Supporter) error {
+		g.Channel() <- s
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 	log.Println("ReadSupporters: done")
-	close(g.Channel())
 	return nil
 }
 
 // ProcessSupporters reads supporters from an interface-provided channel, then
 // calls Visit in the interface.  At end of data, the app calls Finalize() then
-// sends true to the DoneChannel.
+// sends true to the DoneChannel.  Returns the first error from Visit or
+// Finalize.  Supporters after a Visit error are drained so that the reader
+// can finish.
 func ProcessSupporters(e *goengage.Environment, g SupporterGuide) error {
 	log.Println("ProcessSupporters: start")
+	var err error
 	for {
 		s, ok := <-g.Channel()
 		if !ok {
 			break
 		}
-		g.Visit(s)
+		if err != nil {
+			continue
+		}
+		err = g.Visit(s)
+		if err != nil {
+			log.Printf("ProcessSupporters: %v\n", err)
+		}
+	}
+	ferr := g.Finalize()
+	if err == nil {
+		err = ferr
 	}
-	g.Finalize()
 	g.DoneChannel() <- true
 	log.Println("ProcessSupporters: end")
-	return nil
+	return err
+}
+
+// SupporterSource returns a pipeline source that emits each supporter.
+// Resumable readers start at the checkpoint.  Set the checkpoint's Rewind
+// to the pipeline's Capacity.
+func SupporterSource(e *goengage.Environment, g SupporterReader) pipeline.Source {
+	return func(ctx context.Context, emit pipeline.Emit) error {
+		return SupporterPages(e, g, 0, func(s goengage.Supporter) error {
+			return emit(s)
+		})
+	}
+}
+
+// RunSupporters reads all supporters and calls Visit with a number of
+// workers.  Finalize is called once after all supporters have been
+// visited.  Returns the first error.  For resumable tasks, the pipeline's
+// capacity is added to the checkpoint's Rewind.
+func RunSupporters(e *goengage.Environment, g SupporterTask, workers int) error {
+	p := pipeline.NewPipeline("RunSupporters").
+		Source("ReadSupporters", SupporterSource(e, g)).
+		Stage("Visit", workers, func(ctx context.Context, x interface{}, emit pipeline.Emit) error {
+			return g.Visit(x.(goengage.Supporter))
+		})
+	if cp := CheckpointFor(g); cp != nil {
+		cp.Rewind += int32(p.Capacity())
+	}
+	err := p.Run(context.Background())
+	ferr := g.Finalize()
+	if err == nil {
+		err = ferr
+	}
+	return err
 }
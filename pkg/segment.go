@@ -1,6 +1,7 @@
 package goengage
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -10,6 +11,7 @@ const (
 	SegmentSearchMembers = "/api/integration/ext/v1/segments/members/search"
 	UpsertSegment        = "/api/integration/ext/v1/segments"
 	DeleteSegment        = "/api/integration/ext/v1/segments"
+	AssignSegmentMembers = "/api/integration/ext/v1/segments/members"
 )
 
 // Constants to drive counting, or not counting, supporters on a segment read.
@@ -158,6 +160,7 @@ type SegmentMembershipRequestPayload struct {
 type SegmentMembershipResponse struct {
 	Header  Header                           `json:"header,omitempty"`
 	Payload SegmentMembershipResponsePayload `json:"payload,omitempty"`
+	Errors  []Error                          `json:"errors,omitempty"`
 }
 
 // SegmentMembershipResponsePayload carries a batch of supporters for
@@ -178,14 +181,18 @@ type AssignSupportersRequest struct {
 	} `json:"payload"`
 }
 
+// SegmentMemberResult is the result of adding or removing a single
+// supporter from a segment.
+type SegmentMemberResult struct {
+	SupporterID string `json:"supporterId"`
+	Result      string `json:"result"`
+}
+
 // AssignSupportersResponse carries the results of adding supporters to a segment.
 type AssignSupportersResponse struct {
 	Payload struct {
-		Supporters []struct {
-			SupporterID string `json:"supporterId"`
-			Result      string `json:"result"`
-		} `json:"supporters"`
-		Count int32 `json:"count"`
+		Supporters []SegmentMemberResult `json:"supporters"`
+		Count      int32                 `json:"count"`
 	} `json:"payload"`
 }
 
@@ -209,3 +216,40 @@ type DeleteSupportersResponse struct {
 		Count int32 `json:"count"`
 	} `json:"payload"`
 }
+
+// AssignSupporters adds the provided supporter IDs to a segment.  The IDs
+// are sent to Engage in batches of MaxBatchSize.  Returns the results
+// for each supporter.
+func AssignSupporters(e *Environment, segmentID string, ids []string, logger *UtilLogger) ([]SegmentMemberResult, error) {
+	var a []SegmentMemberResult
+	size := int(e.BatchSize())
+	if size <= 0 {
+		return a, fmt.Errorf("AssignSupporters: invalid batch size %d", size)
+	}
+	for len(ids) > 0 {
+		n := size
+		if n > len(ids) {
+			n = len(ids)
+		}
+		var rqt AssignSupportersRequest
+		rqt.Payload.SegmentID = segmentID
+		rqt.Payload.SupporterIds = ids[:n]
+		var resp AssignSupportersResponse
+		op := NetOp{
+			Host:     e.Host,
+			Method:   UpdateMethod,
+			Endpoint: AssignSegmentMembers,
+			Token:    e.Token,
+			Request:  &rqt,
+			Response: &resp,
+			Logger:   logger,
+		}
+		err := op.Do()
+		if err != nil {
+			return a, err
+		}
+		a = append(a, resp.Payload.Supporters...)
+		ids = ids[n:]
+	}
+	return a, nil
+}
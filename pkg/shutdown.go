@@ -0,0 +1,174 @@
+package goengage
+
+import (
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	//DefaultGrace is how long a command has to finish after a signal.
+	DefaultGrace = 30 * time.Second
+
+	//ExitStopped is the exit code for a command that was stopped by a
+	//signal.
+	ExitStopped = 130
+)
+
+// ErrStopped is returned by NetOp.Do after a shutdown starts.
+var ErrStopped = errors.New("stopped by a signal")
+
+// Shutdown stops commands gracefully.  The first SIGINT or SIGTERM starts
+// a shutdown.  NetOp.Do returns ErrStopped for new calls.  Readers stop and
+// close their channels.  Listeners drain the channels and writers flush
+// their files.  Checkpoints keep their files so that the command can be
+// resumed.
+//
+// If the command doesn't finish within Grace, or if a second signal
+// arrives, then the registered flushers are called and the process exits
+// with ExitStopped.
+type Shutdown struct {
+	sync.Mutex
+	Grace    time.Duration
+	Signal   os.Signal
+	stopped  chan struct{}
+	flushers map[int]func() error
+	next     int
+}
+
+// Stopping is the shutdown used by NetOp.Do, readers and writers.
+var Stopping = NewShutdown()
+
+// NewShutdown returns a shutdown that hasn't started.
+func NewShutdown() *Shutdown {
+	return &Shutdown{
+		Grace:    DefaultGrace,
+		stopped:  make(chan struct{}),
+		flushers: make(map[int]func() error),
+	}
+}
+
+// Notify starts watching for SIGINT and SIGTERM.
+func (s *Shutdown) Notify() {
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go (func() {
+		sig := <-c
+		s.Stop(sig)
+		log.Printf("Shutdown: %v, stopping.  Waiting up to %v for output to be written\n", sig, s.Grace)
+		select {
+		case sig = <-c:
+			log.Printf("Shutdown: %v, exiting now\n", sig)
+		case <-time.After(s.Grace):
+			log.Printf("Shutdown: not done after %v, exiting now\n", s.Grace)
+		}
+		s.Flush()
+		os.Exit(ExitStopped)
+	})()
+}
+
+// Stop starts a shutdown.  Sig is the signal that caused it, and can be
+// nil.  Stopping twice does nothing.
+func (s *Shutdown) Stop(sig os.Signal) {
+	s.Lock()
+	defer s.Unlock()
+	if s.stopping() {
+		return
+	}
+	s.Signal = sig
+	close(s.stopped)
+}
+
+// stopping returns true if a shutdown has started.  The shutdown must be
+// locked.
+func (s *Shutdown) stopping() bool {
+	select {
+	case <-s.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stopped returns true if a shutdown has started.
+func (s *Shutdown) Stopped() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.stopping()
+}
+
+// Done returns a channel that is closed when a shutdown starts.
+func (s *Shutdown) Done() <-chan struct{} {
+	return s.stopped
+}
+
+// Err returns ErrStopped if a shutdown has started.
+func (s *Shutdown) Err() error {
+	if s.Stopped() {
+		return ErrStopped
+	}
+	return nil
+}
+
+// Register adds a function that flushes output.  Flushers are called if
+// the process has to exit before the command finishes.  Returns an ID for
+// Unregister.
+func (s *Shutdown) Register(f func() error) int {
+	s.Lock()
+	defer s.Unlock()
+	s.next++
+	s.flushers[s.next] = f
+	return s.next
+}
+
+// Unregister removes a flusher.  Call it when the output is closed.
+func (s *Shutdown) Unregister(id int) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.flushers, id)
+}
+
+// Flush calls the registered flushers.  Errors are logged.
+func (s *Shutdown) Flush() {
+	s.Lock()
+	var a []func() error
+	for _, f := range s.flushers {
+		a = append(a, f)
+	}
+	s.Unlock()
+	for _, f := range a {
+		err := f()
+		if err != nil {
+			log.Printf("Shutdown: flush error %v\n", err)
+		}
+	}
+}
+
+// Exit is the exit path for commands.  The registered flushers are called
+// so that output that wasn't closed can still be read.  An error is
+// logged and exits with 1.  A command that was stopped by a signal exits
+// with ExitStopped.
+func Exit(name string, err error) {
+	Stopping.Flush()
+	if err != nil {
+		log.Printf("%s: %v\n", name, err)
+	}
+	if Stopping.Stopped() {
+		log.Printf("%s: stopped by %v\n", name, Stopping.Signal)
+		os.Exit(ExitStopped)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// Run is the entry point for commands.  It watches for signals, calls
+// main with the arguments, then leaves through Exit.
+func Run(name string, main func(args []string) error, args []string) {
+	Stopping.Notify()
+	Exit(name, main(args))
+}
@@ -0,0 +1,132 @@
+package goengage
+
+//Formatters change a column's text.  A column can have more than one.
+//They're applied in order.  Some formatters take an argument after a colon.
+//
+//	title           ToTitle, like "In_Honor"
+//	upper, lower    change the case
+//	trim            remove leading and trailing spaces
+//	strip           replace newlines and tabs with spaces and squeeze
+//	                repeated spaces
+//	currency        two decimal places
+//	date[:layout]   a date in the report's timezone.  The default layout
+//	                is YYYY-MM-DD.  Layouts use Go's reference time.
+//	datetime        a date and time in the report's timezone
+//	default:text    text to use when the value is empty
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	report "github.com/salsalabs/goengage/pkg/report"
+)
+
+// Formatter names.
+const (
+	Title    = "title"
+	Upper    = "upper"
+	Lower    = "lower"
+	Trim     = "trim"
+	Strip    = "strip"
+	Money    = "currency"
+	DateOnly = "date"
+	DateTime = "datetime"
+	Default  = "default"
+)
+
+// DateTimeLayout is the default layout for datetime.  Dates use
+// report.BriefFormat.
+const DateTimeLayout = "2006-01-02 15:04:05"
+
+// spaces matches runs of whitespace for Strip.
+var spaces = regexp.MustCompile(`[\r\n\t ]+`)
+
+// Formatter changes a value's text.
+type Formatter func(s string) string
+
+// Formats is a list of formatter names.  YAML can have a single name or
+// a list.
+type Formats []string
+
+// UnmarshalYAML accepts a name or a list of names.
+func (f *Formats) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*f = Formats{s}
+		return nil
+	}
+	var a []string
+	err := unmarshal(&a)
+	if err != nil {
+		return err
+	}
+	*f = Formats(a)
+	return nil
+}
+
+// NewFormatter returns the formatter for a name.  Dates use loc.
+func NewFormatter(name string, loc *time.Location) (Formatter, error) {
+	arg := ""
+	if i := strings.Index(name, ":"); i != -1 {
+		name, arg = name[:i], name[i+1:]
+	}
+	switch name {
+	case Title:
+		return goengage.ToTitle, nil
+	case Upper:
+		return strings.ToUpper, nil
+	case Lower:
+		return strings.ToLower, nil
+	case Trim:
+		return strings.TrimSpace, nil
+	case Strip:
+		return func(s string) string {
+			return strings.TrimSpace(spaces.ReplaceAllString(s, " "))
+		}, nil
+	case Money:
+		return func(s string) string {
+			x, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return s
+			}
+			return fmt.Sprintf("%.2f", x)
+		}, nil
+	case DateOnly, DateTime:
+		layout := DateTimeLayout
+		if name == DateOnly {
+			layout = report.BriefFormat
+		}
+		if len(arg) != 0 {
+			layout = arg
+		}
+		return func(s string) string {
+			t, ok := ParseTime(s, loc)
+			if !ok {
+				return s
+			}
+			return t.In(loc).Format(layout)
+		}, nil
+	case Default:
+		return func(s string) string {
+			if len(s) == 0 {
+				return arg
+			}
+			return s
+		}, nil
+	}
+	return nil, fmt.Errorf("%s is not a formatter", name)
+}
+
+// ParseTime parses an Engage time or a YYYY-MM-DD date.  Dates are in loc.
+func ParseTime(s string, loc *time.Location) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err == nil {
+		return t, true
+	}
+	t, err = time.ParseInLocation(report.BriefFormat, s, loc)
+	return t, err == nil
+}
@@ -0,0 +1,256 @@
+package goengage
+
+//Paths find values in records.  Records are decoded JSON, so paths use the
+//JSON names from the Engage API.
+//
+//	supporter.address.city          a field in a nested object
+//	transactions[0].type            an item in a list
+//	customField("Favorite color")   a custom field's value, by name
+//	contact("EMAIL")                a contact's value, by type
+//
+//Names match exactly, or else without regard to case.  A path can start
+//with the record's name, so "supporter.firstName" and "firstName" are the
+//same for supporter records.  customField and contact at the start of a
+//path look in the record, then in the record's supporter.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path functions.
+const (
+	//CustomField finds a custom field value by name.
+	CustomField = "customField"
+	//Contact finds a contact value by type.
+	Contact = "contact"
+)
+
+// Record is a decoded JSON object.
+type Record map[string]interface{}
+
+// stepKind is what a step does.
+type stepKind int
+
+const (
+	keyStep stepKind = iota
+	indexStep
+	callStep
+)
+
+// step is a part of a path.
+type step struct {
+	Kind  stepKind
+	Name  string
+	Index int
+	Arg   string
+}
+
+// Path is a parsed field path.
+type Path struct {
+	Text  string
+	steps []step
+}
+
+// ParsePath parses a field path.
+func ParsePath(s string) (Path, error) {
+	p := Path{Text: s}
+	r := []rune(strings.TrimSpace(s))
+	i := 0
+	fail := func(msg string) (Path, error) {
+		return Path{}, fmt.Errorf("path %s: %s at position %d", s, msg, i+1)
+	}
+	for i < len(r) {
+		start := i
+		for i < len(r) && (r[i] == '_' || isLetter(r[i]) || (i > start && isDigit(r[i]))) {
+			i++
+		}
+		if i == start {
+			return fail("expected a name")
+		}
+		name := string(r[start:i])
+		if i < len(r) && r[i] == '(' {
+			if name != CustomField && name != Contact {
+				return fail(fmt.Sprintf("%s is not %s or %s", name, CustomField, Contact))
+			}
+			i++
+			if i >= len(r) || r[i] != '"' {
+				return fail("expected a quoted argument")
+			}
+			end := i + 1
+			for end < len(r) && r[end] != '"' {
+				if r[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(r) {
+				return fail("unterminated argument")
+			}
+			arg, err := strconv.Unquote(string(r[i : end+1]))
+			if err != nil {
+				return fail(err.Error())
+			}
+			i = end + 1
+			if i >= len(r) || r[i] != ')' {
+				return fail("expected )")
+			}
+			i++
+			p.steps = append(p.steps, step{Kind: callStep, Name: name, Arg: arg})
+		} else {
+			p.steps = append(p.steps, step{Kind: keyStep, Name: name})
+		}
+		for i < len(r) && r[i] == '[' {
+			end := i + 1
+			for end < len(r) && isDigit(r[end]) {
+				end++
+			}
+			if end == i+1 || end >= len(r) || r[end] != ']' {
+				return fail("expected [number]")
+			}
+			n, err := strconv.Atoi(string(r[i+1 : end]))
+			if err != nil {
+				return fail(err.Error())
+			}
+			p.steps = append(p.steps, step{Kind: indexStep, Index: n})
+			i = end + 1
+		}
+		if i < len(r) {
+			if r[i] != '.' || i == len(r)-1 {
+				return fail("expected .")
+			}
+			i++
+		}
+	}
+	if len(p.steps) == 0 {
+		return fail("empty path")
+	}
+	return p, nil
+}
+
+// isLetter returns true for ASCII letters.
+func isLetter(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isDigit returns true for ASCII digits.
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Starts returns true if the path starts with a name or a function.
+func (p Path) Starts(name string) bool {
+	return len(p.steps) != 0 && p.steps[0].Kind != indexStep && strings.EqualFold(p.steps[0].Name, name)
+}
+
+// Value returns the value at the path.  Returns nil if the record doesn't
+// have one.  Name is the record's name, like "supporter".
+func (p Path) Value(r Record, name string) interface{} {
+	var v interface{} = map[string]interface{}(r)
+	steps := p.steps
+	if steps[0].Kind == keyStep && strings.EqualFold(steps[0].Name, name) && field(v, steps[0].Name) == nil {
+		steps = steps[1:]
+	}
+	for i, s := range steps {
+		switch s.Kind {
+		case keyStep:
+			v = field(v, s.Name)
+		case indexStep:
+			a, ok := v.([]interface{})
+			if !ok || s.Index >= len(a) {
+				return nil
+			}
+			v = a[s.Index]
+		case callStep:
+			x := call(v, s)
+			if x == nil && i == 0 {
+				x = call(field(v, "supporter"), s)
+			}
+			v = x
+		}
+		if v == nil {
+			return nil
+		}
+	}
+	return v
+}
+
+// field returns a field from an object.  Names match exactly, or else
+// without regard to case.
+func field(v interface{}, name string) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if x, ok := m[name]; ok {
+		return x
+	}
+	for k, x := range m {
+		if strings.EqualFold(k, name) {
+			return x
+		}
+	}
+	return nil
+}
+
+// call finds a custom field value by name or a contact value by type.
+func call(v interface{}, s step) interface{} {
+	list, key := "customFieldValues", "name"
+	if s.Name == Contact {
+		list, key = "contacts", "type"
+	}
+	a, ok := field(v, list).([]interface{})
+	if !ok {
+		return nil
+	}
+	for _, x := range a {
+		k, _ := field(x, key).(string)
+		if k == s.Arg || (s.Name == Contact && strings.EqualFold(k, s.Arg)) {
+			return field(x, "value")
+		}
+	}
+	return nil
+}
+
+// Text returns a value as a string.  Objects and lists are JSON.
+func Text(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// NewRecord converts a struct from the API to a Record.
+func NewRecord(x interface{}) (Record, error) {
+	if r, ok := x.(Record); ok {
+		return r, nil
+	}
+	if m, ok := x.(map[string]interface{}); ok {
+		return Record(m), nil
+	}
+	b, err := json.Marshal(x)
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var r Record
+	err = d.Decode(&r)
+	return r, err
+}
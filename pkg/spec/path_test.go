@@ -0,0 +1,138 @@
+package goengage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path  string
+		steps []step
+	}{
+		{"firstName", []step{{Kind: keyStep, Name: "firstName"}}},
+		{" firstName ", []step{{Kind: keyStep, Name: "firstName"}}},
+		{"supporter.address.city", []step{
+			{Kind: keyStep, Name: "supporter"},
+			{Kind: keyStep, Name: "address"},
+			{Kind: keyStep, Name: "city"},
+		}},
+		{"address_line1", []step{{Kind: keyStep, Name: "address_line1"}}},
+		{"transactions[0].type", []step{
+			{Kind: keyStep, Name: "transactions"},
+			{Kind: indexStep, Index: 0},
+			{Kind: keyStep, Name: "type"},
+		}},
+		{"grid[1][12]", []step{
+			{Kind: keyStep, Name: "grid"},
+			{Kind: indexStep, Index: 1},
+			{Kind: indexStep, Index: 12},
+		}},
+		{`customField("Favorite color")`, []step{{Kind: callStep, Name: CustomField, Arg: "Favorite color"}}},
+		{`contact("EMAIL")`, []step{{Kind: callStep, Name: Contact, Arg: "EMAIL"}}},
+		{`supporter.customField("Say \"hi\"")`, []step{
+			{Kind: keyStep, Name: "supporter"},
+			{Kind: callStep, Name: CustomField, Arg: `Say "hi"`},
+		}},
+		{`customField("Tags")[0]`, []step{
+			{Kind: callStep, Name: CustomField, Arg: "Tags"},
+			{Kind: indexStep, Index: 0},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			p, err := ParsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Text != tt.path {
+				t.Errorf("Text = %q, want %q", p.Text, tt.path)
+			}
+			if !reflect.DeepEqual(p.steps, tt.steps) {
+				t.Errorf("steps = %+v, want %+v", p.steps, tt.steps)
+			}
+		})
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"   ",
+		"1st",
+		".name",
+		"name.",
+		"a..b",
+		"a b",
+		"a-b",
+		"[0]",
+		"a[]",
+		"a[x]",
+		"a[1",
+		`other("x")`,
+		"customField(x)",
+		`customField("x"`,
+		`customField("x`,
+		"customField(",
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			_, err := ParsePath(tt)
+			if err == nil {
+				t.Errorf("ParsePath(%q) = nil, want an error", tt)
+			}
+		})
+	}
+}
+
+func TestPathValue(t *testing.T) {
+	r := Record{
+		"firstName": "Ada",
+		"Address":   map[string]interface{}{"city": "Boston"},
+		"transactions": []interface{}{
+			map[string]interface{}{"type": "CHARGE"},
+		},
+		"customFieldValues": []interface{}{
+			map[string]interface{}{"name": "Color", "value": "blue"},
+		},
+		"supporter": map[string]interface{}{
+			"contacts": []interface{}{
+				map[string]interface{}{"type": "EMAIL", "value": "ada@example.com"},
+			},
+			"customFieldValues": []interface{}{
+				map[string]interface{}{"name": "Size", "value": "L"},
+			},
+		},
+	}
+	tests := []struct {
+		path string
+		name string
+		want string
+	}{
+		{"firstName", "donation", "Ada"},
+		{"FIRSTNAME", "donation", "Ada"},
+		{"donation.firstName", "donation", "Ada"},
+		{"supporter.firstName", "donation", ""},
+		{"supporter.customFieldValues[0].value", "supporter", "L"},
+		{"address.city", "donation", "Boston"},
+		{"transactions[0].type", "donation", "CHARGE"},
+		{"transactions[1].type", "donation", ""},
+		{"firstName[0]", "donation", ""},
+		{`customField("Color")`, "donation", "blue"},
+		{`customField("color")`, "donation", ""},
+		{`customField("Size")`, "donation", "L"},
+		{`contact("email")`, "donation", "ada@example.com"},
+		{"missing.field", "donation", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			p, err := ParsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Text(p.Value(r, tt.name)); got != tt.want {
+				t.Errorf("Value = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
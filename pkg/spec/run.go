@@ -0,0 +1,316 @@
+package goengage
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	output "github.com/salsalabs/goengage/pkg/output"
+	pipeline "github.com/salsalabs/goengage/pkg/pipeline"
+	report "github.com/salsalabs/goengage/pkg/report"
+)
+
+// activityPage is a page of activities.  Activities are decoded as
+// records so that every activity type keeps all of its fields.
+type activityPage struct {
+	Header  goengage.Header `json:"header,omitempty"`
+	Payload struct {
+		Total      int32                    `json:"total,omitempty"`
+		Offset     int32                    `json:"offset,omitempty"`
+		Count      int32                    `json:"count,omitempty"`
+		Activities []map[string]interface{} `json:"activities,omitempty"`
+	} `json:"payload,omitempty"`
+	Errors []goengage.Error `json:"errors,omitempty"`
+}
+
+// PageCount returns the number of activities in the page.
+// Implements goengage.ActivityPage.
+func (r *activityPage) PageCount() int32 {
+	return r.Payload.Count
+}
+
+// PageErrors returns the errors that Engage returned for the page.
+// Implements goengage.ActivityPage.
+func (r *activityPage) PageErrors() []goengage.Error {
+	return r.Errors
+}
+
+// supporterReader reads supporters modified in a time span.
+// Implements goengage.report.SupporterReader.
+type supporterReader struct {
+	ts report.TimeSpan
+}
+
+// Payload returns a payload for supporters modified in the time span.
+func (x supporterReader) Payload() goengage.SupporterSearchRequestPayload {
+	return goengage.SupporterSearchRequestPayload{
+		IdentifierType: goengage.SupporterIDType,
+		ModifiedFrom:   x.ts.Start,
+		ModifiedTo:     x.ts.End,
+	}
+}
+
+// Offset returns the first offset.
+func (x supporterReader) Offset() int32 {
+	return 0
+}
+
+// AdjustOffset does nothing.
+func (x supporterReader) AdjustOffset(offset int32) int32 {
+	return offset
+}
+
+// blastReader reads email blasts or comm series published in a time span.
+// Implements goengage.report.EmailBlastReader.
+type blastReader struct {
+	ts         report.TimeSpan
+	commSeries bool
+}
+
+// Payload returns a payload for blasts published in the time span.
+func (x blastReader) Payload() goengage.EmailBlastSearchRequestPayload {
+	t := goengage.Email
+	if x.commSeries {
+		t = goengage.CommSeries
+	}
+	return goengage.EmailBlastSearchRequestPayload{
+		Type:          t,
+		PublishedFrom: x.ts.Start,
+		PublishedTo:   x.ts.End,
+	}
+}
+
+// Offset returns the first offset.
+func (x blastReader) Offset() int32 {
+	return 0
+}
+
+// TimeSpan returns the source's dates in Engage format.
+func (s *Spec) TimeSpan() report.TimeSpan {
+	st := report.Parse(s.Source.Start, s.loc, report.StartDuration)
+	et := report.Parse(s.Source.End, s.loc, report.EndDuration)
+	return report.NewTimeSpan(st, et)
+}
+
+// PipelineSource returns a pipeline source for the spec's records.
+func (s *Spec) PipelineSource(e *goengage.Environment) pipeline.Source {
+	ts := s.TimeSpan()
+	switch s.Source.Type {
+	case Supporters:
+		return report.SupporterSource(e, supporterReader{ts})
+	case Blasts:
+		return report.EmailBlastSource(e, blastReader{ts, s.Source.CommSeries})
+	case Segment:
+		return s.segmentSource(e)
+	}
+	return s.activitySource(e, ts)
+}
+
+// segmentSource emits the members of the spec's segment.  The calls are
+// planned after the first page is read.
+func (s *Spec) segmentSource(e *goengage.Environment) pipeline.Source {
+	return func(ctx context.Context, emit pipeline.Emit) error {
+		count := e.BatchSize()
+		offset := int32(0)
+		first := true
+		for count == e.BatchSize() {
+			rqt := goengage.SegmentMembershipRequest{
+				Header: goengage.RequestHeader{},
+				Payload: goengage.SegmentMembershipRequestPayload{
+					SegmentID: s.Source.SegmentID,
+					Offset:    offset,
+					Count:     count,
+				},
+			}
+			var resp goengage.SegmentMembershipResponse
+			n := goengage.NetOp{
+				Host:     e.Host,
+				Method:   goengage.SearchMethod,
+				Endpoint: goengage.SegmentSearchMembers,
+				Token:    e.Token,
+				Request:  &rqt,
+				Response: &resp,
+			}
+			err := n.Do()
+			if err != nil {
+				return err
+			}
+			if len(resp.Errors) != 0 {
+				x := resp.Errors[0]
+				return fmt.Errorf("%s: segment %s %v %v %v", s.Name, s.Source.SegmentID, x.Code, x.Message, x.Details)
+			}
+			count = int32(len(resp.Payload.Supporters))
+			if first {
+				first = false
+				err = goengage.APICalls.Plan(goengage.Plan{
+					Name:      s.Name,
+					Endpoint:  goengage.SegmentSearchMembers,
+					Total:     resp.Payload.Total,
+					BatchSize: e.BatchSize(),
+					Offset:    offset + count,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			log.Printf("%s: segment offset %6d of %6d\n", s.Name, offset, resp.Payload.Total)
+			for _, x := range resp.Payload.Supporters {
+				err = emit(x)
+				if err != nil {
+					return err
+				}
+			}
+			offset += count
+		}
+		return nil
+	}
+}
+
+// activityPayload returns the search for the spec's activities.
+func (s *Spec) activityPayload(ts report.TimeSpan) goengage.ActivityRequestPayload {
+	return goengage.ActivityRequestPayload{
+		Type:         s.Source.ActivityType,
+		ModifiedFrom: ts.Start,
+		ModifiedTo:   ts.End,
+	}
+}
+
+// Plan reads the number of activities and returns the plan for reading
+// them.  Specs that use the supporter need a lookup for each activity.
+func (s *Spec) Plan(e *goengage.Environment, ts report.TimeSpan) (goengage.Plan, error) {
+	payload := s.activityPayload(ts)
+	payload.Count = 1
+	rqt := goengage.ActivityRequest{
+		Header:  goengage.RequestHeader{},
+		Payload: payload,
+	}
+	var resp activityPage
+	n := goengage.NetOp{
+		Host:     e.Host,
+		Method:   goengage.SearchMethod,
+		Endpoint: goengage.SearchActivity,
+		Token:    e.Token,
+		Request:  &rqt,
+		Response: &resp,
+	}
+	err := n.Do()
+	if err != nil {
+		return goengage.Plan{}, err
+	}
+	if len(resp.Errors) != 0 {
+		x := resp.Errors[0]
+		return goengage.Plan{}, fmt.Errorf("%s: %s %v %v %v", s.Name, payload.Type, x.Code, x.Message, x.Details)
+	}
+	p := goengage.Plan{
+		Name:      s.Name,
+		Endpoint:  goengage.SearchActivity,
+		Total:     resp.Payload.Total,
+		BatchSize: e.BatchSize(),
+	}
+	if s.NeedsSupporters() {
+		p.Lookups = 1
+		p.LookupEndpoint = goengage.SearchSupporter
+	}
+	return p, nil
+}
+
+// activitySource emits the activities of the spec's type modified in the
+// time span.  The calls are planned before the first page is read.
+func (s *Spec) activitySource(e *goengage.Environment, ts report.TimeSpan) pipeline.Source {
+	return func(ctx context.Context, emit pipeline.Emit) error {
+		p, err := s.Plan(e, ts)
+		if err != nil {
+			return err
+		}
+		err = goengage.APICalls.Plan(p)
+		if err != nil {
+			return err
+		}
+		page := func() goengage.ActivityPage {
+			return &activityPage{}
+		}
+		return goengage.ActivityPages(e, s.activityPayload(ts), nil, page, func(p goengage.ActivityPage) error {
+			resp := p.(*activityPage)
+			log.Printf("%s: activity offset %6d of %6d\n", s.Name, resp.Payload.Offset, resp.Payload.Total)
+			for _, x := range resp.Payload.Activities {
+				err := emit(Record(x))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// addSupporter reads the supporter for an activity and adds it to the
+// record as "supporter".  Deleted supporters leave their activities
+// behind, so a missing supporter isn't an error.
+func addSupporter(e *goengage.Environment, r Record) error {
+	id := Text(field(map[string]interface{}(r), "supporterId"))
+	if len(id) == 0 {
+		return nil
+	}
+	x, err := goengage.SupporterByID(e, id)
+	if err != nil || x == nil {
+		return err
+	}
+	m, err := NewRecord(*x)
+	if err != nil {
+		return err
+	}
+	r["supporter"] = map[string]interface{}(m)
+	return nil
+}
+
+// Run reads the spec's records and writes a row for each record that
+// passes the filters.  The headers must already be written.  Rows are
+// written in the order that workers finish them.
+func (s *Spec) Run(e *goengage.Environment, w output.Writer) error {
+	workers := s.Workers
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+	lookup := s.NeedsSupporters()
+	if lookup {
+		log.Printf("%s: reading the supporter for each %s\n", s.Name, s.Source.ActivityType)
+	}
+	rows := 0
+	p := pipeline.NewPipeline(s.Name).
+		Source(fmt.Sprintf("Read %s", s.Source.Type), s.PipelineSource(e)).
+		Stage("Rows", workers, func(ctx context.Context, x interface{}, emit pipeline.Emit) error {
+			r, err := NewRecord(x)
+			if err != nil {
+				return err
+			}
+			if lookup {
+				if !s.PassEarly(r) {
+					return nil
+				}
+				err = addSupporter(e, r)
+				if err != nil {
+					return err
+				}
+			}
+			if !s.Pass(r) {
+				return nil
+			}
+			return emit(s.Row(r))
+		}).
+		Sink("Write", func(ctx context.Context, x interface{}) error {
+			err := w.Write(x.([]string))
+			if err != nil {
+				return err
+			}
+			rows++
+			if rows%output.FlushRows == 0 {
+				log.Printf("%s: %d rows\n", s.Name, rows)
+				return w.Flush()
+			}
+			return nil
+		})
+	err := p.Run(context.Background())
+	log.Printf("%s: wrote %d rows\n", s.Name, rows)
+	return err
+}
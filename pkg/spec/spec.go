@@ -0,0 +1,412 @@
+package goengage
+
+//The spec package runs reports that are defined in YAML.  A spec names a
+//source of records, filters that records must pass, and the columns to
+//write.  Columns are field paths with optional formatters.  See path.go
+//and format.go.
+//
+//	name: dedications
+//	timezone: America/New_York
+//	source:
+//	  type: activity
+//	  activityType: FUNDRAISE
+//	  start: 2026-10-05
+//	  end: 2026-10-11
+//	filters:
+//	  - field: dedicationType
+//	    op: notEmpty
+//	  - field: dedicationType
+//	    op: ne
+//	    value: NONE
+//	columns:
+//	  - header: City
+//	    field: supporter.address.city
+//	  - header: TransactionType
+//	    field: transactions[0].type
+//	    format: title
+//	  - header: DedicationAddress
+//	    field: customField("Address of Recipient to Notify")
+//	    format: strip
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	goengage "github.com/salsalabs/goengage/pkg"
+	report "github.com/salsalabs/goengage/pkg/report"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Source types.
+const (
+	//Supporters reads all supporters modified in the date range.
+	Supporters = "supporters"
+	//Segment reads the members of a segment.
+	Segment = "segment"
+	//Activity reads activities of a type modified in the date range.
+	Activity = "activity"
+	//Blasts reads email blasts published in the date range.
+	Blasts = "blasts"
+)
+
+// Filter operators.
+const (
+	Equals    = "eq"
+	NotEquals = "ne"
+	In        = "in"
+	NotIn     = "notIn"
+	Contains  = "contains"
+	Matches   = "matches"
+	Empty     = "empty"
+	NotEmpty  = "notEmpty"
+	Before    = "before"
+	After     = "after"
+)
+
+const (
+	//DefaultTimezone is used when a spec doesn't have one.
+	DefaultTimezone = "America/New_York"
+
+	//DefaultStart is used when a source doesn't have a start date.
+	DefaultStart = "2000-01-01"
+
+	//DefaultWorkers is the number of workers that filter records and
+	//read supporters for activities.
+	DefaultWorkers = 5
+)
+
+// SourceTypes lists the source types.
+var SourceTypes = []string{Supporters, Segment, Activity, Blasts}
+
+// Spec is a report definition.
+type Spec struct {
+	Name     string   `yaml:"name"`
+	Timezone string   `yaml:"timezone"`
+	Output   string   `yaml:"output"`
+	Format   string   `yaml:"format"`
+	Workers  int      `yaml:"workers"`
+	Source   Source   `yaml:"source"`
+	Filters  []Filter `yaml:"filters"`
+	Columns  []Column `yaml:"columns"`
+
+	loc *time.Location
+}
+
+// Source is where a spec's records come from.  ActivityType is needed for
+// activities and SegmentID is needed for segments.  Start and End are
+// YYYY-MM-DD dates.  End defaults to today.
+type Source struct {
+	Type         string `yaml:"type"`
+	ActivityType string `yaml:"activityType"`
+	SegmentID    string `yaml:"segmentId"`
+	CommSeries   bool   `yaml:"commSeries"`
+	Start        string `yaml:"start"`
+	End          string `yaml:"end"`
+}
+
+// Filter is a test that records must pass.  Value is used by eq, ne,
+// contains, matches, before and after.  Values is used by in and notIn.
+// Before and after accept "start" and "end" for the source's first and
+// last moments.
+type Filter struct {
+	Field  string   `yaml:"field"`
+	Op     string   `yaml:"op"`
+	Value  string   `yaml:"value"`
+	Values []string `yaml:"values"`
+
+	path Path
+	re   *regexp.Regexp
+	when time.Time
+	loc  *time.Location
+}
+
+// Column is a column in the output.  Header defaults to the field.
+type Column struct {
+	Header string  `yaml:"header"`
+	Field  string  `yaml:"field"`
+	Format Formats `yaml:"format"`
+
+	path       Path
+	formatters []Formatter
+}
+
+// Load reads a spec from a YAML file and validates it.  Unknown keys are
+// errors, to catch typos.
+func Load(fn string) (*Spec, error) {
+	raw, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var s Spec
+	err = yaml.UnmarshalStrict(raw, &s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	if len(s.Name) == 0 {
+		s.Name = strings.TrimSuffix(filepath.Base(fn), filepath.Ext(fn))
+	}
+	err = s.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	return &s, nil
+}
+
+// Validate checks the spec and prepares its paths, filters and
+// formatters.  Call it again after changing the spec.
+func (s *Spec) Validate() (err error) {
+	if len(s.Timezone) == 0 {
+		s.Timezone = DefaultTimezone
+	}
+	s.loc, err = time.LoadLocation(s.Timezone)
+	if err != nil {
+		return err
+	}
+	err = s.Source.validate(s.loc)
+	if err != nil {
+		return err
+	}
+	if len(s.Columns) == 0 {
+		return fmt.Errorf("spec %s has no columns", s.Name)
+	}
+	for i := range s.Filters {
+		err = s.Filters[i].compile(s.loc, s.Source)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range s.Columns {
+		err = s.Columns[i].compile(s.loc)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks the source's type and dates.
+func (x *Source) validate(loc *time.Location) error {
+	switch x.Type {
+	case Supporters, Blasts:
+	case Segment:
+		if len(x.SegmentID) == 0 {
+			return fmt.Errorf("source %s needs a segmentId", Segment)
+		}
+	case Activity:
+		if len(x.ActivityType) == 0 {
+			return fmt.Errorf("source %s needs an activityType, like %s", Activity, goengage.FundraiseType)
+		}
+		x.ActivityType = strings.ToUpper(x.ActivityType)
+	default:
+		return fmt.Errorf("source type %q is not one of %s", x.Type, strings.Join(SourceTypes, ", "))
+	}
+	if len(x.Start) == 0 {
+		x.Start = DefaultStart
+	}
+	if len(x.End) == 0 {
+		x.End = time.Now().In(loc).Format(report.BriefFormat)
+	}
+	st, err := time.ParseInLocation(report.BriefFormat, x.Start, loc)
+	if err != nil {
+		return fmt.Errorf("source start: %v", err)
+	}
+	et, err := time.ParseInLocation(report.BriefFormat, x.End, loc)
+	if err != nil {
+		return fmt.Errorf("source end: %v", err)
+	}
+	if et.Before(st) {
+		return fmt.Errorf("source end %s is before start %s", x.End, x.Start)
+	}
+	return nil
+}
+
+// compile parses the filter's path and value.
+func (f *Filter) compile(loc *time.Location, src Source) (err error) {
+	f.loc = loc
+	f.path, err = ParsePath(f.Field)
+	if err != nil {
+		return err
+	}
+	switch f.Op {
+	case Equals, NotEquals, Contains, In, NotIn, Empty, NotEmpty:
+	case Matches:
+		f.re, err = regexp.Compile(f.Value)
+	case Before, After:
+		//report.Parse shifts times for Engage, so it can't be used here.
+		switch f.Value {
+		case "start":
+			f.when, _ = time.ParseInLocation(report.BriefFormat, src.Start, loc)
+		case "end":
+			f.when, _ = time.ParseInLocation(report.BriefFormat, src.End, loc)
+			f.when = f.when.AddDate(0, 0, 1).Add(-time.Millisecond)
+		default:
+			var ok bool
+			f.when, ok = ParseTime(f.Value, loc)
+			if !ok {
+				err = fmt.Errorf("%s is not a date", f.Value)
+			}
+		}
+	default:
+		err = fmt.Errorf("op %q is not a filter operator", f.Op)
+	}
+	if err != nil {
+		return fmt.Errorf("filter on %s: %v", f.Field, err)
+	}
+	return nil
+}
+
+// Pass returns true if a record passes the filter.
+func (f *Filter) Pass(r Record, name string) bool {
+	v := Text(f.path.Value(r, name))
+	switch f.Op {
+	case Equals:
+		return v == f.Value
+	case NotEquals:
+		return v != f.Value
+	case In, NotIn:
+		for _, x := range f.Values {
+			if v == x {
+				return f.Op == In
+			}
+		}
+		return f.Op == NotIn
+	case Contains:
+		return strings.Contains(v, f.Value)
+	case Matches:
+		return f.re.MatchString(v)
+	case Empty:
+		return len(v) == 0
+	case NotEmpty:
+		return len(v) != 0
+	case Before, After:
+		t, ok := ParseTime(v, f.loc)
+		if !ok {
+			return false
+		}
+		if f.Op == Before {
+			return t.Before(f.when)
+		}
+		return !t.Before(f.when)
+	}
+	return false
+}
+
+// compile parses the column's path and formatters.
+func (c *Column) compile(loc *time.Location) (err error) {
+	if len(c.Header) == 0 {
+		c.Header = c.Field
+	}
+	c.path, err = ParsePath(c.Field)
+	if err != nil {
+		return fmt.Errorf("column %s: %v", c.Header, err)
+	}
+	c.formatters = nil
+	for _, name := range c.Format {
+		f, err := NewFormatter(name, loc)
+		if err != nil {
+			return fmt.Errorf("column %s: %v", c.Header, err)
+		}
+		c.formatters = append(c.formatters, f)
+	}
+	return nil
+}
+
+// Cell returns the column's formatted text for a record.
+func (c *Column) Cell(r Record, name string) string {
+	s := Text(c.path.Value(r, name))
+	for _, f := range c.formatters {
+		s = f(s)
+	}
+	return s
+}
+
+// Location returns the spec's timezone.
+func (s *Spec) Location() *time.Location {
+	return s.loc
+}
+
+// Headers returns the column headers.
+func (s *Spec) Headers() []string {
+	var a []string
+	for _, c := range s.Columns {
+		a = append(a, c.Header)
+	}
+	return a
+}
+
+// RecordName returns the name of the source's records.  Paths can start
+// with it.
+func (s *Spec) RecordName() string {
+	switch s.Source.Type {
+	case Supporters, Segment:
+		return "supporter"
+	case Blasts:
+		return "blast"
+	}
+	return "activity"
+}
+
+// Pass returns true if a record passes all of the filters.
+func (s *Spec) Pass(r Record) bool {
+	name := s.RecordName()
+	for i := range s.Filters {
+		if !s.Filters[i].Pass(r, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// PassEarly returns true if a record passes the filters that don't use
+// the supporter.  Activities that fail don't need a supporter read.
+func (s *Spec) PassEarly(r Record) bool {
+	name := s.RecordName()
+	for i := range s.Filters {
+		f := &s.Filters[i]
+		if !usesSupporter(f.path) && !f.Pass(r, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// usesSupporter returns true if a path reads an activity's supporter.
+func usesSupporter(p Path) bool {
+	return p.Starts("supporter") || p.Starts(CustomField) || p.Starts(Contact)
+}
+
+// Row returns the cells for a record.
+func (s *Spec) Row(r Record) []string {
+	name := s.RecordName()
+	var a []string
+	for i := range s.Columns {
+		a = append(a, s.Columns[i].Cell(r, name))
+	}
+	return a
+}
+
+// NeedsSupporters returns true if an activity spec uses the supporter.
+// Paths that start with "supporter", customField or contact read the
+// supporter for each activity.
+func (s *Spec) NeedsSupporters() bool {
+	if s.Source.Type != Activity {
+		return false
+	}
+	var paths []Path
+	for _, f := range s.Filters {
+		paths = append(paths, f.path)
+	}
+	for _, c := range s.Columns {
+		paths = append(paths, c.path)
+	}
+	for _, p := range paths {
+		if usesSupporter(p) {
+			return true
+		}
+	}
+	return false
+}
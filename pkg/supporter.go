@@ -1,6 +1,7 @@
 package goengage
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -14,6 +15,10 @@ const (
 	SupporterSearchGroups = "/api/integration/ext/v1/supporters/groups"
 )
 
+// ErrEmailNotFound is returned by SupporterByEmail when Engage doesn't have
+// a supporter with the email.  Other errors mean that the search failed.
+var ErrEmailNotFound = errors.New("is not a valid email")
+
 // Contact types.
 const (
 	ContactEmail    = "EMAIL"
@@ -203,18 +208,7 @@ func SupporterUpsert(e *Environment, s *Supporter, logger *UtilLogger) (*Support
 	count := int32(len(response.Payload.Supporters))
 	if count != 0 {
 		s = &response.Payload.Supporters[0]
-		switch s.Result {
-		case Added:
-			err = nil
-		case Updated:
-			err = nil
-		case ValidationError:
-			err = fmt.Errorf("engage returned %s for ID %s", s.Result, s.SupporterID)
-		case SystemError:
-			err = fmt.Errorf("engage returned %s for ID %s", s.Result, s.SupporterID)
-		case NotFound:
-			err = fmt.Errorf("engage returned %s for ID %s", s.Result, s.SupporterID)
-		}
+		err = UpsertResult(*s)
 	} else {
 		err = fmt.Errorf("engage return zero responses for ID %s", s.SupporterID)
 
@@ -222,6 +216,52 @@ func SupporterUpsert(e *Environment, s *Supporter, logger *UtilLogger) (*Support
 	return s, err
 }
 
+// SupportersUpsert upserts a batch of supporters into Engage with a
+// single call.  Batches can't be larger than the environment's batch
+// size.  Returns the supporters from the response, and an error for the
+// first supporter that wasn't added or updated.
+func SupportersUpsert(e *Environment, a []Supporter, logger *UtilLogger) ([]Supporter, error) {
+	request := SupporterUpdateRequest{
+		Header:  RequestHeader{},
+		Payload: SupporterUpdatePayload{Supporters: a},
+	}
+	var response SupporterUpdateResponse
+	n := NetOp{
+		Host:     e.Host,
+		Endpoint: UpsertSupporter,
+		Method:   UpdateMethod,
+		Token:    e.Token,
+		Request:  &request,
+		Response: &response,
+		Logger:   logger,
+	}
+	err := n.Do()
+	if err != nil {
+		return nil, err
+	}
+	r := response.Payload.Supporters
+	if len(r) != len(a) {
+		return r, fmt.Errorf("engage returned %d responses for %d supporters", len(r), len(a))
+	}
+	for _, s := range r {
+		err = UpsertResult(s)
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// UpsertResult returns an error if an upserted supporter wasn't added or
+// updated.
+func UpsertResult(s Supporter) error {
+	switch s.Result {
+	case ValidationError, SystemError, NotFound:
+		return fmt.Errorf("engage returned %s for ID %s", s.Result, s.SupporterID)
+	}
+	return nil
+}
+
 // SupporterGroupsRequest requests the groups (segments) that a supporter
 // belongs to.
 type SupporterGroupsRequest struct {
@@ -268,7 +308,7 @@ func SupporterByID(e *Environment, k string) (*Supporter, error) {
 		Identifiers:    []string{k},
 		IdentifierType: SupporterIDType,
 		Offset:         int32(0),
-		Count:          e.Metrics.MaxBatchSize,
+		Count:          e.BatchSize(),
 	}
 	request := SupporterSearchRequest{
 		Header:  RequestHeader{},
@@ -302,6 +342,51 @@ func SupporterByID(e *Environment, k string) (*Supporter, error) {
 	return nil, nil
 }
 
+// SupportersByID returns the supporters for a list of supporter IDs.
+// Supporters are read in batches.  Supporters that are not found are
+// not in the map.
+func SupportersByID(e *Environment, ids []string, logger *UtilLogger) (map[string]Supporter, error) {
+	m := make(map[string]Supporter)
+	size := int(e.BatchSize())
+	if size <= 0 {
+		return m, fmt.Errorf("invalid batch size %d", size)
+	}
+	for i := 0; i < len(ids); i += size {
+		j := i + size
+		if j > len(ids) {
+			j = len(ids)
+		}
+		request := SupporterSearchRequest{
+			Header: RequestHeader{},
+			Payload: SupporterSearchRequestPayload{
+				Identifiers:    ids[i:j],
+				IdentifierType: SupporterIDType,
+				Count:          e.BatchSize(),
+			},
+		}
+		var response SupporterSearchResults
+		n := NetOp{
+			Host:     e.Host,
+			Endpoint: SearchSupporter,
+			Method:   SearchMethod,
+			Token:    e.Token,
+			Request:  &request,
+			Response: &response,
+			Logger:   logger,
+		}
+		err := n.Do()
+		if err != nil {
+			return m, err
+		}
+		for _, s := range response.Payload.Supporters {
+			if s.Result == Found {
+				m[s.SupporterID] = s
+			}
+		}
+	}
+	return m, nil
+}
+
 // SupporterByEmail returns the first supporter whose email
 // matches the provided email.  Duplicates are gleefully ignored.
 func SupporterByEmail(e *Environment, email string) (s *Supporter, err error) {
@@ -310,7 +395,7 @@ func SupporterByEmail(e *Environment, email string) (s *Supporter, err error) {
 		Identifiers:    []string{email},
 		IdentifierType: EmailAddressType,
 		Offset:         offset,
-		Count:          e.Metrics.MaxBatchSize,
+		Count:          e.BatchSize(),
 	}
 	rqt := SupporterSearchRequest{
 		Header:  RequestHeader{},
@@ -339,7 +424,7 @@ func SupporterByEmail(e *Environment, email string) (s *Supporter, err error) {
 			}
 		}
 	}
-	err = fmt.Errorf("error: %s is not a valid email", email)
+	err = fmt.Errorf("error: %s %w", email, ErrEmailNotFound)
 	return s, err
 }
 
@@ -347,7 +432,7 @@ func SupporterByEmail(e *Environment, email string) (s *Supporter, err error) {
 // where the supporter is a member.
 func SupporterSegments(e *Environment, s string) (a []Segment, err error) {
 	offset := int32(0)
-	count := e.Metrics.MaxBatchSize
+	count := e.BatchSize()
 
 	payload := SupporterGroupsRequestPayload{
 		Identifiers:    []string{s},
@@ -367,7 +452,7 @@ func SupporterSegments(e *Environment, s string) (a []Segment, err error) {
 		Response: &resp,
 	}
 
-	for count == e.Metrics.MaxBatchSize {
+	for count == e.BatchSize() {
 		payload.Offset = offset
 		payload.Count = count
 		err := n.Do()
@@ -1,5 +1,7 @@
 package goengage
 
+import "fmt"
+
 //Provides a way to search for transaction templates.
 //If you're want to search for transactions, use pkg/transactions.go.
 
@@ -11,52 +13,52 @@ const (
 // TransactionTemplate is an Engage donation template. Part of transactions in
 // the API documentation.
 type TransactionTemplate struct {
-	CreatedBy              string `json:"createdBy"`
-	CreatedDate            string `json:"createdDate"`
-	ModifiedBy             string `json:"modifiedBy"`
-	LastModified           string `json:"lastModified"`
-	TransactionTemplateID  string `json:"templateId"`
-	ActivityDate           string `json:"activityDate"`
-	PersonID               string `json:"personId"`
-	ActivityID             string `json:"activityId"`
-	ActivityFormID         string `json:"activityFormId"`
-	ActivityName           string `json:"activityName"`
-	AccountExpiration      string `json:"accountExpiration"`
-	AccountType            string `json:"accountType"`
-	DonationType           string `json:"donationType"`
-	LastTransactionType    string `json:"lastTransactionType"`
-	OneTimeAmount          int    `json:"oneTimeAmount"`
-	RecurringAmount        int    `json:"recurringAmount"`
-	TotalReceivedAmount    int    `json:"totalReceivedAmount"`
-	RecurringEnd           string `json:"recurringEnd"`
-	RecurringInterval      string `json:"recurringInterval"`
-	RecurringStart         string `json:"recurringStart"`
-	RecurringTransactionID string `json:"recurringTransactionId"`
-	IsFirstDonation        bool   `json:"isFirstDonation"`
-	Dedication             string `json:"dedication"`
-	DedicationType         string `json:"dedicationType"`
-	Designation            string `json:"designation"`
-	Notify                 string `json:"notify"`
-	WasImported            bool   `json:"wasImported"`
-	ReceivedAmountDonation int    `json:"receivedAmountDonation"`
-	FeesPaid               int    `json:"feesPaid"`
-	ReceivedAmountTickets  int    `json:"receivedAmountTickets"`
-	Appeal                 string `json:"appeal"`
-	Campaign               string `json:"campaign"`
-	AppealName             string `json:"appealName"`
-	CampaignName           string `json:"campaignName"`
-	Fund                   string `json:"fund"`
-	FundName               string `json:"fundName"`
-	ReceivedAmountProducts int    `json:"receivedAmountProducts"`
-	IsAnonymous            bool   `json:"isAnonymous"`
-	DisplayName            string `json:"displayName"`
-	WasAPIImported         bool   `json:"wasApiImported"`
-	ExternalID             string `json:"externalId"`
-	DoNotSyncCrm           bool   `json:"doNotSyncCrm"`
-	HideAmount             bool   `json:"hideAmount"`
-	SmartAmount            bool   `json:"smartAmount"`
-	OpenEnded              bool   `json:"openEnded"`
-	Result                 string `json:"result"`
+	CreatedBy              string  `json:"createdBy"`
+	CreatedDate            string  `json:"createdDate"`
+	ModifiedBy             string  `json:"modifiedBy"`
+	LastModified           string  `json:"lastModified"`
+	TransactionTemplateID  string  `json:"templateId"`
+	ActivityDate           string  `json:"activityDate"`
+	PersonID               string  `json:"personId"`
+	ActivityID             string  `json:"activityId"`
+	ActivityFormID         string  `json:"activityFormId"`
+	ActivityName           string  `json:"activityName"`
+	AccountExpiration      string  `json:"accountExpiration"`
+	AccountType            string  `json:"accountType"`
+	DonationType           string  `json:"donationType"`
+	LastTransactionType    string  `json:"lastTransactionType"`
+	OneTimeAmount          float64 `json:"oneTimeAmount"`
+	RecurringAmount        float64 `json:"recurringAmount"`
+	TotalReceivedAmount    float64 `json:"totalReceivedAmount"`
+	RecurringEnd           string  `json:"recurringEnd"`
+	RecurringInterval      string  `json:"recurringInterval"`
+	RecurringStart         string  `json:"recurringStart"`
+	RecurringTransactionID string  `json:"recurringTransactionId"`
+	IsFirstDonation        bool    `json:"isFirstDonation"`
+	Dedication             string  `json:"dedication"`
+	DedicationType         string  `json:"dedicationType"`
+	Designation            string  `json:"designation"`
+	Notify                 string  `json:"notify"`
+	WasImported            bool    `json:"wasImported"`
+	ReceivedAmountDonation float64 `json:"receivedAmountDonation"`
+	FeesPaid               float64 `json:"feesPaid"`
+	ReceivedAmountTickets  float64 `json:"receivedAmountTickets"`
+	Appeal                 string  `json:"appeal"`
+	Campaign               string  `json:"campaign"`
+	AppealName             string  `json:"appealName"`
+	CampaignName           string  `json:"campaignName"`
+	Fund                   string  `json:"fund"`
+	FundName               string  `json:"fundName"`
+	ReceivedAmountProducts float64 `json:"receivedAmountProducts"`
+	IsAnonymous            bool    `json:"isAnonymous"`
+	DisplayName            string  `json:"displayName"`
+	WasAPIImported         bool    `json:"wasApiImported"`
+	ExternalID             string  `json:"externalId"`
+	DoNotSyncCrm           bool    `json:"doNotSyncCrm"`
+	HideAmount             bool    `json:"hideAmount"`
+	SmartAmount            bool    `json:"smartAmount"`
+	OpenEnded              bool    `json:"openEnded"`
+	Result                 string  `json:"result"`
 }
 
 // TransactionTemplateSearchRequest contains parameters for searching for segments.
@@ -75,8 +77,8 @@ type TransactionTemplateSearchRequestPayload struct {
 	CreatedTo      string   `json:"createdTo,omitempty"`
 	ModifiedFrom   string   `json:"modifiedFrom,omitempty"`
 	ModifiedTo     string   `json:"modifiedTo,omitempty"`
-	Offset         int      `json:"offset,omitempty"`
-	Count          int      `json:"count,omitempty"`
+	Offset         int32    `json:"offset,omitempty"`
+	Count          int32    `json:"count,omitempty"`
 }
 
 // TransactionTemplateSearchResponse contains the results returned by searching for segments.
@@ -103,3 +105,43 @@ type TransactionTemplateSearchResponsePayload struct {
 	Total                int32                        `json:"total,omitempty"`
 	TransactionTemplates []TransactionTemplateWrapper `json:"transactionTransactionTemplates,omitempty"`
 }
+
+// TransactionTemplates returns all of the transaction templates that match
+// the provided payload.  Offset and Count in the payload are managed here.
+func TransactionTemplates(e *Environment, payload TransactionTemplateSearchRequestPayload, logger *UtilLogger) ([]TransactionTemplate, error) {
+	var a []TransactionTemplate
+	count := e.BatchSize()
+	offset := int32(0)
+	for count == e.BatchSize() {
+		payload.Offset = offset
+		payload.Count = count
+		rqt := TransactionTemplateSearchRequest{
+			Header:  RequestHeader{},
+			Payload: payload,
+		}
+		var resp TransactionTemplateSearchResponse
+		n := NetOp{
+			Host:     e.Host,
+			Method:   SearchMethod,
+			Endpoint: SearchTransactionTransactionTemplates,
+			Token:    e.Token,
+			Request:  &rqt,
+			Response: &resp,
+			Logger:   logger,
+		}
+		err := n.Do()
+		if err != nil {
+			return a, err
+		}
+		if len(resp.Errors) != 0 {
+			x := resp.Errors[0]
+			return a, fmt.Errorf("TransactionTemplates: %v %v %v", x.Code, x.Message, x.Details)
+		}
+		for _, w := range resp.Payload.TransactionTemplates {
+			a = append(a, w.TransactionTemplate)
+		}
+		count = resp.Payload.Count
+		offset += count
+	}
+	return a, nil
+}
@@ -1,5 +1,7 @@
 package goengage
 
+import "fmt"
+
 //Describes search for transactions. Note that transactions are listed
 //as part of actions *and* can be searched without actions being involved.
 
@@ -11,33 +13,35 @@ const (
 // Transaction is an Engage donation template. Part of transactions in
 // the API documentation.
 type DonationTransaction struct {
-	CreatedBy              string  `json:"createdBy,omitempty"`
-	CreatedDate            string  `json:"createdDate,omitempty"`
-	ModifiedBy             string  `json:"modifiedBy,omitempty"`
-	LastModified           string  `json:"lastModified,omitempty"`
-	TransactionID          string  `json:"transactionId,omitempty"`
-	ActivityID             string  `json:"activityId,omitempty"`
-	ActivityFormID         string  `json:"activityFormId,omitempty"`
-	ActivityName           string  `json:"activityName,omitempty"`
-	SupporterID            string  `json:"supporterId,omitempty"`
-	AccountExpiration      string  `json:"accountExpiration,omitempty"`
-	AccountType            string  `json:"accountType,omitempty"`
-	Amount                 float32 `json:"amount,omitempty"`
-	TemplateID             string  `json:"templateId,omitempty"`
-	RelatedTransactionID   string  `json:"relatedTransactionId,omitempty"`
-	TransactionDate        string  `json:"transactionDate,omitempty"`
-	Transaction            string  `json:"transaction,omitempty"`
-	TransactionType        string  `json:"transactionType,omitempty"`
-	ConfirmationEmailID    string  `json:"confirmationEmailId,omitempty"`
-	ConfirmationEmailSent  string  `json:"confirmationEmailSent,omitempty"`
-	WasImported            bool    `json:"wasImported,omitempty"`
-	WasOffline             bool    `json:"wasOffline,omitempty"`
-	DeductibleAmount       float32 `json:"deductibleAmount,omitempty"`
-	FeesPaid               float32 `json:"feesPaid,omitempty"`
-	WasAPIImported         bool    `json:"wasApiImported,omitempty"`
-	ConvertedValidityCheck bool    `json:"convertedValidityCheck,omitempty"`
-	ValidityCheckDate      string  `json:"validityCheckDate,omitempty"`
-	Result                 string  `json:"result,omitempty"`
+	CreatedBy                string  `json:"createdBy,omitempty"`
+	CreatedDate              string  `json:"createdDate,omitempty"`
+	ModifiedBy               string  `json:"modifiedBy,omitempty"`
+	LastModified             string  `json:"lastModified,omitempty"`
+	TransactionID            string  `json:"transactionId,omitempty"`
+	ActivityID               string  `json:"activityId,omitempty"`
+	ActivityFormID           string  `json:"activityFormId,omitempty"`
+	ActivityName             string  `json:"activityName,omitempty"`
+	SupporterID              string  `json:"supporterId,omitempty"`
+	AccountExpiration        string  `json:"accountExpiration,omitempty"`
+	AccountType              string  `json:"accountType,omitempty"`
+	Amount                   float32 `json:"amount,omitempty"`
+	TemplateID               string  `json:"templateId,omitempty"`
+	RelatedTransactionID     string  `json:"relatedTransactionId,omitempty"`
+	TransactionDate          string  `json:"transactionDate,omitempty"`
+	Transaction              string  `json:"transaction,omitempty"`
+	TransactionType          string  `json:"transactionType,omitempty"`
+	ConfirmationEmailID      string  `json:"confirmationEmailId,omitempty"`
+	ConfirmationEmailSent    string  `json:"confirmationEmailSent,omitempty"`
+	WasImported              bool    `json:"wasImported,omitempty"`
+	WasOffline               bool    `json:"wasOffline,omitempty"`
+	DeductibleAmount         float32 `json:"deductibleAmount,omitempty"`
+	FeesPaid                 float32 `json:"feesPaid,omitempty"`
+	GatewayTransactionID     string  `json:"gatewayTransactionId,omitempty"`
+	GatewayAuthorizationCode string  `json:"gatewayAuthorizationCode,omitempty"`
+	WasAPIImported           bool    `json:"wasApiImported,omitempty"`
+	ConvertedValidityCheck   bool    `json:"convertedValidityCheck,omitempty"`
+	ValidityCheckDate        string  `json:"validityCheckDate,omitempty"`
+	Result                   string  `json:"result,omitempty"`
 }
 
 // TransactionSearchRequest contains parameters for searching for segments.
@@ -82,3 +86,43 @@ type TransactionSearchResponsePayload struct {
 	Total        int32                `json:"total,omitempty"`
 	Transactions []TransactionWrapper `json:"transactions,omitempty"`
 }
+
+// Transactions returns all of the transactions that match the provided
+// payload.  Offset and Count in the payload are managed here.
+func Transactions(e *Environment, payload TransactionSearchRequestPayload, logger *UtilLogger) ([]DonationTransaction, error) {
+	var a []DonationTransaction
+	count := e.BatchSize()
+	offset := int32(0)
+	for count == e.BatchSize() {
+		payload.Offset = offset
+		payload.Count = count
+		rqt := TransactionSearchRequest{
+			Header:  RequestHeader{},
+			Payload: payload,
+		}
+		var resp TransactionSearchResponse
+		n := NetOp{
+			Host:     e.Host,
+			Method:   SearchMethod,
+			Endpoint: SearchTransactionDetails,
+			Token:    e.Token,
+			Request:  &rqt,
+			Response: &resp,
+			Logger:   logger,
+		}
+		err := n.Do()
+		if err != nil {
+			return a, err
+		}
+		if len(resp.Errors) != 0 {
+			x := resp.Errors[0]
+			return a, fmt.Errorf("Transactions: %v %v %v", x.Code, x.Message, x.Details)
+		}
+		for _, w := range resp.Payload.Transactions {
+			a = append(a, w.DonationTransaction)
+		}
+		count = resp.Payload.Count
+		offset += count
+	}
+	return a, nil
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -43,17 +44,40 @@ func FirstPhone(s Supporter) *string {
 	return nil
 }
 
-// Date parses an Engage date and returns a Go time.
-func Date(s string) (t *time.Time) {
+// Date parses an Engage date and returns a Go time.  Returns nil for
+// an empty string.
+func Date(s string) (*time.Time, error) {
 	if len(s) == 0 {
-		return t
+		return nil, nil
 	}
 	p := strings.Replace(time.RFC3339Nano, "9999999Z07:00", "Z", -1)
 	x, err := time.Parse(p, s)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return &x
+	return &x, nil
+}
+
+// FirstError keeps the first error returned by a group of goroutines.
+type FirstError struct {
+	sync.Mutex
+	err error
+}
+
+// Set records an error if it's the first one.
+func (f *FirstError) Set(err error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+// Err returns the first error.
+func (f *FirstError) Err() error {
+	f.Lock()
+	defer f.Unlock()
+	return f.err
 }
 
 // UtilLogger is an environment to support a file logger.  It contains
@@ -104,6 +128,18 @@ func (t TimeStamp) MarshalJSON() ([]byte, error) {
 	return []byte(s), nil
 }
 
+// UnmarshalJSON parses JSON for a time.  Without this, JSON is parsed
+// into the embedded *time.Time, which is nil.
+func (t *TimeStamp) UnmarshalJSON(b []byte) error {
+	var x time.Time
+	err := x.UnmarshalJSON(b)
+	if err != nil {
+		return err
+	}
+	t.Time = &x
+	return nil
+}
+
 // DoneListener waits for 'n' messages on the provided channel.
 func DoneListener(c chan bool, n int) {
 	log.Println("DoneListener: start")